@@ -0,0 +1,202 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IncidentPhase is the lifecycle phase of an Incident.
+// +kubebuilder:validation:Enum=Open;Remediating;Resolved;Acknowledged
+type IncidentPhase string
+
+const (
+	// IncidentPhaseOpen means the problem has been detected and analyzed.
+	IncidentPhaseOpen IncidentPhase = "Open"
+	// IncidentPhaseRemediating means an automatic fix has been attempted and
+	// the problem is waiting to be resolved.
+	IncidentPhaseRemediating IncidentPhase = "Remediating"
+	// IncidentPhaseResolved means the problem is gone.
+	IncidentPhaseResolved IncidentPhase = "Resolved"
+	// IncidentPhaseAcknowledged means a user has acknowledged the problem.
+	IncidentPhaseAcknowledged IncidentPhase = "Acknowledged"
+)
+
+const (
+	// IncidentKopilotLabel is the label holding the name of the Kopilot that detected an Incident.
+	IncidentKopilotLabel = "kopilot.fl0rencess720/kopilot"
+	// IncidentFingerprintLabel is the label holding the fingerprint of an Incident.
+	IncidentFingerprintLabel = "kopilot.fl0rencess720/fingerprint"
+)
+
+// IncidentSpec defines the detected problem.
+type IncidentSpec struct {
+	// KopilotRef is the name of the Kopilot that detected the incident.
+	// +kubebuilder:validation:Required
+	KopilotRef string `json:"kopilotRef"`
+
+	// Fingerprint identifies the incident by its owner workload, failing container
+	// and failure reason. The reasons of a crash loop, CrashLoopBackOff, OOMKilled,
+	// RestartStorm and Error, share a fingerprint.
+	// +kubebuilder:validation:Required
+	Fingerprint string `json:"fingerprint"`
+
+	// PodRef is a reference to the pod that was analyzed. It is not set for node incidents.
+	// +optional
+	PodRef *PodReference `json:"podRef,omitempty"`
+
+	// NodeName is the name of the node that was analyzed, for node incidents.
+	// +optional
+	NodeName string `json:"nodeName,omitempty"`
+
+	// OwnerRef is a reference to the workload that owns the pod, or to the node of a node incident.
+	// +optional
+	OwnerRef *WorkloadReference `json:"ownerRef,omitempty"`
+
+	// Container is the name of the failing container.
+	// +optional
+	Container string `json:"container,omitempty"`
+
+	// FailureReason is the last observed reason why the pod is unhealthy, e.g. CrashLoopBackOff.
+	// +optional
+	FailureReason string `json:"failureReason,omitempty"`
+
+	// Acknowledged can be set by a user to acknowledge the incident.
+	// Acknowledged incidents are not notified again until they are resolved.
+	// +optional
+	Acknowledged bool `json:"acknowledged,omitempty"`
+}
+
+// PodReference is a reference to a pod.
+type PodReference struct {
+	// Namespace of the pod.
+	Namespace string `json:"namespace"`
+	// Name of the pod.
+	Name string `json:"name"`
+	// UID of the pod.
+	// +optional
+	UID string `json:"uid,omitempty"`
+}
+
+// WorkloadReference is a reference to a workload.
+type WorkloadReference struct {
+	// Kind of the workload, e.g. Deployment.
+	Kind string `json:"kind"`
+	// Name of the workload.
+	Name string `json:"name"`
+}
+
+// IncidentAnalysis is the structured result of an LLM analysis.
+type IncidentAnalysis struct {
+	// Reason is the root cause analysis.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Solution is the proposed solution.
+	// +optional
+	Solution string `json:"solution,omitempty"`
+	// Sink indicates whether the LLM considered the problem worth notifying.
+	// +optional
+	Sink bool `json:"sink,omitempty"`
+	// NewRevisionAtFault indicates whether the LLM considered the new revision
+	// the cause of a stalled rollout.
+	// +optional
+	NewRevisionAtFault *bool `json:"newRevisionAtFault,omitempty"`
+	// RollbackRecommended indicates whether the LLM recommended rolling back a
+	// stalled rollout.
+	// +optional
+	RollbackRecommended *bool `json:"rollbackRecommended,omitempty"`
+}
+
+// IncidentRemediation records the results of the multi-agent working mode.
+type IncidentRemediation struct {
+	// AutoFixResult is the outcome of the automatic fix.
+	// +optional
+	AutoFixResult string `json:"autoFixResult,omitempty"`
+	// SearchResult is the outcome of the web search.
+	// +optional
+	SearchResult string `json:"searchResult,omitempty"`
+	// HumanHelpResult is the document generated for manual handling.
+	// +optional
+	HumanHelpResult string `json:"humanHelpResult,omitempty"`
+}
+
+// IncidentStatus defines the observed state of Incident.
+type IncidentStatus struct {
+	// Phase is the lifecycle phase of the incident.
+	// +optional
+	Phase IncidentPhase `json:"phase,omitempty"`
+
+	// AffectedPods lists the pods that showed the problem.
+	// +optional
+	AffectedPods []string `json:"affectedPods,omitempty"`
+
+	// LogsExcerpt is an excerpt of the logs that were analyzed.
+	// +optional
+	LogsExcerpt string `json:"logsExcerpt,omitempty"`
+
+	// Analysis is the structured result of the LLM analysis.
+	// +optional
+	Analysis *IncidentAnalysis `json:"analysis,omitempty"`
+
+	// Remediation records the results of the multi-agent working mode.
+	// +optional
+	Remediation *IncidentRemediation `json:"remediation,omitempty"`
+
+	// FirstSeen is when the problem was first detected.
+	// +optional
+	FirstSeen *metav1.Time `json:"firstSeen,omitempty"`
+
+	// LastAnalyzed is when the problem was last analyzed and notified.
+	// +optional
+	LastAnalyzed *metav1.Time `json:"lastAnalyzed,omitempty"`
+
+	// ResolvedAt is when the problem was found to be resolved.
+	// +optional
+	ResolvedAt *metav1.Time `json:"resolvedAt,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Pod",type="string",JSONPath=".spec.podRef.name"
+// +kubebuilder:printcolumn:name="Node",type="string",JSONPath=".spec.nodeName",priority=1
+// +kubebuilder:printcolumn:name="Owner",type="string",JSONPath=".spec.ownerRef.name",priority=1
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".spec.failureReason"
+// +kubebuilder:printcolumn:name="Last Analyzed",type="date",JSONPath=".status.lastAnalyzed"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// Incident is the Schema for the incidents API
+type Incident struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IncidentSpec   `json:"spec,omitempty"`
+	Status IncidentStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// IncidentList contains a list of Incident
+type IncidentList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Incident `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Incident{}, &IncidentList{})
+}
@@ -25,12 +25,55 @@ import (
 
 // KopilotSpec defines the desired state of Kopilot
 type KopilotSpec struct {
+	// Schedule is the cron schedule of the periodic sweep over all pods in scope.
 	// +kubebuilder:validation:Required
 	Schedule string `json:"schedule"`
 
+	// Trigger configures how unhealthy pods are detected between scheduled sweeps.
+	// +optional
+	Trigger *TriggerSpec `json:"trigger,omitempty"`
+
+	// Detection configures when a pod is considered unhealthy.
+	// +optional
+	Detection *DetectionSpec `json:"detection,omitempty"`
+
+	// Targets are the kinds of resources whose health is analyzed.
+	// "Pods" analyzes the unhealthy pods selected by Selector and the namespace fields.
+	// "Nodes" analyzes nodes with unhealthy conditions, such as NotReady or DiskPressure.
+	// "Rollouts" analyzes Deployments that exceeded their progress deadline and
+	// StatefulSets whose rolling update stalled.
+	// +listType=set
+	// +kubebuilder:validation:items:Enum=Pods;Nodes;Rollouts
+	// +kubebuilder:default:={"Pods"}
+	// +optional
+	Targets []string `json:"targets,omitempty"`
+
+	// NodeSelector is a label selector for the nodes to be analyzed by the Nodes target.
+	// If not specified, all nodes are considered.
+	// +optional
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+
+	// Selector is a label selector for the pods to be analyzed.
+	// An empty selector matches all pods.
 	// +kubebuilder:validation:Required
 	Selector *metav1.LabelSelector `json:"selector"`
 
+	// NamespaceSelector is a label selector for the namespaces to be analyzed.
+	// If not specified, pods in all namespaces are considered.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Namespaces is an explicit allow list of namespaces to be analyzed.
+	// If specified, only pods in these namespaces are considered.
+	// It is combined with NamespaceSelector when both are set.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// ExcludedNamespaces is a deny list of namespaces that are never analyzed.
+	// It takes precedence over Namespaces and NamespaceSelector.
+	// +optional
+	ExcludedNamespaces []string `json:"excludedNamespaces,omitempty"`
+
 	// +kubebuilder:validation:Required
 	LogSource LogSourceSpec `json:"logSource"`
 
@@ -42,18 +85,305 @@ type KopilotSpec struct {
 
 	// +optional
 	KnowledgeBase *KnowledgeBaseSpec `json:"knowledgeBase,omitempty"`
+
+	// Deduplication configures how repeated failures of the same workload are deduplicated.
+	// +optional
+	Deduplication *DeduplicationSpec `json:"deduplication,omitempty"`
+
+	// Redaction replaces sensitive data in the logs and the pod YAML with
+	// placeholders before they are sent to the LLM. Disabled if not specified.
+	// +optional
+	Redaction *RedactionSpec `json:"redaction,omitempty"`
+}
+
+// RedactionSpec defines how sensitive data is redacted. Each distinct value is
+// replaced by a placeholder such as [REDACTED_EMAIL_1], so that the LLM can
+// still correlate occurrences of the same value.
+type RedactionSpec struct {
+	// Detectors are the built-in detectors to use. Defaults to all of them.
+	// +listType=set
+	// +kubebuilder:validation:items:Enum=jwt;bearerToken;awsKey;email;ipv4;ipv6;creditCard
+	// +optional
+	Detectors []string `json:"detectors,omitempty"`
+
+	// Rules are additional regular expressions to redact. If a rule has a
+	// capturing group, only the first group is redacted.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Rules []RedactionRule `json:"rules,omitempty"`
+
+	// RulesConfigMapRef references a ConfigMap with additional rules, one per
+	// key, with the key as the rule name and the value as the regular expression.
+	// +optional
+	RulesConfigMapRef *ConfigMapRef `json:"rulesConfigMapRef,omitempty"`
+
+	// MaskEnv replaces the values of the environment variables in the pod YAML.
+	// +kubebuilder:default:=true
+	MaskEnv bool `json:"maskEnv"`
+
+	// RestoreInIncidents restores the original values of the placeholders in
+	// the analysis results and logs of the Incident status. Notifications
+	// always keep the placeholders, since they leave the cluster.
+	// +optional
+	RestoreInIncidents bool `json:"restoreInIncidents,omitempty"`
+}
+
+// RedactionRule is a user-defined redaction rule.
+type RedactionRule struct {
+	// Name is used in the placeholders, e.g. [REDACTED_CUSTOMER_ID_1].
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z][a-zA-Z0-9_]*$`
+	Name string `json:"name"`
+
+	// Pattern is a regular expression in Go (RE2) syntax.
+	// +kubebuilder:validation:MinLength=1
+	Pattern string `json:"pattern"`
+}
+
+// ConfigMapRef is a reference to a ConfigMap.
+type ConfigMapRef struct {
+	// Namespace is the namespace where the ConfigMap is located.
+	// If not specified, defaults to the same namespace as the Kopilot instance.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Name of the ConfigMap.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+}
+
+// TriggerSpec defines how analyses are triggered.
+type TriggerSpec struct {
+	// Mode specifies the trigger mode.
+	// "schedule" only analyzes pods on the cron schedule.
+	// "watch" additionally watches pods and analyzes them as soon as they become unhealthy,
+	// with the cron schedule kept as a periodic sweep.
+	// +kubebuilder:validation:Enum=schedule;watch
+	// +kubebuilder:default:="schedule"
+	Mode string `json:"mode"`
+
+	// Debounce is how long to wait after a pod becomes unhealthy before analyzing it,
+	// so that a burst of pod updates results in a single analysis.
+	// +kubebuilder:default:="30s"
+	// +optional
+	Debounce *metav1.Duration `json:"debounce,omitempty"`
+
+	// MinInterval is the minimum interval between two watch-triggered analyses of the same pod.
+	// +kubebuilder:default:="10m"
+	// +optional
+	MinInterval *metav1.Duration `json:"minInterval,omitempty"`
+}
+
+// DetectionSpec defines the thresholds of the health evaluation. Pods with
+// failing containers, such as CrashLoopBackOff, OOMKilled or
+// CreateContainerConfigError, are always unhealthy.
+type DetectionSpec struct {
+	// RestartCount is the number of restarts of a container from which it is
+	// unhealthy, if it restarted within the last hour.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default:=5
+	// +optional
+	RestartCount int32 `json:"restartCount,omitempty"`
+
+	// RestartsPerHour is the restart rate of a container since the pod started
+	// from which it is unhealthy.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default:=6
+	// +optional
+	RestartsPerHour int32 `json:"restartsPerHour,omitempty"`
+
+	// PendingTimeout is how long a pod may stay Pending, e.g. unschedulable or
+	// waiting for a volume, before it is unhealthy.
+	// +kubebuilder:default:="10m"
+	// +optional
+	PendingTimeout *metav1.Duration `json:"pendingTimeout,omitempty"`
+
+	// NotReadyTimeout is how long a running container may stay not ready, e.g.
+	// because of a failing readiness probe, before it is unhealthy.
+	// +kubebuilder:default:="5m"
+	// +optional
+	NotReadyTimeout *metav1.Duration `json:"notReadyTimeout,omitempty"`
+
+	// RolloutTimeout is how long a StatefulSet rolling update may make no
+	// progress before it is stalled. Deployments use their progressDeadlineSeconds.
+	// +kubebuilder:default:="10m"
+	// +optional
+	RolloutTimeout *metav1.Duration `json:"rolloutTimeout,omitempty"`
+
+	// Rules are user-defined health rules, evaluated in order before the
+	// built-in rules.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Rules []HealthRule `json:"rules,omitempty"`
+
+	// RulesMode specifies how the rules are combined with the built-in rules.
+	// "augment" evaluates the built-in rules for pods that no rule matched.
+	// "replace" only evaluates the rules.
+	// +kubebuilder:validation:Enum=augment;replace
+	// +kubebuilder:default:="augment"
+	// +optional
+	RulesMode string `json:"rulesMode,omitempty"`
+}
+
+// HealthRule is a CEL expression that classifies a pod. The expression must
+// evaluate to a bool and can use the variables:
+//   - pod: the pod object
+//   - containerStatuses: the statuses of the init and regular containers
+//   - events: the events of the pod. Rules that use events are only
+//     evaluated in the sweeps, not on the pod updates of the watch trigger.
+//   - now: the current time as a timestamp
+//
+// For example `pod.status.containerStatuses.exists(c, c.restartCount > 5)`.
+type HealthRule struct {
+	// Name is the failure reason of the pods matched by the rule.
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z][a-zA-Z0-9_-]*$`
+	Name string `json:"name"`
+
+	// Expression is the CEL expression.
+	// +kubebuilder:validation:MinLength=1
+	Expression string `json:"expression"`
+
+	// Action specifies what to do with a matched pod.
+	// "unhealthy" classifies it as unhealthy with the severity of the rule.
+	// "ignore" considers it healthy, e.g. for pods annotated kopilot.io/ignore.
+	// +kubebuilder:validation:Enum=unhealthy;ignore
+	// +kubebuilder:default:="unhealthy"
+	// +optional
+	Action string `json:"action,omitempty"`
+
+	// Severity of the pods matched by the rule.
+	// +kubebuilder:validation:Enum=critical;warning
+	// +kubebuilder:default:="warning"
+	// +optional
+	Severity string `json:"severity,omitempty"`
+
+	// PromptHint is added to the prompt of the pods matched by the rule, e.g.
+	// to point the LLM at a known cause.
+	// +optional
+	PromptHint string `json:"promptHint,omitempty"`
+}
+
+// DeduplicationSpec defines how incidents are deduplicated.
+// An incident is identified by a fingerprint of the owner workload, the failing container and
+// the failure reason, with the reasons of a crash loop fingerprinted as one.
+// An open incident is only re-analyzed when the re-notify interval has passed, and it is
+// resolved when three consecutive sweeps did not observe it.
+type DeduplicationSpec struct {
+	// ReNotifyInterval is the interval after which an open incident is analyzed and notified again.
+	// +kubebuilder:default:="24h"
+	// +optional
+	ReNotifyInterval *metav1.Duration `json:"reNotifyInterval,omitempty"`
+
+	// NotifyResolved enables sending a notification when an incident is resolved.
+	// +kubebuilder:default:=true
+	NotifyResolved bool `json:"notifyResolved"`
+
+	// ResolvedRetention is how long Resolved Incidents are kept before they are deleted.
+	// +kubebuilder:default:="168h"
+	// +optional
+	ResolvedRetention *metav1.Duration `json:"resolvedRetention,omitempty"`
 }
 
 // LogSourceSpec defines the source of logs.
+// +kubebuilder:validation:XValidation:rule="self.type != 'Loki' || has(self.loki)",message="loki must be set when type is Loki"
+// +kubebuilder:validation:XValidation:rule="self.type != 'Elasticsearch' || has(self.elasticsearch)",message="elasticsearch must be set when type is Elasticsearch"
 type LogSourceSpec struct {
 	// Type specifies the log source type.
-	// +kubebuilder:validation:Enum=Kubernetes;Loki
+	// Elasticsearch is also used for OpenSearch.
+	// +kubebuilder:validation:Enum=Kubernetes;Loki;Elasticsearch
 	// +kubebuilder:default:="Kubernetes"
 	Type string `json:"type"`
 
 	// This is only used if Type is "Loki".
 	// +optional
 	Loki *LokiSource `json:"loki,omitempty"`
+
+	// This is only used if Type is "Elasticsearch".
+	// +optional
+	Elasticsearch *ElasticsearchSource `json:"elasticsearch,omitempty"`
+
+	// TailLines is the maximum number of log lines fetched per container.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=5000
+	// +kubebuilder:default:=100
+	// +optional
+	TailLines *int64 `json:"tailLines,omitempty"`
+
+	// SinceSeconds only fetches logs newer than this many seconds.
+	// It is ignored when TimeWindow applies.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	SinceSeconds *int64 `json:"sinceSeconds,omitempty"`
+
+	// MaxBytes caps the size of the fetched logs per container, keeping the most recent lines.
+	// +kubebuilder:validation:Minimum=1024
+	// +kubebuilder:default:=65536
+	// +optional
+	MaxBytes *int64 `json:"maxBytes,omitempty"`
+
+	// TimeWindow only fetches logs written in this window before the last
+	// termination of the failing container (lastState.terminated.finishedAt).
+	// If the container has not terminated, the window ends now.
+	// +optional
+	TimeWindow *metav1.Duration `json:"timeWindow,omitempty"`
+
+	// Preprocessing configures how the fetched logs are reduced before they are
+	// sent to the LLM. If not specified, ANSI codes are stripped, repeated lines
+	// are deduplicated, the first and last errors are kept and the logs are
+	// capped to 4000 tokens.
+	// +optional
+	Preprocessing *PreprocessingSpec `json:"preprocessing,omitempty"`
+}
+
+// PreprocessingSpec defines the steps applied to the logs of each container
+// before they are sent to the LLM, in the order of the fields.
+type PreprocessingSpec struct {
+	// StripANSI removes ANSI escape sequences, e.g. colors.
+	// +kubebuilder:default:=true
+	StripANSI bool `json:"stripANSI"`
+
+	// KeepErrors keeps the first and last error lines verbatim, so that they
+	// survive deduplication, clustering and the token budget.
+	// +kubebuilder:default:=true
+	KeepErrors bool `json:"keepErrors"`
+
+	// ErrorPattern is the regular expression matching error lines.
+	// Defaults to a case-insensitive match of error, exception, fatal, panic, failed and traceback.
+	// +optional
+	ErrorPattern string `json:"errorPattern,omitempty"`
+
+	// Deduplicate collapses repeated identical lines into a single line with a count.
+	// +kubebuilder:default:=true
+	Deduplicate bool `json:"deduplicate"`
+
+	// Clustering groups similar lines into templates (Drain-style), e.g. lines
+	// that only differ in IDs or timestamps. Disabled if not specified.
+	// +optional
+	Clustering *ClusteringSpec `json:"clustering,omitempty"`
+
+	// MaxTokens caps the estimated number of tokens of the logs of a pod.
+	// The most recent lines are kept. 0 disables the cap.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default:=4000
+	// +optional
+	MaxTokens int32 `json:"maxTokens,omitempty"`
+}
+
+// ClusteringSpec defines how similar log lines are clustered.
+type ClusteringSpec struct {
+	// SimilarityThreshold is the minimum fraction of equal tokens for a line
+	// to join a cluster.
+	// +kubebuilder:validation:Pattern=`^(0(\.[0-9]+)?|1(\.0+)?)$`
+	// +kubebuilder:default:="0.5"
+	// +optional
+	SimilarityThreshold string `json:"similarityThreshold,omitempty"`
+
+	// MinClusterSize is the minimum number of lines of a cluster to be
+	// replaced by its template. Smaller clusters keep their lines.
+	// +kubebuilder:validation:Minimum=2
+	// +kubebuilder:default:=3
+	// +optional
+	MinClusterSize int32 `json:"minClusterSize,omitempty"`
 }
 
 // LokiSource defines connection details for a Loki instance.
@@ -62,19 +392,156 @@ type LokiSource struct {
 	Address string `json:"address"`
 
 	// LogQLQuery is the query to execute against Loki to fetch logs.
-	// The query can use variables like {pod}, {namespace}, {container}.
+	// The query can use variables like {pod}, {namespace}, {container} inside
+	// label matcher values, e.g. {k8s_pod_name="{pod}"} |= "error".
+	// If {container} is used, the query is run once per container.
+	// Defaults to {namespace="{namespace}",pod="{pod}"}.
 	// +optional
 	LogQLQuery string `json:"logqlQuery,omitempty"`
+
+	// Auth configures basic or bearer authentication.
+	// +optional
+	Auth *LokiAuth `json:"auth,omitempty"`
+
+	// TenantID is sent as the X-Scope-OrgID header of multi-tenant Loki installations.
+	// +optional
+	TenantID string `json:"tenantID,omitempty"`
+
+	// TLS configures the CA bundle and client certificate used to connect to Loki.
+	// +optional
+	TLS *TLSConfig `json:"tls,omitempty"`
+
+	// Headers are additional HTTP headers sent with every request.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Headers []HTTPHeader `json:"headers,omitempty"`
+}
+
+// LokiAuth defines how to authenticate to Loki.
+// +kubebuilder:validation:XValidation:rule="self.type != 'basic' || (has(self.usernameSecretRef) && has(self.passwordSecretRef))",message="usernameSecretRef and passwordSecretRef are required for basic auth"
+// +kubebuilder:validation:XValidation:rule="self.type != 'bearer' || has(self.tokenSecretRef)",message="tokenSecretRef is required for bearer auth"
+type LokiAuth struct {
+	// +kubebuilder:validation:Enum=basic;bearer
+	Type string `json:"type"`
+
+	// +optional
+	UsernameSecretRef *SecretKeyRef `json:"usernameSecretRef,omitempty"`
+
+	// +optional
+	PasswordSecretRef *SecretKeyRef `json:"passwordSecretRef,omitempty"`
+
+	// +optional
+	TokenSecretRef *SecretKeyRef `json:"tokenSecretRef,omitempty"`
+}
+
+// TLSConfig defines the TLS settings of a connection.
+// +kubebuilder:validation:XValidation:rule="has(self.certSecretRef) == has(self.keySecretRef)",message="certSecretRef and keySecretRef must be set together"
+type TLSConfig struct {
+	// CASecretRef references a PEM encoded CA bundle used to verify the server.
+	// The system roots are used if not set.
+	// +optional
+	CASecretRef *SecretKeyRef `json:"caSecretRef,omitempty"`
+
+	// CertSecretRef and KeySecretRef reference a PEM encoded client certificate and key.
+	// +optional
+	CertSecretRef *SecretKeyRef `json:"certSecretRef,omitempty"`
+
+	// +optional
+	KeySecretRef *SecretKeyRef `json:"keySecretRef,omitempty"`
+
+	// ServerName overrides the server name used to verify the certificate.
+	// +optional
+	ServerName string `json:"serverName,omitempty"`
+
+	// InsecureSkipVerify disables the verification of the server certificate.
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// HTTPHeader is an HTTP header with a literal value or a value from a Secret.
+// +kubebuilder:validation:XValidation:rule="has(self.value) != has(self.valueSecretRef)",message="exactly one of value and valueSecretRef must be set"
+type HTTPHeader struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// +optional
+	ValueSecretRef *SecretKeyRef `json:"valueSecretRef,omitempty"`
+}
+
+// ElasticsearchSource defines connection details for an Elasticsearch or OpenSearch cluster.
+// +kubebuilder:validation:XValidation:rule="has(self.usernameSecretRef) == has(self.passwordSecretRef)",message="usernameSecretRef and passwordSecretRef must be set together"
+// +kubebuilder:validation:XValidation:rule="!(has(self.usernameSecretRef) && has(self.apiKeySecretRef))",message="only one of basic auth and apiKeySecretRef may be set"
+type ElasticsearchSource struct {
+	// +kubebuilder:validation:Required
+	Address string `json:"address"`
+
+	// IndexPattern is the index pattern to search, e.g. "logstash-*".
+	// +kubebuilder:default:="logstash-*"
+	// +optional
+	IndexPattern string `json:"indexPattern,omitempty"`
+
+	// UsernameSecretRef and PasswordSecretRef configure basic authentication.
+	// +optional
+	UsernameSecretRef *SecretKeyRef `json:"usernameSecretRef,omitempty"`
+
+	// +optional
+	PasswordSecretRef *SecretKeyRef `json:"passwordSecretRef,omitempty"`
+
+	// APIKeySecretRef references a base64 encoded API key, sent as "Authorization: ApiKey <key>".
+	// +optional
+	APIKeySecretRef *SecretKeyRef `json:"apiKeySecretRef,omitempty"`
+
+	// TLS configures the CA bundle and client certificate used to connect to Elasticsearch.
+	// +optional
+	TLS *TLSConfig `json:"tls,omitempty"`
+
+	// Fields maps the log document fields. The defaults match the Fluent Bit
+	// kubernetes filter.
+	// +kubebuilder:default:={}
+	// +optional
+	Fields ElasticsearchFieldMapping `json:"fields,omitempty"`
+}
+
+// ElasticsearchFieldMapping defines the fields of a log document. Nested fields
+// are separated by dots. Pod, Namespace and Container are matched with term
+// queries, so they must be keyword fields, e.g. the "keyword" multi-field of a
+// dynamically mapped text field.
+type ElasticsearchFieldMapping struct {
+	// +kubebuilder:default:="kubernetes.pod_name.keyword"
+	// +optional
+	Pod string `json:"pod,omitempty"`
+
+	// +kubebuilder:default:="kubernetes.namespace_name.keyword"
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// +kubebuilder:default:="kubernetes.container_name.keyword"
+	// +optional
+	Container string `json:"container,omitempty"`
+
+	// +kubebuilder:default:="log"
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// +kubebuilder:default:="@timestamp"
+	// +optional
+	Timestamp string `json:"timestamp,omitempty"`
 }
 
 // LLMSpec defines the AI configuration.
+// +kubebuilder:validation:XValidation:rule="self.model != 'openai' || has(self.openai)",message="openai must be set when model is openai"
 type LLMSpec struct {
 	// WorkingMode specifies the AI working mode.
 	// +kubebuilder:validation:Enum=single;multi
 	// +kubebuilder:default:="single"
 	WorkingMode string `json:"workingMode"`
 	// Model specifies the AI model to be used for analysis.
-	// +kubebuilder:validation:Enum=gemini;deepseek
+	// +kubebuilder:validation:Enum=gemini;deepseek;openai
 	// +kubebuilder:default:="gemini"
 	Model string `json:"model"`
 
@@ -88,6 +555,10 @@ type LLMSpec struct {
 
 	// +optional
 	DeepSeek DeepSeekSpec `json:"deepseek"`
+
+	// OpenAI configures an OpenAI-compatible API, e.g. vLLM, Ollama or LiteLLM.
+	// +optional
+	OpenAI *OpenAISpec `json:"openai,omitempty"`
 }
 
 // GeminiSpec defines Gemini-specific configuration.
@@ -116,6 +587,43 @@ type DeepSeekSpec struct {
 	APIKeySecretRef SecretKeyRef `json:"apiKeySecretRef"`
 }
 
+// OpenAISpec defines the configuration of an OpenAI-compatible chat completions API.
+type OpenAISpec struct {
+	// BaseURL is the base URL of the API, e.g. http://vllm.llm.svc:8000/v1.
+	// +kubebuilder:default:="https://api.openai.com/v1"
+	// +optional
+	BaseURL string `json:"baseURL,omitempty"`
+
+	// ModelName is the model to use.
+	// +kubebuilder:validation:Required
+	ModelName string `json:"modelName"`
+
+	// APIKeySecretRef is a reference to a Kubernetes Secret.
+	// The secret must contain a key (e.g., 'apiKey') with the API key.
+	// It can be omitted for gateways that do not require authentication.
+	// +optional
+	APIKeySecretRef *SecretKeyRef `json:"apiKeySecretRef,omitempty"`
+
+	// MaxTokens is the maximum number of tokens to generate.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default:=2000
+	// +optional
+	MaxTokens *int32 `json:"maxTokens,omitempty"`
+
+	// Temperature is the sampling temperature, between 0 and 2.
+	// +kubebuilder:validation:Pattern=`^([01](\.[0-9]+)?|2(\.0+)?)$`
+	// +optional
+	Temperature string `json:"temperature,omitempty"`
+
+	// JSONMode specifies how structured responses are requested from the model.
+	// json_object asks for any JSON object, json_schema passes the response schema,
+	// and none relies on the prompt only, for servers without structured output support.
+	// +kubebuilder:validation:Enum=none;json_object;json_schema
+	// +kubebuilder:default:="json_object"
+	// +optional
+	JSONMode string `json:"jsonMode,omitempty"`
+}
+
 // NotificationSpec defines where and how to send notifications.
 type NotificationSpec struct {
 	// Sinks is a list of notification channels.
@@ -124,15 +632,23 @@ type NotificationSpec struct {
 }
 
 // NotificationSink defines a single notification channel.
+// +kubebuilder:validation:XValidation:rule="[has(self.feishu), has(self.slack), has(self.webhook)].filter(x, x).size() == 1",message="exactly one sink type must be configured"
 type NotificationSink struct {
 	// Name is a unique identifier for this sink.
 	// +kubebuilder:validation:Required
 	Name string `json:"name"`
 
 	// Feishu configures notifications to a Feishu (Lark) webhook.
-	// In the future, you could add other types like Slack here.
 	// +optional
 	Feishu *FeishuSink `json:"feishu,omitempty"`
+
+	// Slack configures notifications to Slack.
+	// +optional
+	Slack *SlackSink `json:"slack,omitempty"`
+
+	// Webhook configures notifications to a generic HTTP endpoint.
+	// +optional
+	Webhook *WebhookSink `json:"webhook,omitempty"`
 }
 
 // FeishuSink defines the configuration for a Feishu webhook.
@@ -149,6 +665,74 @@ type FeishuSink struct {
 	SignatureSecretRef SecretKeyRef `json:"signatureSecretRef"`
 }
 
+// SlackSink defines the configuration for Slack notifications.
+// Messages are either posted to an incoming webhook, or posted to a channel with a bot token.
+// +kubebuilder:validation:XValidation:rule="has(self.webhookSecretRef) != has(self.botTokenSecretRef)",message="exactly one of webhookSecretRef and botTokenSecretRef must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.botTokenSecretRef) || has(self.channel)",message="channel is required when botTokenSecretRef is set"
+type SlackSink struct {
+	// WebhookSecretRef is a reference to a Kubernetes Secret.
+	// The secret must contain a key (e.g., 'url') with the Slack incoming webhook URL.
+	// +optional
+	WebhookSecretRef *SecretKeyRef `json:"webhookSecretRef,omitempty"`
+
+	// BotTokenSecretRef is a reference to a Kubernetes Secret.
+	// The secret must contain a key (e.g., 'token') with the Slack bot token.
+	// +optional
+	BotTokenSecretRef *SecretKeyRef `json:"botTokenSecretRef,omitempty"`
+
+	// Channel is the ID or name of the channel to post to when using a bot token.
+	// +optional
+	Channel string `json:"channel,omitempty"`
+}
+
+// WebhookSink defines the configuration for a generic HTTP webhook.
+// Notifications are POSTed as a versioned JSON document.
+type WebhookSink struct {
+	// URLSecretRef is a reference to a Kubernetes Secret.
+	// The secret must contain a key (e.g., 'url') with the webhook URL.
+	// +kubebuilder:validation:Required
+	URLSecretRef SecretKeyRef `json:"urlSecretRef"`
+
+	// SigningSecretRef is a reference to a Kubernetes Secret that holds the signing key.
+	// If set, the request body is signed with HMAC-SHA256 and the signature is sent
+	// in the X-Kopilot-Signature header.
+	// +optional
+	SigningSecretRef *SecretKeyRef `json:"signingSecretRef,omitempty"`
+
+	// Headers are additional HTTP headers whose values are read from Secrets.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Headers []WebhookHeader `json:"headers,omitempty"`
+
+	// Timeout is the timeout of a single request.
+	// +kubebuilder:default="10s"
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// MaxRetries is the number of times a failed request is retried.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	// +kubebuilder:default=3
+	// +optional
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+
+	// RetryBackoff is the delay before the first retry. It doubles on every further retry.
+	// +kubebuilder:default="1s"
+	// +optional
+	RetryBackoff *metav1.Duration `json:"retryBackoff,omitempty"`
+}
+
+// WebhookHeader is an HTTP header whose value is read from a Secret.
+type WebhookHeader struct {
+	// Name of the header.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+	// ValueSecretRef is a reference to the Secret key holding the header value.
+	// +kubebuilder:validation:Required
+	ValueSecretRef SecretKeyRef `json:"valueSecretRef"`
+}
+
 // SecretKeyRef is a reference to a key within a Kubernetes Secret.
 type SecretKeyRef struct {
 	// Namespace is the namespace where the Secret is located.
@@ -208,6 +792,21 @@ type ArkSpec struct {
 	APIKeySecretRef SecretKeyRef `json:"apiKeySecretRef"`
 }
 
+const (
+	// ConditionReady indicates whether the Kopilot is fully operational.
+	ConditionReady = "Ready"
+	// ConditionSecretsResolved indicates whether all Secrets referenced by the Kopilot could be read.
+	ConditionSecretsResolved = "SecretsResolved"
+	// ConditionLogSourceReachable indicates whether logs could be fetched from the log source.
+	ConditionLogSourceReachable = "LogSourceReachable"
+	// ConditionLLMReachable indicates whether the last LLM analysis succeeded.
+	ConditionLLMReachable = "LLMReachable"
+	// ConditionKnowledgeBaseReady indicates whether the knowledge base could be loaded.
+	ConditionKnowledgeBaseReady = "KnowledgeBaseReady"
+	// ConditionSinksHealthy indicates whether all notification sinks delivered the last notification.
+	ConditionSinksHealthy = "SinksHealthy"
+)
+
 // KopilotStatus defines the observed state of Kopilot.
 type KopilotStatus struct {
 	// Conditions store the status of the Kopilot instance.
@@ -221,6 +820,10 @@ type KopilotStatus struct {
 	// +optional
 	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
 
+	// LastRun records the pod counters of the last run.
+	// +optional
+	LastRun *RunStatistics `json:"lastRun,omitempty"`
+
 	// LastAnalysisResult is a summary of the latest AI analysis.
 	// +optional
 	LastAnalysisResult string `json:"lastAnalysisResult,omitempty"`
@@ -228,13 +831,73 @@ type KopilotStatus struct {
 	// LastError records the last error encountered by the operator for this instance.
 	// +optional
 	LastError string `json:"lastError,omitempty"`
+
+	// Sinks records the delivery status of each notification sink.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Sinks []SinkStatus `json:"sinks,omitempty"`
+}
+
+// RunStatistics counts the pods, nodes and rollouts handled in a run.
+type RunStatistics struct {
+	// PodsScanned is the number of pods in scope.
+	PodsScanned int32 `json:"podsScanned"`
+	// PodsUnhealthy is the number of unhealthy pods.
+	PodsUnhealthy int32 `json:"podsUnhealthy"`
+	// PodsAnalyzed is the number of pods analyzed by the LLM.
+	PodsAnalyzed int32 `json:"podsAnalyzed"`
+	// PodsNotified is the number of pods notified to at least one sink.
+	PodsNotified int32 `json:"podsNotified"`
+	// NodesScanned is the number of nodes in scope of the Nodes target.
+	// +optional
+	NodesScanned int32 `json:"nodesScanned,omitempty"`
+	// NodesUnhealthy is the number of unhealthy nodes.
+	// +optional
+	NodesUnhealthy int32 `json:"nodesUnhealthy,omitempty"`
+	// NodesAnalyzed is the number of nodes analyzed by the LLM.
+	// +optional
+	NodesAnalyzed int32 `json:"nodesAnalyzed,omitempty"`
+	// RolloutsScanned is the number of Deployments and StatefulSets in scope of the Rollouts target.
+	// +optional
+	RolloutsScanned int32 `json:"rolloutsScanned,omitempty"`
+	// RolloutsStalled is the number of stalled rollouts.
+	// +optional
+	RolloutsStalled int32 `json:"rolloutsStalled,omitempty"`
+	// RolloutsAnalyzed is the number of stalled rollouts analyzed by the LLM.
+	// +optional
+	RolloutsAnalyzed int32 `json:"rolloutsAnalyzed,omitempty"`
+}
+
+// SinkStatus is the delivery status of a notification sink.
+type SinkStatus struct {
+	// Name of the sink.
+	Name string `json:"name"`
+
+	// Healthy is false if the last delivery to the sink failed.
+	Healthy bool `json:"healthy"`
+
+	// LastSuccessTime is the time of the last successful delivery.
+	// +optional
+	LastSuccessTime *metav1.Time `json:"lastSuccessTime,omitempty"`
+
+	// LastFailureTime is the time of the last failed delivery.
+	// +optional
+	LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
+
+	// LastError is the error of the last failed delivery.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Schedule",type="string",JSONPath=".spec.schedule"
 // +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].reason"
+// +kubebuilder:printcolumn:name="Unhealthy",type="integer",JSONPath=".status.lastRun.podsUnhealthy"
+// +kubebuilder:printcolumn:name="Notified",type="integer",JSONPath=".status.lastRun.podsNotified"
 // +kubebuilder:printcolumn:name="Last Check",type="date",JSONPath=".status.lastCheckTime"
+// +kubebuilder:printcolumn:name="Last Result",type="string",JSONPath=".status.lastAnalysisResult",priority=1
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Kopilot is the Schema for the kopilots API
@@ -42,6 +42,7 @@ import (
 
 	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
 	"github.com/Fl0rencess720/Kopilot/internal/controller"
+	webhookv1 "github.com/Fl0rencess720/Kopilot/internal/webhook/v1"
 	"github.com/Fl0rencess720/Kopilot/pkg/consts"
 	"github.com/Fl0rencess720/Kopilot/pkg/logger"
 	// +kubebuilder:scaffold:imports
@@ -236,6 +237,13 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "Kopilot")
 		os.Exit(1)
 	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err := webhookv1.SetupKopilotWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Kopilot")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if metricsCertWatcher != nil {
@@ -0,0 +1,384 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Fl0rencess720/Kopilot/pkg/llm"
+	"github.com/go-logr/logr"
+	"gopkg.in/yaml.v3"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// contextEventLimit is the number of most recent events per object.
+	contextEventLimit = 15
+	// contextRevisionLimit is the number of most recent ReplicaSet revisions.
+	contextRevisionLimit = 5
+	// ownerChainDepth bounds the owners followed from a pod, e.g.
+	// Pod -> ReplicaSet -> Deployment or Pod -> Job -> CronJob.
+	ownerChainDepth = 3
+
+	revisionAnnotation = "deployment.kubernetes.io/revision"
+)
+
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get
+// +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=get
+// +kubebuilder:rbac:groups=batch,resources=jobs;cronjobs,verbs=get
+
+// owner is a workload in the owner chain of a pod.
+type owner struct {
+	kind   string
+	object client.Object
+}
+
+// podContext collects the cluster context of an unhealthy pod: the events of
+// the pod and its owners, the spec and status of the owners, the ReplicaSet
+// revisions of a Deployment, the schedule and Jobs of a CronJob and the
+// conditions of the node.
+func (r *KopilotReconciler) podContext(ctx context.Context, l logr.Logger, pod corev1.Pod) []llm.ContextSection {
+	var sections []llm.ContextSection
+	addEvents := func(kind, name string, uid types.UID) {
+		events, err := r.listEvents(ctx, pod.Namespace, uid)
+		if err != nil {
+			l.Error(err, "unable to list events", "kind", kind, "name", name, "namespace", pod.Namespace)
+			return
+		}
+		if len(events) > 0 {
+			sections = append(sections, llm.ContextSection{
+				Title:   fmt.Sprintf("Events of %s %s", kind, name),
+				Content: formatEvents(events),
+			})
+		}
+	}
+
+	// The pod template of a failed Job without pods has no events.
+	if pod.UID != "" {
+		addEvents("Pod", pod.Name, pod.UID)
+	}
+
+	owners, err := r.ownerChain(ctx, &pod)
+	if err != nil {
+		l.Error(err, "unable to get the owners of pod", "pod", pod.Name, "namespace", pod.Namespace)
+	}
+	for _, o := range owners {
+		content, err := formatOwner(o.object)
+		if err != nil {
+			l.Error(err, "unable to format owner", "kind", o.kind, "name", o.object.GetName())
+		} else {
+			sections = append(sections, llm.ContextSection{
+				Title:   fmt.Sprintf("%s %s (spec and status, pod template omitted)", o.kind, o.object.GetName()),
+				Content: content,
+			})
+		}
+		addEvents(o.kind, o.object.GetName(), o.object.GetUID())
+
+		if cronJob, ok := o.object.(*batchv1.CronJob); ok {
+			jobs, err := r.cronJobHistory(ctx, cronJob)
+			if err != nil {
+				l.Error(err, "unable to list jobs", "cronjob", cronJob.Name, "namespace", cronJob.Namespace)
+			} else {
+				sections = append(sections, llm.ContextSection{
+					Title:   fmt.Sprintf("Schedule and history of CronJob %s", cronJob.Name),
+					Content: formatCronJobHistory(cronJob, jobs),
+				})
+			}
+		}
+		if deployment, ok := o.object.(*appsv1.Deployment); ok {
+			revisions, err := r.replicaSetRevisions(ctx, deployment)
+			if err != nil {
+				l.Error(err, "unable to list ReplicaSets", "deployment", deployment.Name, "namespace", deployment.Namespace)
+			} else if len(revisions) > 0 {
+				sections = append(sections, llm.ContextSection{
+					Title:   fmt.Sprintf("ReplicaSet revisions of Deployment %s", deployment.Name),
+					Content: formatRevisions(deployment, revisions),
+				})
+			}
+		}
+	}
+
+	if pod.Spec.NodeName != "" {
+		node, err := r.Clientset.CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{})
+		if err != nil {
+			l.Error(err, "unable to get node", "node", pod.Spec.NodeName)
+		} else {
+			sections = append(sections, llm.ContextSection{
+				Title:   fmt.Sprintf("Node %s", node.Name),
+				Content: formatNode(node),
+			})
+		}
+	}
+	return sections
+}
+
+// listEvents returns the events of an object, the most recent first.
+func (r *KopilotReconciler) listEvents(ctx context.Context, namespace string, uid types.UID) ([]corev1.Event, error) {
+	list, err := r.Clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("involvedObject.uid", string(uid)).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var events []corev1.Event
+	for _, event := range list.Items {
+		if event.InvolvedObject.UID == uid {
+			events = append(events, event)
+		}
+	}
+	return sortEvents(events), nil
+}
+
+// listNamespaceEvents returns the events of a namespace by the UID of their
+// object, the most recent first.
+func (r *KopilotReconciler) listNamespaceEvents(ctx context.Context, namespace string) (map[types.UID][]corev1.Event, error) {
+	list, err := r.Clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	events := map[types.UID][]corev1.Event{}
+	for _, event := range list.Items {
+		events[event.InvolvedObject.UID] = append(events[event.InvolvedObject.UID], event)
+	}
+	for uid := range events {
+		sortEvents(events[uid])
+	}
+	return events, nil
+}
+
+// sortEvents sorts events by time, the most recent first.
+func sortEvents(events []corev1.Event) []corev1.Event {
+	sort.SliceStable(events, func(i, j int) bool {
+		return eventTime(events[i]).After(eventTime(events[j]))
+	})
+	return events
+}
+
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	case !event.FirstTimestamp.IsZero():
+		return event.FirstTimestamp.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+func formatEvents(events []corev1.Event) string {
+	var lines []string
+	for i, event := range events {
+		if i == contextEventLimit {
+			lines = append(lines, fmt.Sprintf("... %d older events omitted", len(events)-i))
+			break
+		}
+		line := fmt.Sprintf("%s %s %s", eventTime(event).UTC().Format(time.RFC3339), event.Type, event.Reason)
+		if event.Count > 1 {
+			line += fmt.Sprintf(" (x%d)", event.Count)
+		}
+		lines = append(lines, line+": "+strings.TrimSpace(event.Message))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ownerChain returns the controllers of the pod, the closest first. Owners of
+// other kinds end the chain.
+func (r *KopilotReconciler) ownerChain(ctx context.Context, pod *corev1.Pod) ([]owner, error) {
+	var owners []owner
+	var object client.Object = pod
+	for range ownerChainDepth {
+		ref := metav1.GetControllerOf(object)
+		if ref == nil {
+			break
+		}
+		next, err := r.getOwner(ctx, pod.Namespace, *ref)
+		if err != nil {
+			return owners, err
+		}
+		if next == nil {
+			break
+		}
+		owners = append(owners, owner{kind: ref.Kind, object: next})
+		object = next
+	}
+	return owners, nil
+}
+
+func (r *KopilotReconciler) getOwner(ctx context.Context, namespace string, ref metav1.OwnerReference) (client.Object, error) {
+	opts := metav1.GetOptions{}
+	switch ref.Kind {
+	case "ReplicaSet":
+		return r.Clientset.AppsV1().ReplicaSets(namespace).Get(ctx, ref.Name, opts)
+	case "Deployment":
+		return r.Clientset.AppsV1().Deployments(namespace).Get(ctx, ref.Name, opts)
+	case "StatefulSet":
+		return r.Clientset.AppsV1().StatefulSets(namespace).Get(ctx, ref.Name, opts)
+	case "DaemonSet":
+		return r.Clientset.AppsV1().DaemonSets(namespace).Get(ctx, ref.Name, opts)
+	case "Job":
+		return r.Clientset.BatchV1().Jobs(namespace).Get(ctx, ref.Name, opts)
+	case "CronJob":
+		return r.Clientset.BatchV1().CronJobs(namespace).Get(ctx, ref.Name, opts)
+	}
+	return nil, nil
+}
+
+// formatOwner renders the spec and status of an owner. The pod template is
+// omitted, it is part of the pod YAML.
+func formatOwner(object client.Object) (string, error) {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+	if err != nil {
+		return "", err
+	}
+	unstructured.RemoveNestedField(u, "spec", "template")
+	unstructured.RemoveNestedField(u, "spec", "jobTemplate", "spec", "template")
+	out, err := yaml.Marshal(map[string]any{"spec": u["spec"], "status": u["status"]})
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// replicaSetRevisions returns the ReplicaSets of a Deployment, the most recent
+// revision first.
+func (r *KopilotReconciler) replicaSetRevisions(ctx context.Context, deployment *appsv1.Deployment) ([]appsv1.ReplicaSet, error) {
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	list, err := r.Clientset.AppsV1().ReplicaSets(deployment.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	var replicaSets []appsv1.ReplicaSet
+	for _, rs := range list.Items {
+		if metav1.IsControlledBy(&rs, deployment) {
+			replicaSets = append(replicaSets, rs)
+		}
+	}
+	sort.SliceStable(replicaSets, func(i, j int) bool {
+		return revision(&replicaSets[i]) > revision(&replicaSets[j])
+	})
+	if len(replicaSets) > contextRevisionLimit {
+		replicaSets = replicaSets[:contextRevisionLimit]
+	}
+	return replicaSets, nil
+}
+
+func revision(object metav1.Object) int64 {
+	n, _ := strconv.ParseInt(object.GetAnnotations()[revisionAnnotation], 10, 64)
+	return n
+}
+
+// formatRevisions renders the revisions with the image changes to the
+// previous revision.
+func formatRevisions(deployment *appsv1.Deployment, replicaSets []appsv1.ReplicaSet) string {
+	current := revision(deployment)
+	var b strings.Builder
+	for i, rs := range replicaSets {
+		var replicas int32
+		if rs.Spec.Replicas != nil {
+			replicas = *rs.Spec.Replicas
+		}
+		fmt.Fprintf(&b, "revision %d: %s, replicas %d, ready %d, created %s",
+			revision(&rs), rs.Name, replicas, rs.Status.ReadyReplicas, rs.CreationTimestamp.UTC().Format(time.RFC3339))
+		if revision(&rs) == current {
+			b.WriteString(" (current)")
+		}
+		b.WriteString("\n")
+		for _, container := range rs.Spec.Template.Spec.Containers {
+			fmt.Fprintf(&b, "  image %s: %s\n", container.Name, container.Image)
+		}
+		if i+1 < len(replicaSets) {
+			previous := replicaSets[i+1]
+			for _, change := range imageChanges(previous.Spec.Template.Spec, rs.Spec.Template.Spec) {
+				fmt.Fprintf(&b, "  changed from revision %d: %s\n", revision(&previous), change)
+			}
+		}
+	}
+	return b.String()
+}
+
+// imageChanges describes the container and image changes between two pod specs.
+func imageChanges(before, after corev1.PodSpec) []string {
+	beforeContainers := slices.Concat(before.InitContainers, before.Containers)
+	afterContainers := slices.Concat(after.InitContainers, after.Containers)
+	images := func(containers []corev1.Container) map[string]string {
+		m := map[string]string{}
+		for _, container := range containers {
+			m[container.Name] = container.Image
+		}
+		return m
+	}
+	beforeImages, afterImages := images(beforeContainers), images(afterContainers)
+
+	var changes []string
+	for _, container := range afterContainers {
+		image, ok := beforeImages[container.Name]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("added container %s (%s)", container.Name, container.Image))
+		case image != container.Image:
+			changes = append(changes, fmt.Sprintf("container %s image %s -> %s", container.Name, image, container.Image))
+		}
+	}
+	for _, container := range beforeContainers {
+		if _, ok := afterImages[container.Name]; !ok {
+			changes = append(changes, fmt.Sprintf("removed container %s", container.Name))
+		}
+	}
+	return changes
+}
+
+func formatNode(node *corev1.Node) string {
+	var b strings.Builder
+	for _, condition := range node.Status.Conditions {
+		fmt.Fprintf(&b, "%s=%s", condition.Type, condition.Status)
+		if condition.Reason != "" {
+			fmt.Fprintf(&b, " (%s)", condition.Reason)
+		}
+		if condition.Message != "" {
+			fmt.Fprintf(&b, ": %s", condition.Message)
+		}
+		fmt.Fprintf(&b, ", since %s\n", condition.LastTransitionTime.UTC().Format(time.RFC3339))
+	}
+	if node.Spec.Unschedulable {
+		b.WriteString("Unschedulable: true\n")
+	}
+	for _, taint := range node.Spec.Taints {
+		fmt.Fprintf(&b, "Taint: %s\n", taint.ToString())
+	}
+	allocatable := node.Status.Allocatable
+	fmt.Fprintf(&b, "Allocatable: cpu=%s, memory=%s, pods=%s\n", allocatable.Cpu(), allocatable.Memory(), allocatable.Pods())
+	return b.String()
+}
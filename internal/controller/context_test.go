@@ -0,0 +1,95 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/Fl0rencess720/Kopilot/pkg/llm"
+	"github.com/go-logr/logr"
+)
+
+var _ = Describe("Context gathering", func() {
+	It("should collect events, the owner chain, ReplicaSet revisions and node conditions", func() {
+		isController := true
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "web", Namespace: "default", UID: "deployment-uid",
+				Annotations: map[string]string{revisionAnnotation: "2"},
+			},
+			Spec: appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+		}
+		replicaSet := func(name, revision, image string) *appsv1.ReplicaSet {
+			return &appsv1.ReplicaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name, Namespace: "default", UID: types.UID("rs-" + name),
+					Labels:      map[string]string{"app": "web"},
+					Annotations: map[string]string{revisionAnnotation: revision},
+					OwnerReferences: []metav1.OwnerReference{{
+						Kind: "Deployment", Name: "web", UID: deployment.UID, Controller: &isController,
+					}},
+				},
+				Spec: appsv1.ReplicaSetSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app", Image: image}},
+				}}},
+			}
+		}
+		pod := crashingPod("web-5d4f8-a").Pod
+		pod.UID = "pod-uid"
+		pod.Spec.NodeName = "node-1"
+		pod.OwnerReferences[0].UID = "rs-web-5d4f8"
+
+		r := &KopilotReconciler{Clientset: fake.NewClientset(
+			deployment,
+			replicaSet("web-7c9b6", "1", "web:1.0"),
+			replicaSet("web-5d4f8", "2", "web:1.1"),
+			&corev1.Event{
+				ObjectMeta:     metav1.ObjectMeta{Name: "web-5d4f8-a.1", Namespace: "default"},
+				InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: pod.Name, UID: pod.UID},
+				Type:           corev1.EventTypeWarning,
+				Reason:         "BackOff",
+				Message:        "Back-off restarting failed container app",
+				Count:          4,
+			},
+			&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+				Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{{
+					Type: corev1.NodeMemoryPressure, Status: corev1.ConditionTrue, Reason: "KubeletHasInsufficientMemory",
+				}}},
+			},
+		)}
+
+		sections := r.podContext(context.Background(), logr.Discard(), pod)
+		Expect(sections).To(HaveLen(5))
+		Expect(sections[0].Content).To(ContainSubstring("Warning BackOff (x4): Back-off restarting failed container app"))
+		Expect(sections[1].Title).To(HavePrefix("ReplicaSet web-5d4f8"))
+		Expect(sections[2].Title).To(HavePrefix("Deployment web"))
+		Expect(sections[3].Content).To(ContainSubstring("revision 2: web-5d4f8"))
+		Expect(sections[3].Content).To(ContainSubstring("changed from revision 1: container app image web:1.0 -> web:1.1"))
+		Expect(sections[4].Content).To(ContainSubstring("MemoryPressure=True (KubeletHasInsufficientMemory)"))
+
+		Expect(llm.FormatContext(sections)).To(ContainSubstring("=== Node node-1 ==="))
+	})
+})
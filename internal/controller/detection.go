@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/Fl0rencess720/Kopilot/internal/controller/utils"
+	"github.com/Fl0rencess720/Kopilot/pkg/health"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// healthRuleCache keeps the compiled health rules of every Kopilot, so that
+// they are not compiled again on every run and pod update.
+type healthRuleCache struct {
+	mu      sync.Mutex
+	entries map[types.NamespacedName]compiledRules
+}
+
+type compiledRules struct {
+	rules    []kopilotv1.HealthRule
+	compiled []*health.CELRule
+}
+
+// compile returns the compiled rules of a Kopilot. The cached rules are
+// replaced when the rules of the Kopilot change.
+func (c *healthRuleCache) compile(kopilot types.NamespacedName, rules []kopilotv1.HealthRule) ([]*health.CELRule, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[kopilot]; ok && slices.Equal(entry.rules, rules) {
+		return entry.compiled, nil
+	}
+	compiled := make([]*health.CELRule, 0, len(rules))
+	for _, rule := range rules {
+		celRule, err := health.CompileRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, celRule)
+	}
+	if c.entries == nil {
+		c.entries = map[types.NamespacedName]compiledRules{}
+	}
+	c.entries[kopilot] = compiledRules{rules: slices.Clone(rules), compiled: compiled}
+	return compiled, nil
+}
+
+// forget drops the compiled rules of the given Kopilot.
+func (c *healthRuleCache) forget(kopilot types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, kopilot)
+}
+
+// healthEvaluator returns the health evaluator of the Kopilot, with the
+// user-defined rules if any.
+func (r *KopilotReconciler) healthEvaluator(ctx context.Context, kopilot *kopilotv1.Kopilot) (*health.Evaluator, error) {
+	return r.newHealthEvaluator(kopilot, r.podEvents(ctx))
+}
+
+// triggerHealthEvaluator returns the health evaluator of the Kopilot for pod
+// updates. It skips the rules that use events, which would list the events of
+// every updated pod; they are only evaluated in sweeps.
+func (r *KopilotReconciler) triggerHealthEvaluator(kopilot *kopilotv1.Kopilot) (*health.Evaluator, error) {
+	return r.newHealthEvaluator(kopilot, nil)
+}
+
+func (r *KopilotReconciler) newHealthEvaluator(kopilot *kopilotv1.Kopilot, events health.EventsFunc) (*health.Evaluator, error) {
+	spec := kopilot.Spec
+	evaluator := newHealthEvaluator(spec)
+	if spec.Detection == nil || len(spec.Detection.Rules) == 0 {
+		return evaluator, nil
+	}
+	rules, err := r.healthRules.compile(client.ObjectKeyFromObject(kopilot), spec.Detection.Rules)
+	if err != nil {
+		return nil, err
+	}
+	return evaluator.WithRules(rules, spec.Detection.RulesMode == "replace", events), nil
+}
+
+// podEvents returns a function that returns the events of a pod. The events
+// of a namespace are listed once, when the first pod of the namespace is
+// evaluated, and indexed by the UID of their object, so that a sweep does not
+// list the events of every pod.
+func (r *KopilotReconciler) podEvents(ctx context.Context) health.EventsFunc {
+	type namespaceEvents struct {
+		byUID map[types.UID][]corev1.Event
+		err   error
+	}
+	namespaces := map[string]*namespaceEvents{}
+	return func(pod *corev1.Pod) ([]corev1.Event, error) {
+		events, ok := namespaces[pod.Namespace]
+		if !ok {
+			events = &namespaceEvents{}
+			events.byUID, events.err = r.listNamespaceEvents(ctx, pod.Namespace)
+			namespaces[pod.Namespace] = events
+		}
+		return events.byUID[pod.UID], events.err
+	}
+}
+
+// newHealthEvaluator returns the health evaluator with the thresholds of the spec.
+func newHealthEvaluator(spec kopilotv1.KopilotSpec) *health.Evaluator {
+	thresholds := health.DefaultThresholds
+	if d := spec.Detection; d != nil {
+		if d.RestartCount > 0 {
+			thresholds.RestartCount = d.RestartCount
+		}
+		if d.RestartsPerHour > 0 {
+			thresholds.RestartsPerHour = float64(d.RestartsPerHour)
+		}
+		if d.PendingTimeout != nil {
+			thresholds.PendingTimeout = d.PendingTimeout.Duration
+		}
+		if d.NotReadyTimeout != nil {
+			thresholds.NotReadyTimeout = d.NotReadyTimeout.Duration
+		}
+	}
+	return health.NewEvaluator(thresholds)
+}
+
+func filterUnhealthyPods(evaluator *health.Evaluator, pods []corev1.Pod, now time.Time) []UnHealthyPod {
+	var unhealthyPods []UnHealthyPod
+	for _, pod := range pods {
+		if pod.Kind == "Kopilot" {
+			continue
+		}
+		if classification := evaluator.Evaluate(&pod, now); classification != nil {
+			unhealthyPods = append(unhealthyPods, UnHealthyPod{Pod: pod, Classification: classification})
+		}
+	}
+	return unhealthyPods
+}
+
+// podClassification returns the classification of an unhealthy pod, derived
+// from its container states if the pod was not classified by the evaluator.
+func podClassification(pod UnHealthyPod) health.Classification {
+	if pod.Classification != nil {
+		return *pod.Classification
+	}
+	container, reason := utils.GetPodFailureReason(pod.Pod.Status)
+	return health.Classification{Reason: reason, Container: container, Severity: health.SeverityCritical}
+}
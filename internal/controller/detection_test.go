@@ -0,0 +1,163 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+)
+
+var _ = Describe("Health evaluation", func() {
+	now := time.Now()
+
+	It("should apply the restart count and pending timeout thresholds of the spec", func() {
+		restarting := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "restarting", CreationTimestamp: metav1.NewTime(now.Add(-24 * time.Hour))},
+			Status: corev1.PodStatus{
+				Phase:     corev1.PodRunning,
+				StartTime: &metav1.Time{Time: now.Add(-24 * time.Hour)},
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:         "app",
+					Ready:        true,
+					RestartCount: 3,
+					State:        corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+					LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+						Reason:     "Error",
+						ExitCode:   1,
+						FinishedAt: metav1.NewTime(now.Add(-10 * time.Minute)),
+					}},
+				}},
+			},
+		}
+		pending := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pending", CreationTimestamp: metav1.NewTime(now.Add(-3 * time.Minute))},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodPending,
+				Conditions: []corev1.PodCondition{{
+					Type:    corev1.PodScheduled,
+					Status:  corev1.ConditionFalse,
+					Message: "persistentvolumeclaim \"data\" not found",
+				}},
+			},
+		}
+		pods := []corev1.Pod{restarting, pending}
+
+		Expect(filterUnhealthyPods(newHealthEvaluator(kopilotv1.KopilotSpec{}), pods, now)).To(BeEmpty())
+
+		spec := kopilotv1.KopilotSpec{Detection: &kopilotv1.DetectionSpec{
+			RestartCount:   3,
+			PendingTimeout: &metav1.Duration{Duration: time.Minute},
+		}}
+		unhealthyPods := filterUnhealthyPods(newHealthEvaluator(spec), pods, now)
+		Expect(unhealthyPods).To(HaveLen(2))
+		Expect(unhealthyPods[0].Classification.Reason).To(Equal("RestartStorm"))
+		Expect(unhealthyPods[1].Classification.Reason).To(Equal("Unschedulable"))
+		Expect(unhealthyPods[1].Classification.Message).To(ContainSubstring("persistentvolumeclaim"))
+	})
+
+	It("should evaluate the rules that use events only in sweeps", func() {
+		pod := crashingPod("web-5d4f8-a").Pod
+		pod.UID = "pod-uid"
+		r := &KopilotReconciler{Clientset: fake.NewClientset(&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "web-5d4f8-a.1", Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: pod.Name, Namespace: "default", UID: pod.UID},
+			Reason:         "FailedMount",
+		})}
+		kopilot := &kopilotv1.Kopilot{
+			ObjectMeta: metav1.ObjectMeta{Name: "kopilot", Namespace: "default"},
+			Spec: kopilotv1.KopilotSpec{Detection: &kopilotv1.DetectionSpec{
+				RulesMode: "replace",
+				Rules: []kopilotv1.HealthRule{{
+					Name:       "MountFailed",
+					Expression: `events.exists(e, e.reason == 'FailedMount')`,
+				}},
+			}},
+		}
+
+		evaluator, err := r.healthEvaluator(context.Background(), kopilot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(evaluator.Evaluate(&pod, now)).To(HaveField("Reason", "MountFailed"))
+
+		evaluator, err = r.triggerHealthEvaluator(kopilot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(evaluator.Evaluate(&pod, now)).To(BeNil())
+
+		// A changed rule replaces the cached rules of the Kopilot.
+		kopilot.Spec.Detection.Rules[0].Expression = `pod.metadata.name == 'web-5d4f8-a'`
+		evaluator, err = r.triggerHealthEvaluator(kopilot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(evaluator.Evaluate(&pod, now)).To(HaveField("Reason", "MountFailed"))
+		Expect(r.healthRules.entries).To(HaveLen(1))
+	})
+
+	It("should list the events of a namespace once per sweep", func() {
+		event := func(name, namespace string, uid types.UID, reason string) *corev1.Event {
+			return &corev1.Event{
+				ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: namespace},
+				InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: namespace, UID: uid},
+				Reason:         reason,
+			}
+		}
+		clientset := fake.NewClientset(
+			event("a.1", "default", "uid-a", "FailedMount"),
+			event("b.1", "default", "uid-b", "Pulled"),
+			event("c.1", "other", "uid-c", "FailedMount"),
+		)
+		r := &KopilotReconciler{Clientset: clientset}
+		kopilot := &kopilotv1.Kopilot{
+			ObjectMeta: metav1.ObjectMeta{Name: "kopilot", Namespace: "default"},
+			Spec: kopilotv1.KopilotSpec{Detection: &kopilotv1.DetectionSpec{
+				RulesMode: "replace",
+				Rules: []kopilotv1.HealthRule{{
+					Name:       "MountFailed",
+					Expression: `events.exists(e, e.reason == 'FailedMount')`,
+				}},
+			}},
+		}
+		pod := func(name, namespace string, uid types.UID) corev1.Pod {
+			return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, UID: uid}}
+		}
+
+		evaluator, err := r.healthEvaluator(context.Background(), kopilot)
+		Expect(err).NotTo(HaveOccurred())
+		unhealthyPods := filterUnhealthyPods(evaluator, []corev1.Pod{
+			pod("a", "default", "uid-a"),
+			pod("b", "default", "uid-b"),
+			pod("c", "other", "uid-c"),
+		}, now)
+		Expect(unhealthyPods).To(HaveLen(2))
+		Expect(unhealthyPods[0].Pod.Name).To(Equal("a"))
+		Expect(unhealthyPods[1].Pod.Name).To(Equal("c"))
+
+		var lists []string
+		for _, action := range clientset.Actions() {
+			if action.Matches("list", "events") {
+				lists = append(lists, action.GetNamespace())
+			}
+		}
+		Expect(lists).To(Equal([]string{"default", "other"}))
+	})
+})
@@ -0,0 +1,376 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/Fl0rencess720/Kopilot/internal/controller/utils"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	defaultReNotifyInterval  = 24 * time.Hour
+	defaultResolvedRetention = 7 * 24 * time.Hour
+	// resolveAfterMissedSweeps is the number of consecutive sweeps that must
+	// not observe an incident before it is resolved, so that a crash looping
+	// pod that is running between two crashes does not resolve it.
+	resolveAfterMissedSweeps = 3
+)
+
+// incident is an open problem of a workload, identified by its fingerprint.
+type incident struct {
+	fingerprint string
+	// target is the target of the Kopilot that detected the incident, or
+	// empty if it is unknown.
+	target    string
+	namespace string
+	ownerKind string
+	ownerName string
+	container string
+	reason    string
+	// pod is the analyzed pod of the incident, and pods all of its pods.
+	pod          string
+	pods         sets.Set[string]
+	firstSeen    time.Time
+	lastSeen     time.Time
+	lastAnalyzed time.Time
+	// missedSweeps is the number of consecutive sweeps that did not observe
+	// the incident.
+	missedSweeps int
+	// name is the name of the Incident object recording this incident.
+	name         string
+	acknowledged bool
+}
+
+// nodeName returns the name of the node of a node incident.
+func (inc *incident) nodeName() string {
+	if inc.ownerKind != "Node" {
+		return ""
+	}
+	return inc.ownerName
+}
+
+// incidentTracker keeps the open incidents of every Kopilot so that the same
+// failure is not analyzed and notified on every run.
+type incidentTracker struct {
+	mu sync.Mutex
+	// incidents maps a Kopilot to its open incidents, keyed by fingerprint.
+	incidents map[types.NamespacedName]map[string]*incident
+}
+
+// crashLoopReasons are the failure reasons that alternate during a single
+// crash loop. They share a fingerprint, so that the crash loop is one incident.
+var crashLoopReasons = sets.New("CrashLoopBackOff", "OOMKilled", "RestartStorm", "Error")
+
+// fingerprintPod computes the incident fingerprint of an unhealthy pod from its
+// owner workload, failing container and failure reason.
+func fingerprintPod(unhealthyPod UnHealthyPod) (string, *incident) {
+	pod := unhealthyPod.Pod
+	ownerKind, ownerName := unhealthyPod.OwnerKind, unhealthyPod.OwnerName
+	if ownerKind == "" {
+		ownerKind, ownerName = utils.GetPodOwner(pod)
+	}
+	classification := podClassification(unhealthyPod)
+	return newIncident(targetPods, pod.Namespace, ownerKind, ownerName, classification.Container, classification.Reason)
+}
+
+// fingerprintNode computes the incident fingerprint of an unhealthy node from
+// its name and failure reason. Node incidents have the node as owner.
+func fingerprintNode(node UnHealthyNode) (string, *incident) {
+	return newIncident(targetNodes, "", "Node", node.Node.Name, "", node.Classification.Reason)
+}
+
+// fingerprintRollout computes the incident fingerprint of a stalled rollout
+// from its workload.
+func fingerprintRollout(rollout StalledRollout) (string, *incident) {
+	return newIncident(targetRollouts, rollout.Object.GetNamespace(), rollout.Kind, rollout.Object.GetName(), "", rollout.Classification.Reason)
+}
+
+// newIncident returns the fingerprint and a new incident of the given target.
+// The reasons of a crash loop are fingerprinted as one.
+func newIncident(target, namespace, ownerKind, ownerName, container, reason string) (string, *incident) {
+	fingerprintReason := reason
+	if crashLoopReasons.Has(reason) {
+		fingerprintReason = "CrashLoop"
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%s/%s/%s/%s", target, namespace, ownerKind, ownerName, container, fingerprintReason)))
+	fingerprint := hex.EncodeToString(sum[:])[:16]
+
+	return fingerprint, &incident{
+		fingerprint: fingerprint,
+		target:      target,
+		namespace:   namespace,
+		ownerKind:   ownerKind,
+		ownerName:   ownerName,
+		container:   container,
+		reason:      reason,
+		pods:        sets.New[string](),
+	}
+}
+
+// observe records the given unhealthy pods and returns the ones that need to be
+// analyzed: one pod per incident that is new or whose re-notify interval has passed.
+func (t *incidentTracker) observe(kopilot types.NamespacedName, pods []UnHealthyPod, reNotifyInterval time.Duration, now time.Time) []UnHealthyPod {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	open := t.open(kopilot)
+	selected := sets.New[string]()
+	var toAnalyze []UnHealthyPod
+	for _, pod := range pods {
+		_, observed := fingerprintPod(pod)
+		inc := track(open, observed, pod.Pod.Name, now)
+		if selected.Has(inc.fingerprint) || !inc.due(reNotifyInterval, now) {
+			continue
+		}
+		selected.Insert(inc.fingerprint)
+		pod.Fingerprint = inc.fingerprint
+		toAnalyze = append(toAnalyze, pod)
+	}
+	return toAnalyze
+}
+
+// observeNodes records the given unhealthy nodes and returns the ones that
+// need to be analyzed.
+func (t *incidentTracker) observeNodes(kopilot types.NamespacedName, nodes []UnHealthyNode, reNotifyInterval time.Duration, now time.Time) []UnHealthyNode {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	open := t.open(kopilot)
+	var toAnalyze []UnHealthyNode
+	for _, node := range nodes {
+		_, observed := fingerprintNode(node)
+		inc := track(open, observed, "", now)
+		if !inc.due(reNotifyInterval, now) {
+			continue
+		}
+		node.Fingerprint = inc.fingerprint
+		toAnalyze = append(toAnalyze, node)
+	}
+	return toAnalyze
+}
+
+// observeRollouts records the given stalled rollouts and returns the ones that
+// need to be analyzed.
+func (t *incidentTracker) observeRollouts(kopilot types.NamespacedName, rollouts []StalledRollout, reNotifyInterval time.Duration, now time.Time) []StalledRollout {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	open := t.open(kopilot)
+	var toAnalyze []StalledRollout
+	for _, rollout := range rollouts {
+		_, observed := fingerprintRollout(rollout)
+		inc := track(open, observed, "", now)
+		if !inc.due(reNotifyInterval, now) {
+			continue
+		}
+		rollout.Fingerprint = inc.fingerprint
+		toAnalyze = append(toAnalyze, rollout)
+	}
+	return toAnalyze
+}
+
+// open returns the open incidents of a Kopilot. The caller must hold t.mu.
+func (t *incidentTracker) open(kopilot types.NamespacedName) map[string]*incident {
+	if t.incidents == nil {
+		t.incidents = map[types.NamespacedName]map[string]*incident{}
+	}
+	if t.incidents[kopilot] == nil {
+		t.incidents[kopilot] = map[string]*incident{}
+	}
+	return t.incidents[kopilot]
+}
+
+// track records an observation of the incident, with the given pod if it is
+// not empty, and returns the open incident with its fingerprint. The reason of
+// the incident is the last observed one.
+func track(open map[string]*incident, observed *incident, pod string, now time.Time) *incident {
+	inc, ok := open[observed.fingerprint]
+	if !ok {
+		inc = observed
+		inc.firstSeen = now
+		open[observed.fingerprint] = inc
+	}
+	if pod != "" {
+		inc.pods.Insert(pod)
+	}
+	inc.reason = observed.reason
+	inc.lastSeen = now
+	inc.missedSweeps = 0
+	return inc
+}
+
+// due reports whether the incident needs to be analyzed and notified: it is
+// new, or its re-notify interval has passed and it is not acknowledged.
+func (inc *incident) due(reNotifyInterval time.Duration, now time.Time) bool {
+	return inc.lastAnalyzed.IsZero() || (!inc.acknowledged && now.Sub(inc.lastAnalyzed) >= reNotifyInterval)
+}
+
+// restore adds the open Incident objects of a Kopilot that are not tracked yet,
+// e.g. after a restart of the operator, and refreshes their acknowledgement.
+func (t *incidentTracker) restore(kopilot types.NamespacedName, items []kopilotv1.Incident) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	open := t.open(kopilot)
+	for _, item := range items {
+		if item.Status.Phase == kopilotv1.IncidentPhaseResolved {
+			continue
+		}
+		inc, ok := open[item.Spec.Fingerprint]
+		if !ok {
+			inc = &incident{
+				fingerprint: item.Spec.Fingerprint,
+				container:   item.Spec.Container,
+				reason:      item.Spec.FailureReason,
+				pods:        sets.New(item.Status.AffectedPods...),
+				firstSeen:   item.CreationTimestamp.Time,
+			}
+			if item.Spec.PodRef != nil {
+				inc.namespace = item.Spec.PodRef.Namespace
+				inc.pod = item.Spec.PodRef.Name
+			}
+			if item.Spec.OwnerRef != nil {
+				inc.ownerKind = item.Spec.OwnerRef.Kind
+				inc.ownerName = item.Spec.OwnerRef.Name
+			}
+			if item.Status.FirstSeen != nil {
+				inc.firstSeen = item.Status.FirstSeen.Time
+			}
+			if item.Status.LastAnalyzed != nil {
+				inc.lastAnalyzed = item.Status.LastAnalyzed.Time
+			}
+			inc.target = incidentTarget(inc)
+			open[item.Spec.Fingerprint] = inc
+		}
+		inc.name = item.Name
+		inc.acknowledged = item.Spec.Acknowledged
+	}
+}
+
+// incidentTarget returns the target of a restored incident whose fingerprint
+// matches the fingerprint computed for the target, or empty if none matches.
+func incidentTarget(inc *incident) string {
+	for _, target := range []string{targetPods, targetNodes, targetRollouts} {
+		if fingerprint, _ := newIncident(target, inc.namespace, inc.ownerKind, inc.ownerName, inc.container, inc.reason); fingerprint == inc.fingerprint {
+			return target
+		}
+	}
+	return ""
+}
+
+// get returns a copy of the open incident with the given fingerprint, or nil
+// if there is none. The incident is changed with the setters of the tracker.
+func (t *incidentTracker) get(kopilot types.NamespacedName, fingerprint string) *incident {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	inc, ok := t.incidents[kopilot][fingerprint]
+	if !ok {
+		return nil
+	}
+	c := *inc
+	c.pods = inc.pods.Clone()
+	return &c
+}
+
+// setPod records the analyzed pod of the incident with the given fingerprint.
+func (t *incidentTracker) setPod(kopilot types.NamespacedName, fingerprint, pod string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if inc, ok := t.incidents[kopilot][fingerprint]; ok {
+		inc.pod = pod
+	}
+}
+
+// setName records the name of the Incident object of the incident with the
+// given fingerprint.
+func (t *incidentTracker) setName(kopilot types.NamespacedName, fingerprint, name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if inc, ok := t.incidents[kopilot][fingerprint]; ok {
+		inc.name = name
+	}
+}
+
+// markAnalyzed records that the incident with the given fingerprint has been analyzed and notified.
+func (t *incidentTracker) markAnalyzed(kopilot types.NamespacedName, fingerprint string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if inc, ok := t.incidents[kopilot][fingerprint]; ok {
+		inc.lastAnalyzed = now
+	}
+}
+
+// resolve counts a missed sweep for the open incidents that were not observed
+// in the sweep that started at the given time, then removes and returns the
+// ones that were missed by resolveAfterMissedSweeps consecutive sweeps. The
+// incidents of the failed targets, whose detection failed in the sweep, are
+// not missed; neither are the incidents of an unknown target if any failed.
+func (t *incidentTracker) resolve(kopilot types.NamespacedName, sweepStart time.Time, failedTargets sets.Set[string]) []*incident {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var resolved []*incident
+	for fingerprint, inc := range t.incidents[kopilot] {
+		if !inc.lastSeen.Before(sweepStart) || failedTargets.Has(inc.target) || (inc.target == "" && failedTargets.Len() > 0) {
+			continue
+		}
+		inc.missedSweeps++
+		if inc.missedSweeps >= resolveAfterMissedSweeps {
+			resolved = append(resolved, inc)
+			delete(t.incidents[kopilot], fingerprint)
+		}
+	}
+	return resolved
+}
+
+// forget drops all incidents of the given Kopilot.
+func (t *incidentTracker) forget(kopilot types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.incidents, kopilot)
+}
+
+func reNotifyInterval(spec kopilotv1.KopilotSpec) time.Duration {
+	if spec.Deduplication != nil && spec.Deduplication.ReNotifyInterval != nil {
+		return spec.Deduplication.ReNotifyInterval.Duration
+	}
+	return defaultReNotifyInterval
+}
+
+func resolvedRetention(spec kopilotv1.KopilotSpec) time.Duration {
+	if spec.Deduplication != nil && spec.Deduplication.ResolvedRetention != nil {
+		return spec.Deduplication.ResolvedRetention.Duration
+	}
+	return defaultResolvedRetention
+}
+
+func notifyResolved(spec kopilotv1.KopilotSpec) bool {
+	return spec.Deduplication == nil || spec.Deduplication.NotifyResolved
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// maxLogsExcerptBytes bounds the size of the logs kept in an Incident.
+const maxLogsExcerptBytes = 4096
+
+// syncIncidents loads the open Incident objects of the Kopilot into the
+// incident tracker and moves acknowledged ones to the Acknowledged phase.
+func (r *KopilotReconciler) syncIncidents(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot) {
+	var incidents kopilotv1.IncidentList
+	if err := r.List(ctx, &incidents, client.InNamespace(kopilot.Namespace),
+		client.MatchingLabels{kopilotv1.IncidentKopilotLabel: kopilot.Name}); err != nil {
+		l.Error(err, "unable to list incidents")
+		return
+	}
+
+	r.incidents.restore(client.ObjectKeyFromObject(kopilot), incidents.Items)
+
+	for i := range incidents.Items {
+		item := &incidents.Items[i]
+		if !item.Spec.Acknowledged ||
+			item.Status.Phase == kopilotv1.IncidentPhaseAcknowledged || item.Status.Phase == kopilotv1.IncidentPhaseResolved {
+			continue
+		}
+		item.Status.Phase = kopilotv1.IncidentPhaseAcknowledged
+		if err := r.Status().Update(ctx, item); err != nil {
+			l.Error(err, "unable to update incident status", "incident", item.Name)
+		}
+	}
+}
+
+// recordIncident creates or updates the Incident object of an analyzed pod,
+// or of an analyzed node if podRef is nil.
+func (r *KopilotReconciler) recordIncident(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, fingerprint string, podRef *kopilotv1.PodReference,
+	logs string, analysis *kopilotv1.IncidentAnalysis, remediation *kopilotv1.IncidentRemediation, now time.Time) {
+	key := client.ObjectKeyFromObject(kopilot)
+	inc := r.incidents.get(key, fingerprint)
+	if inc == nil {
+		return
+	}
+	if podRef != nil {
+		r.incidents.setPod(key, fingerprint, podRef.Name)
+	}
+
+	var obj kopilotv1.Incident
+	if inc.name != "" {
+		if err := r.Get(ctx, types.NamespacedName{Namespace: kopilot.Namespace, Name: inc.name}, &obj); err != nil {
+			if !apierrors.IsNotFound(err) {
+				l.Error(err, "unable to get incident", "incident", inc.name)
+				return
+			}
+			inc.name = ""
+			r.incidents.setName(key, fingerprint, "")
+		}
+	}
+
+	if inc.name == "" {
+		obj = kopilotv1.Incident{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: kopilot.Name + "-",
+				Namespace:    kopilot.Namespace,
+				Labels: map[string]string{
+					kopilotv1.IncidentKopilotLabel:     kopilot.Name,
+					kopilotv1.IncidentFingerprintLabel: inc.fingerprint,
+				},
+			},
+			Spec: kopilotv1.IncidentSpec{
+				KopilotRef:  kopilot.Name,
+				Fingerprint: inc.fingerprint,
+				PodRef:      podRef,
+				NodeName:    inc.nodeName(),
+				OwnerRef: &kopilotv1.WorkloadReference{
+					Kind: inc.ownerKind,
+					Name: inc.ownerName,
+				},
+				Container:     inc.container,
+				FailureReason: inc.reason,
+			},
+		}
+		if r.Scheme != nil {
+			if err := controllerutil.SetControllerReference(kopilot, &obj, r.Scheme); err != nil {
+				l.Error(err, "unable to set owner reference on incident")
+			}
+		}
+		if err := r.Create(ctx, &obj); err != nil {
+			l.Error(err, "unable to create incident", "fingerprint", inc.fingerprint)
+			return
+		}
+		r.incidents.setName(key, fingerprint, obj.Name)
+	} else if (podRef != nil && !equality.Semantic.DeepEqual(obj.Spec.PodRef, podRef)) || obj.Spec.FailureReason != inc.reason {
+		if podRef != nil {
+			obj.Spec.PodRef = podRef
+		}
+		obj.Spec.FailureReason = inc.reason
+		if err := r.Update(ctx, &obj); err != nil {
+			l.Error(err, "unable to update incident", "incident", obj.Name)
+			return
+		}
+	}
+
+	phase := kopilotv1.IncidentPhaseOpen
+	if remediation != nil && remediation.AutoFixResult != "" {
+		phase = kopilotv1.IncidentPhaseRemediating
+	}
+	if obj.Spec.Acknowledged {
+		phase = kopilotv1.IncidentPhaseAcknowledged
+	}
+
+	obj.Status.Phase = phase
+	obj.Status.AffectedPods = sets.List(inc.pods)
+	obj.Status.LogsExcerpt = logsExcerpt(logs)
+	obj.Status.Analysis = analysis
+	obj.Status.Remediation = remediation
+	obj.Status.FirstSeen = &metav1.Time{Time: inc.firstSeen}
+	obj.Status.LastAnalyzed = &metav1.Time{Time: now}
+	if err := r.Status().Update(ctx, &obj); err != nil {
+		l.Error(err, "unable to update incident status", "incident", obj.Name)
+	}
+}
+
+// resolveIncident moves the Incident object of a resolved incident to the Resolved phase.
+func (r *KopilotReconciler) resolveIncident(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, inc *incident, now time.Time) {
+	if inc.name == "" {
+		return
+	}
+
+	var obj kopilotv1.Incident
+	if err := r.Get(ctx, types.NamespacedName{Namespace: kopilot.Namespace, Name: inc.name}, &obj); err != nil {
+		if !apierrors.IsNotFound(err) {
+			l.Error(err, "unable to get incident", "incident", inc.name)
+		}
+		return
+	}
+
+	obj.Status.Phase = kopilotv1.IncidentPhaseResolved
+	obj.Status.AffectedPods = sets.List(inc.pods)
+	obj.Status.ResolvedAt = &metav1.Time{Time: now}
+	if err := r.Status().Update(ctx, &obj); err != nil {
+		l.Error(err, "unable to update incident status", "incident", obj.Name)
+	}
+}
+
+// pruneResolvedIncidents deletes the Resolved Incident objects of the Kopilot
+// that were resolved longer ago than the resolved retention.
+func (r *KopilotReconciler) pruneResolvedIncidents(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, now time.Time) {
+	var incidents kopilotv1.IncidentList
+	if err := r.List(ctx, &incidents, client.InNamespace(kopilot.Namespace),
+		client.MatchingLabels{kopilotv1.IncidentKopilotLabel: kopilot.Name}); err != nil {
+		l.Error(err, "unable to list incidents")
+		return
+	}
+
+	retention := resolvedRetention(kopilot.Spec)
+	for i := range incidents.Items {
+		item := &incidents.Items[i]
+		if item.Status.Phase != kopilotv1.IncidentPhaseResolved {
+			continue
+		}
+		resolvedAt := item.CreationTimestamp.Time
+		if item.Status.ResolvedAt != nil {
+			resolvedAt = item.Status.ResolvedAt.Time
+		}
+		if now.Sub(resolvedAt) < retention {
+			continue
+		}
+		if err := r.Delete(ctx, item); client.IgnoreNotFound(err) != nil {
+			l.Error(err, "unable to delete incident", "incident", item.Name)
+		}
+	}
+}
+
+// podReference returns the reference of a pod in an Incident, or nil for the
+// pod template of a failed Job without pods.
+func podReference(pod corev1.Pod) *kopilotv1.PodReference {
+	if pod.Name == "" {
+		return nil
+	}
+	return &kopilotv1.PodReference{
+		Namespace: pod.Namespace,
+		Name:      pod.Name,
+		UID:       string(pod.UID),
+	}
+}
+
+// logsExcerpt keeps the tail of the logs, which usually holds the error.
+func logsExcerpt(logs string) string {
+	if len(logs) <= maxLogsExcerptBytes {
+		return logs
+	}
+	start := len(logs) - maxLogsExcerptBytes
+	for start < len(logs) && !utf8.RuneStart(logs[start]) {
+		start++
+	}
+	return logs[start:]
+}
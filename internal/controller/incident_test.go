@@ -0,0 +1,229 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/Fl0rencess720/Kopilot/pkg/health"
+	"github.com/go-logr/logr"
+)
+
+func crashingPod(name string) UnHealthyPod {
+	isController := true
+	return UnHealthyPod{Pod: corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{"pod-template-hash": "5d4f8"},
+			OwnerReferences: []metav1.OwnerReference{{
+				Kind:       "ReplicaSet",
+				Name:       "web-5d4f8",
+				Controller: &isController,
+			}},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "app",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			}},
+		},
+	}}
+}
+
+var _ = Describe("Incident tracker", func() {
+	kopilot := types.NamespacedName{Name: "kopilot", Namespace: "default"}
+
+	It("should analyze one pod per incident and skip it until the re-notify interval passes", func() {
+		tracker := &incidentTracker{}
+		now := time.Now()
+
+		toAnalyze := tracker.observe(kopilot, []UnHealthyPod{crashingPod("web-5d4f8-a"), crashingPod("web-5d4f8-b")}, time.Hour, now)
+		Expect(toAnalyze).To(HaveLen(1))
+		tracker.markAnalyzed(kopilot, toAnalyze[0].Fingerprint, now)
+
+		Expect(tracker.observe(kopilot, []UnHealthyPod{crashingPod("web-5d4f8-a")}, time.Hour, now.Add(time.Minute))).To(BeEmpty())
+		Expect(tracker.observe(kopilot, []UnHealthyPod{crashingPod("web-5d4f8-a")}, time.Hour, now.Add(2*time.Hour))).To(HaveLen(1))
+	})
+
+	It("should resolve incidents that were not observed in consecutive sweeps", func() {
+		tracker := &incidentTracker{}
+		now := time.Now()
+
+		tracker.observe(kopilot, []UnHealthyPod{crashingPod("web-5d4f8-a")}, time.Hour, now)
+		Expect(tracker.resolve(kopilot, now, nil)).To(BeEmpty())
+
+		for i := 1; i < resolveAfterMissedSweeps; i++ {
+			Expect(tracker.resolve(kopilot, now.Add(time.Duration(i)*time.Minute), nil)).To(BeEmpty())
+		}
+		resolved := tracker.resolve(kopilot, now.Add(time.Hour), nil)
+		Expect(resolved).To(HaveLen(1))
+		Expect(resolved[0].ownerKind).To(Equal("Deployment"))
+		Expect(resolved[0].ownerName).To(Equal("web"))
+	})
+
+	It("should keep a single incident for a crash loop whose pod alternates between states", func() {
+		tracker := &incidentTracker{}
+		start := time.Now()
+		classified := func(reason string) UnHealthyPod {
+			pod := crashingPod("web-5d4f8-a")
+			pod.Classification = &health.Classification{Reason: reason, Container: "app", Severity: health.SeverityCritical}
+			return pod
+		}
+		// Between crashes the pod is running and not unhealthy at all.
+		sweeps := [][]UnHealthyPod{
+			{crashingPod("web-5d4f8-a")},
+			nil,
+			{classified("OOMKilled")},
+			{classified("RestartStorm")},
+			nil,
+			nil,
+			{crashingPod("web-5d4f8-a")},
+		}
+
+		var analyzed int
+		for i, pods := range sweeps {
+			now := start.Add(time.Duration(i) * time.Minute)
+			for _, pod := range tracker.observe(kopilot, pods, time.Hour, now) {
+				tracker.markAnalyzed(kopilot, pod.Fingerprint, now)
+				analyzed++
+			}
+			Expect(tracker.resolve(kopilot, now, nil)).To(BeEmpty(), "sweep %d", i)
+		}
+		Expect(analyzed).To(Equal(1))
+	})
+
+	It("should analyze a workload again when its failure reason changes beyond a crash loop", func() {
+		tracker := &incidentTracker{}
+		now := time.Now()
+		classified := func(reason string) UnHealthyPod {
+			pod := crashingPod("web-5d4f8-a")
+			pod.Classification = &health.Classification{Reason: reason, Container: "app", Severity: health.SeverityCritical}
+			return pod
+		}
+
+		toAnalyze := tracker.observe(kopilot, []UnHealthyPod{classified("ImagePullBackOff")}, time.Hour, now)
+		Expect(toAnalyze).To(HaveLen(1))
+		tracker.markAnalyzed(kopilot, toAnalyze[0].Fingerprint, now)
+
+		toAnalyze = tracker.observe(kopilot, []UnHealthyPod{classified("CrashLoopBackOff")}, time.Hour, now.Add(time.Minute))
+		Expect(toAnalyze).To(HaveLen(1))
+		tracker.markAnalyzed(kopilot, toAnalyze[0].Fingerprint, now.Add(time.Minute))
+
+		Expect(tracker.observe(kopilot, []UnHealthyPod{classified("OOMKilled")}, time.Hour, now.Add(2*time.Minute))).To(BeEmpty())
+		Expect(tracker.get(kopilot, toAnalyze[0].Fingerprint).reason).To(Equal("OOMKilled"))
+	})
+
+	It("should not resolve the incidents of a target whose detection failed", func() {
+		tracker := &incidentTracker{}
+		now := time.Now()
+
+		tracker.observe(kopilot, []UnHealthyPod{crashingPod("web-5d4f8-a")}, time.Hour, now)
+		fingerprint, _ := fingerprintPod(crashingPod("web-5d4f8-a"))
+		tracker.restore(kopilot, []kopilotv1.Incident{{
+			ObjectMeta: metav1.ObjectMeta{Name: "kopilot-node"},
+			Spec: kopilotv1.IncidentSpec{
+				Fingerprint: "0123456789abcdef",
+				OwnerRef:    &kopilotv1.WorkloadReference{Kind: "Node", Name: "node-a"},
+			},
+		}})
+		Expect(tracker.get(kopilot, fingerprint).target).To(Equal(targetPods))
+		Expect(tracker.get(kopilot, "0123456789abcdef").target).To(BeEmpty())
+
+		for i := 1; i <= resolveAfterMissedSweeps; i++ {
+			Expect(tracker.resolve(kopilot, now.Add(time.Duration(i)*time.Minute), sets.New(targetPods))).To(BeEmpty())
+		}
+		// The incident of an unknown target is missed only if no target failed.
+		for i := 1; i < resolveAfterMissedSweeps; i++ {
+			Expect(tracker.resolve(kopilot, now.Add(time.Duration(i)*time.Hour), sets.New(targetNodes))).To(BeEmpty())
+		}
+		resolved := tracker.resolve(kopilot, now.Add(24*time.Hour), nil)
+		Expect(resolved).To(HaveLen(1))
+		Expect(resolved[0].fingerprint).To(Equal(fingerprint))
+	})
+
+	It("should return copies of the tracked incidents", func() {
+		tracker := &incidentTracker{}
+		tracker.observe(kopilot, []UnHealthyPod{crashingPod("web-5d4f8-a")}, time.Hour, time.Now())
+		fingerprint, _ := fingerprintPod(crashingPod("web-5d4f8-a"))
+
+		inc := tracker.get(kopilot, fingerprint)
+		inc.name = "kopilot-abcde"
+		inc.pods.Insert("web-5d4f8-b")
+		Expect(tracker.get(kopilot, fingerprint).name).To(BeEmpty())
+		Expect(tracker.get(kopilot, fingerprint).pods.UnsortedList()).To(ConsistOf("web-5d4f8-a"))
+
+		tracker.setName(kopilot, fingerprint, "kopilot-abcde")
+		tracker.setPod(kopilot, fingerprint, "web-5d4f8-a")
+		inc = tracker.get(kopilot, fingerprint)
+		Expect(inc.name).To(Equal("kopilot-abcde"))
+		Expect(inc.pod).To(Equal("web-5d4f8-a"))
+		Expect(tracker.get(kopilot, "0123456789abcdef")).To(BeNil())
+	})
+
+	It("should delete the Resolved incidents older than the resolved retention", func() {
+		scheme := runtime.NewScheme()
+		Expect(kopilotv1.AddToScheme(scheme)).To(Succeed())
+		now := time.Now()
+		item := func(name string, phase kopilotv1.IncidentPhase, resolvedAt time.Time) *kopilotv1.Incident {
+			obj := &kopilotv1.Incident{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name, Namespace: kopilot.Namespace,
+					Labels: map[string]string{kopilotv1.IncidentKopilotLabel: kopilot.Name},
+				},
+				Status: kopilotv1.IncidentStatus{Phase: phase},
+			}
+			if !resolvedAt.IsZero() {
+				obj.Status.ResolvedAt = &metav1.Time{Time: resolvedAt}
+			}
+			return obj
+		}
+		r := &KopilotReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			item("old", kopilotv1.IncidentPhaseResolved, now.Add(-2*time.Hour)),
+			item("recent", kopilotv1.IncidentPhaseResolved, now.Add(-time.Minute)),
+			item("open", kopilotv1.IncidentPhaseOpen, time.Time{}),
+		).Build()}
+
+		r.pruneResolvedIncidents(context.Background(), logr.Discard(), &kopilotv1.Kopilot{
+			ObjectMeta: metav1.ObjectMeta{Name: kopilot.Name, Namespace: kopilot.Namespace},
+			Spec: kopilotv1.KopilotSpec{Deduplication: &kopilotv1.DeduplicationSpec{
+				ResolvedRetention: &metav1.Duration{Duration: time.Hour},
+			}},
+		}, now)
+
+		var incidents kopilotv1.IncidentList
+		Expect(r.List(context.Background(), &incidents, client.InNamespace(kopilot.Namespace))).To(Succeed())
+		var names []string
+		for _, inc := range incidents.Items {
+			names = append(names, inc.Name)
+		}
+		Expect(names).To(ConsistOf("recent", "open"))
+	})
+})
@@ -0,0 +1,392 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/Fl0rencess720/Kopilot/internal/controller/utils"
+	"github.com/Fl0rencess720/Kopilot/pkg/health"
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// contextJobHistoryLimit is the number of most recent Jobs of a CronJob.
+const contextJobHistoryLimit = 10
+
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=list
+
+// applyJobStatus classifies the unhealthy pods of Jobs by the status of their
+// Job. The pods of a failed Job are analyzed as one failure of the Job, through
+// its last failed pod, and the failed pods of a Job that eventually completed
+// are dropped.
+func (r *KopilotReconciler) applyJobStatus(ctx context.Context, l logr.Logger, unhealthyPods []UnHealthyPod) []UnHealthyPod {
+	jobs := map[types.UID]*batchv1.Job{}
+	// last is the index of the last failed pod of each failed Job.
+	last := map[types.UID]int{}
+	for i, pod := range unhealthyPods {
+		ref := metav1.GetControllerOf(&pod.Pod)
+		if ref == nil || ref.Kind != "Job" {
+			continue
+		}
+		job, ok := jobs[ref.UID]
+		if !ok {
+			var err error
+			job, err = r.Clientset.BatchV1().Jobs(pod.Pod.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+			if err != nil {
+				if !apierrors.IsNotFound(err) {
+					l.Error(err, "unable to get job", "job", ref.Name, "namespace", pod.Pod.Namespace)
+				}
+				job = nil
+			}
+			jobs[ref.UID] = job
+		}
+		if job == nil || health.EvaluateJob(job) == nil {
+			continue
+		}
+		if j, ok := last[ref.UID]; !ok || podFinishedAt(pod).After(podFinishedAt(unhealthyPods[j])) {
+			last[ref.UID] = i
+		}
+	}
+
+	var result []UnHealthyPod
+	for i, pod := range unhealthyPods {
+		ref := metav1.GetControllerOf(&pod.Pod)
+		if ref == nil || ref.Kind != "Job" || jobs[ref.UID] == nil {
+			result = append(result, pod)
+			continue
+		}
+		job := jobs[ref.UID]
+		if health.JobComplete(job) {
+			continue
+		}
+		c := health.EvaluateJob(job)
+		if c == nil {
+			result = append(result, pod)
+			continue
+		}
+		if last[ref.UID] != i {
+			continue
+		}
+		pod.Classification = jobClassification(job, pod)
+		result = append(result, pod)
+	}
+	return result
+}
+
+// jobClassification returns the classification of a failed Job through its
+// last failed pod. It has no container, so that a failed Job is one incident
+// whether it is analyzed through a pod or, once its pods are gone, on its own.
+func jobClassification(job *batchv1.Job, last UnHealthyPod) *health.Classification {
+	c := health.EvaluateJob(job)
+	if podC := podClassification(last); podC.Message != "" {
+		c.Message += fmt.Sprintf(", last failed pod %s", last.Pod.Name)
+		if podC.Container != "" {
+			c.Message += fmt.Sprintf(" (container %s)", podC.Container)
+		}
+		c.Message += ": " + podC.Message
+	}
+	return c
+}
+
+// getFailedJobs returns the failed Jobs in scope that have no pod among the
+// unhealthy pods, e.g. because the pods were deleted by the active deadline,
+// the TTL of the Job or the pod garbage collector. Each Job is analyzed
+// through its last failed pod with the logs captured when the pod failed, or
+// through its pod template if no pod was captured. Captured pods of Jobs that
+// no longer exist or completed are dropped.
+func (r *KopilotReconciler) getFailedJobs(ctx context.Context, l logr.Logger, kopilot types.NamespacedName, scope *podScope, unhealthyPods []UnHealthyPod) ([]UnHealthyPod, error) {
+	analyzed := sets.New[types.UID]()
+	for _, pod := range unhealthyPods {
+		if ref := metav1.GetControllerOf(&pod.Pod); ref != nil && ref.Kind == "Job" {
+			analyzed.Insert(ref.UID)
+		}
+	}
+
+	namespaces := []string{metav1.NamespaceAll}
+	if scope.namespaces != nil {
+		namespaces = sets.List(scope.namespaces)
+	}
+
+	var result []UnHealthyPod
+	unfinished := sets.New[types.UID]()
+	for _, namespace := range namespaces {
+		if namespace != metav1.NamespaceAll && !scope.containsNamespace(namespace) {
+			continue
+		}
+		jobs, err := r.Clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			l.Error(err, "unable to list jobs", "namespace", namespace)
+			return nil, err
+		}
+		for i := range jobs.Items {
+			job := &jobs.Items[i]
+			if !scope.containsTemplate(job.Namespace, job.Spec.Template) || health.JobComplete(job) {
+				continue
+			}
+			unfinished.Insert(job.UID)
+			if health.EvaluateJob(job) == nil || analyzed.Has(job.UID) {
+				continue
+			}
+
+			pod, ok := r.failedJobPods.get(kopilot, job.UID)
+			if !ok {
+				pod = UnHealthyPod{
+					Pod: jobPod(job),
+					Log: fmt.Sprintf("No pod of job %s is left and no logs were captured when its pods failed.", job.Name),
+					// The pod has no logs to fetch.
+					LogsCaptured: true,
+				}
+			}
+			pod.Classification = jobClassification(job, pod)
+			result = append(result, pod)
+		}
+	}
+	r.failedJobPods.retain(kopilot, unfinished)
+	return result, nil
+}
+
+// jobPod returns a pod of the pod template of a Job that has no pods left.
+// The pod has no name.
+func jobPod(job *batchv1.Job) corev1.Pod {
+	isController := true
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   job.Namespace,
+			Labels:      job.Spec.Template.Labels,
+			Annotations: job.Spec.Template.Annotations,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: batchv1.SchemeGroupVersion.String(),
+				Kind:       "Job",
+				Name:       job.Name,
+				UID:        job.UID,
+				Controller: &isController,
+			}},
+		},
+		Spec: job.Spec.Template.Spec,
+	}
+}
+
+// captureFailedJobPods fetches the logs of the failed Job pods queued by the
+// pod informer while the pods still exist, and keeps the last failed pod of
+// every Job for the analysis of the Job once it failed.
+func (r *KopilotReconciler) captureFailedJobPods(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, now time.Time) {
+	key := client.ObjectKeyFromObject(kopilot)
+	podKeys := r.failedJobPods.pop(key)
+	if len(podKeys) == 0 {
+		return
+	}
+
+	evaluator, err := r.healthEvaluator(ctx, kopilot)
+	if err != nil {
+		l.Error(err, "unable to compile health rules")
+		return
+	}
+	var pods []corev1.Pod
+	for _, podKey := range podKeys {
+		pod, err := r.Clientset.CoreV1().Pods(podKey.Namespace).Get(ctx, podKey.Name, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				l.Error(err, "unable to get pod", "pod", podKey.Name, "namespace", podKey.Namespace)
+			}
+			continue
+		}
+		pods = append(pods, *pod)
+	}
+
+	// The log source status is reported by the sweeps.
+	for _, pod := range r.fetchPodLogs(ctx, l, kopilot, filterUnhealthyPods(evaluator, pods, now), &runStatus{}) {
+		ref := metav1.GetControllerOf(&pod.Pod)
+		if ref == nil || ref.Kind != "Job" {
+			continue
+		}
+		pod.LogsCaptured = true
+		r.failedJobPods.store(key, ref.UID, pod)
+		l.Info("Captured the logs of a failed job pod", "pod", pod.Pod.Name, "namespace", pod.Pod.Namespace, "job", ref.Name)
+	}
+}
+
+// failedJobPods keeps the last failed pod of the Jobs of every Kopilot with
+// its logs, captured when the pod failed, so that a failed Job can be analyzed
+// with logs after its pods are gone.
+type failedJobPods struct {
+	mu sync.Mutex
+	// pending maps a Kopilot to the failed Job pods whose logs are to be captured.
+	pending map[types.NamespacedName]sets.Set[types.NamespacedName]
+	// captured maps a Kopilot to the last failed pod of each Job, by Job UID.
+	captured map[types.NamespacedName]map[types.UID]UnHealthyPod
+}
+
+// add queues a failed Job pod for capture by the given Kopilot. It returns
+// false if the pod is already queued.
+func (f *failedJobPods) add(kopilot, pod types.NamespacedName) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.pending == nil {
+		f.pending = map[types.NamespacedName]sets.Set[types.NamespacedName]{}
+	}
+	if f.pending[kopilot] == nil {
+		f.pending[kopilot] = sets.New[types.NamespacedName]()
+	}
+	if f.pending[kopilot].Has(pod) {
+		return false
+	}
+	f.pending[kopilot].Insert(pod)
+	return true
+}
+
+// pop returns and removes the pods queued for the given Kopilot.
+func (f *failedJobPods) pop(kopilot types.NamespacedName) []types.NamespacedName {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pods := f.pending[kopilot].UnsortedList()
+	delete(f.pending, kopilot)
+	return pods
+}
+
+// store keeps the pod as the last failed pod of the Job, unless a pod that
+// failed later is kept already.
+func (f *failedJobPods) store(kopilot types.NamespacedName, job types.UID, pod UnHealthyPod) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.captured == nil {
+		f.captured = map[types.NamespacedName]map[types.UID]UnHealthyPod{}
+	}
+	if f.captured[kopilot] == nil {
+		f.captured[kopilot] = map[types.UID]UnHealthyPod{}
+	}
+	if last, ok := f.captured[kopilot][job]; ok && podFinishedAt(last).After(podFinishedAt(pod)) {
+		return
+	}
+	f.captured[kopilot][job] = pod
+}
+
+// get returns the last failed pod captured for the Job.
+func (f *failedJobPods) get(kopilot types.NamespacedName, job types.UID) (UnHealthyPod, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pod, ok := f.captured[kopilot][job]
+	return pod, ok
+}
+
+// retain drops the captured pods of the Jobs that are not in the given set.
+func (f *failedJobPods) retain(kopilot types.NamespacedName, jobs sets.Set[types.UID]) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for job := range f.captured[kopilot] {
+		if !jobs.Has(job) {
+			delete(f.captured[kopilot], job)
+		}
+	}
+}
+
+// forget drops all state kept for the given Kopilot.
+func (f *failedJobPods) forget(kopilot types.NamespacedName) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.pending, kopilot)
+	delete(f.captured, kopilot)
+}
+
+// podFinishedAt returns when the failing container of the pod last terminated,
+// or when the pod was created if it has not terminated.
+func podFinishedAt(pod UnHealthyPod) time.Time {
+	if finished := utils.GetPodLastTerminationTime(pod.Pod.Status); !finished.IsZero() {
+		return finished
+	}
+	return pod.Pod.CreationTimestamp.Time
+}
+
+// cronJobHistory returns the Jobs of a CronJob, the most recent first.
+func (r *KopilotReconciler) cronJobHistory(ctx context.Context, cronJob *batchv1.CronJob) ([]batchv1.Job, error) {
+	list, err := r.Clientset.BatchV1().Jobs(cronJob.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var jobs []batchv1.Job
+	for _, job := range list.Items {
+		if ref := metav1.GetControllerOf(&job); ref != nil && ref.UID == cronJob.UID {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreationTimestamp.After(jobs[j].CreationTimestamp.Time)
+	})
+	return jobs, nil
+}
+
+// formatCronJobHistory renders the schedule of a CronJob and the outcome of
+// its most recent Jobs.
+func formatCronJobHistory(cronJob *batchv1.CronJob, jobs []batchv1.Job) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "schedule: %s", cronJob.Spec.Schedule)
+	if cronJob.Spec.TimeZone != nil {
+		fmt.Fprintf(&b, " (%s)", *cronJob.Spec.TimeZone)
+	}
+	if cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend {
+		b.WriteString(", suspended")
+	}
+	b.WriteString("\n")
+	if t := cronJob.Status.LastScheduleTime; t != nil {
+		fmt.Fprintf(&b, "last schedule: %s\n", t.UTC().Format(time.RFC3339))
+	}
+	if t := cronJob.Status.LastSuccessfulTime; t != nil {
+		fmt.Fprintf(&b, "last successful: %s\n", t.UTC().Format(time.RFC3339))
+	}
+
+	for i, job := range jobs {
+		if i == contextJobHistoryLimit {
+			fmt.Fprintf(&b, "... %d older jobs omitted\n", len(jobs)-i)
+			break
+		}
+		fmt.Fprintf(&b, "job %s: %s, created %s, %d succeeded, %d failed pods",
+			job.Name, jobOutcome(&job), job.CreationTimestamp.UTC().Format(time.RFC3339), job.Status.Succeeded, job.Status.Failed)
+		if job.Status.StartTime != nil && job.Status.CompletionTime != nil {
+			fmt.Fprintf(&b, ", took %s", job.Status.CompletionTime.Sub(job.Status.StartTime.Time))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func jobOutcome(job *batchv1.Job) string {
+	if c := health.EvaluateJob(job); c != nil {
+		return fmt.Sprintf("Failed (%s)", c.Reason)
+	}
+	if health.JobComplete(job) {
+		return "Complete"
+	}
+	return fmt.Sprintf("Running (%d active)", job.Status.Active)
+}
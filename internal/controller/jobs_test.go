@@ -0,0 +1,170 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/Fl0rencess720/Kopilot/pkg/health"
+	"github.com/go-logr/logr"
+)
+
+var _ = Describe("Job awareness", func() {
+	isController := true
+	now := time.Now()
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default", UID: "cronjob-uid"},
+		Spec:       batchv1.CronJobSpec{Schedule: "0 * * * *"},
+		Status:     batchv1.CronJobStatus{LastSuccessfulTime: &metav1.Time{Time: now.Add(-2 * time.Hour)}},
+	}
+	job := func(name string, condition batchv1.JobConditionType, reason string, created time.Time) *batchv1.Job {
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name, Namespace: "default", UID: types.UID(name),
+				CreationTimestamp: metav1.NewTime(created),
+				OwnerReferences: []metav1.OwnerReference{{
+					Kind: "CronJob", Name: "backup", UID: cronJob.UID, Controller: &isController,
+				}},
+			},
+			Status: batchv1.JobStatus{
+				Failed:     3,
+				Conditions: []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue, Reason: reason}},
+			},
+		}
+	}
+	failedPod := func(name, jobName string, finished time.Time) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name, Namespace: "default",
+				OwnerReferences: []metav1.OwnerReference{{
+					Kind: "Job", Name: jobName, UID: types.UID(jobName), Controller: &isController,
+				}},
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodFailed,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name: "backup",
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+						Reason: "Error", ExitCode: 1, FinishedAt: metav1.NewTime(finished),
+					}},
+				}},
+			},
+		}
+	}
+
+	It("should analyze the last failed pod of a failed Job with the CronJob history as context", func() {
+		r := &KopilotReconciler{Clientset: fake.NewClientset(
+			cronJob,
+			job("backup-1", batchv1.JobComplete, "", now.Add(-2*time.Hour)),
+			job("backup-2", batchv1.JobFailed, "BackoffLimitExceeded", now.Add(-time.Hour)),
+		)}
+
+		pods := filterUnhealthyPods(health.NewEvaluator(health.Thresholds{}), []corev1.Pod{
+			failedPod("backup-1-x", "backup-1", now.Add(-2*time.Hour)),
+			failedPod("backup-2-a", "backup-2", now.Add(-50*time.Minute)),
+			failedPod("backup-2-b", "backup-2", now.Add(-40*time.Minute)),
+		}, now)
+		Expect(pods).To(HaveLen(3))
+		pods = r.applyJobStatus(context.Background(), logr.Discard(), pods)
+		Expect(pods).To(HaveLen(1))
+		Expect(pods[0].Pod.Name).To(Equal("backup-2-b"))
+		classification := podClassification(pods[0])
+		Expect(classification.Reason).To(Equal("BackoffLimitExceeded"))
+		Expect(classification.Container).To(BeEmpty())
+		Expect(classification.Message).To(ContainSubstring("last failed pod backup-2-b (container backup): Error (exit code 1)"))
+
+		sections := r.podContext(context.Background(), logr.Discard(), pods[0].Pod)
+		var history string
+		for _, section := range sections {
+			if section.Title == "Schedule and history of CronJob backup" {
+				history = section.Content
+			}
+		}
+		Expect(history).To(ContainSubstring("schedule: 0 * * * *"))
+		Expect(history).To(MatchRegexp(`job backup-2: Failed \(BackoffLimitExceeded\)[^\n]*\njob backup-1: Complete`))
+	})
+
+	It("should analyze failed Jobs whose pods are gone, with the logs captured when their pods failed", func() {
+		ctx := context.Background()
+		pod := failedPod("backup-3-a", "backup-3", now.Add(-20*time.Minute))
+		pod.UID = "backup-3-a"
+		pod.Spec.Containers = []corev1.Container{{Name: "backup"}}
+		r := &KopilotReconciler{Clientset: fake.NewClientset(
+			cronJob,
+			job("backup-1", batchv1.JobComplete, "", now.Add(-2*time.Hour)),
+			job("backup-2", batchv1.JobFailed, "BackoffLimitExceeded", now.Add(-time.Hour)),
+			job("backup-3", batchv1.JobFailed, "DeadlineExceeded", now.Add(-30*time.Minute)),
+			&pod,
+		)}
+		kopilot := &kopilotv1.Kopilot{
+			ObjectMeta: metav1.ObjectMeta{Name: "kopilot", Namespace: "default"},
+			Spec:       kopilotv1.KopilotSpec{LogSource: kopilotv1.LogSourceSpec{Type: "Kubernetes"}},
+		}
+		key := client.ObjectKeyFromObject(kopilot)
+
+		Expect(r.failedJobPods.add(key, client.ObjectKeyFromObject(&pod))).To(BeTrue())
+		r.captureFailedJobPods(ctx, logr.Discard(), kopilot, now)
+		// The active deadline of the Job deletes its pods.
+		Expect(r.Clientset.CoreV1().Pods("default").Delete(ctx, pod.Name, metav1.DeleteOptions{})).To(Succeed())
+
+		scope, err := r.buildPodScope(ctx, kopilot.Spec)
+		Expect(err).NotTo(HaveOccurred())
+		failed, err := r.getFailedJobs(ctx, logr.Discard(), key, scope, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failed).To(HaveLen(2))
+		byJob := map[string]UnHealthyPod{}
+		for _, unhealthyPod := range failed {
+			byJob[metav1.GetControllerOf(&unhealthyPod.Pod).Name] = unhealthyPod
+		}
+
+		captured := byJob["backup-3"]
+		Expect(captured.Pod.Name).To(Equal("backup-3-a"))
+		Expect(captured.LogsCaptured).To(BeTrue())
+		Expect(captured.Log).To(ContainSubstring("fake logs"))
+		Expect(podClassification(captured).Reason).To(Equal("DeadlineExceeded"))
+		Expect(podClassification(captured).Message).To(ContainSubstring("last failed pod backup-3-a (container backup)"))
+
+		template := byJob["backup-2"]
+		Expect(template.Pod.Name).To(BeEmpty())
+		Expect(template.LogsCaptured).To(BeTrue())
+		Expect(template.Log).To(ContainSubstring("No pod of job backup-2 is left"))
+		Expect(podClassification(template).Reason).To(Equal("BackoffLimitExceeded"))
+		Expect(podReference(template.Pod)).To(BeNil())
+
+		// A failed Job with an unhealthy pod is analyzed through the pod.
+		failed, err = r.getFailedJobs(ctx, logr.Discard(), key, scope, []UnHealthyPod{captured})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failed).To(HaveLen(1))
+
+		Expect(r.Clientset.BatchV1().Jobs("default").Delete(ctx, "backup-3", metav1.DeleteOptions{})).To(Succeed())
+		_, err = r.getFailedJobs(ctx, logr.Discard(), key, scope, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, ok := r.failedJobPods.get(key, "backup-3")
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -18,24 +18,32 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
 	"github.com/Fl0rencess720/Kopilot/internal/controller/utils"
+	"github.com/Fl0rencess720/Kopilot/pkg/health"
 	"github.com/Fl0rencess720/Kopilot/pkg/llm"
 	"github.com/Fl0rencess720/Kopilot/pkg/llm/multiagent"
-	"github.com/Fl0rencess720/Kopilot/pkg/sink/feishusink"
+	logsource "github.com/Fl0rencess720/Kopilot/pkg/logSource"
+	"github.com/Fl0rencess720/Kopilot/pkg/preprocess"
+	"github.com/Fl0rencess720/Kopilot/pkg/redact"
+	"github.com/Fl0rencess720/Kopilot/pkg/sink"
 	"github.com/go-logr/logr"
 	"github.com/robfig/cron"
-	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -45,18 +53,44 @@ type KopilotReconciler struct {
 	Scheme        *runtime.Scheme
 	Clientset     kubernetes.Interface
 	DynamicClient dynamic.Interface
+
+	triggers  podTriggers
+	incidents incidentTracker
+	secrets   utils.SecretResolver
+	// logClients pools the HTTP clients of the log sources per Kopilot.
+	logClients    logsource.ClientPool
+	healthRules   healthRuleCache
+	failedJobPods failedJobPods
 }
 
 type UnHealthyPod struct {
-	Pod corev1.Pod
-	Log string
+	Pod            corev1.Pod
+	Classification *health.Classification
+	Log            string
+	// Context is the cluster context of the pod, such as events and owners.
+	Context     []llm.ContextSection
+	Fingerprint string
+	// OwnerKind and OwnerName identify the top-level controller of the pod,
+	// and Revision the revision of the workload the pod belongs to.
+	OwnerKind string
+	OwnerName string
+	Revision  string
+	// LogsCaptured is set if Log holds the logs captured when the pod
+	// failed, and the logs are not fetched again.
+	LogsCaptured bool
 }
 
 // +kubebuilder:rbac:groups=kopilot.fl0rencess720,resources=kopilots,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=kopilot.fl0rencess720,resources=kopilots/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=kopilot.fl0rencess720,resources=kopilots/finalizers,verbs=update
-// +kubebuilder:rbac:groups="",resources=pods,verbs=list;watch
+// +kubebuilder:rbac:groups=kopilot.fl0rencess720,resources=incidents,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=kopilot.fl0rencess720,resources=incidents/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=pods/log,verbs=get
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get
+// +kubebuilder:rbac:groups="",resources=events,verbs=list
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -65,12 +99,28 @@ func (r *KopilotReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	var kopilot kopilotv1.Kopilot
 	if err := r.Get(ctx, req.NamespacedName, &kopilot); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.triggers.forget(req.NamespacedName)
+			r.failedJobPods.forget(req.NamespacedName)
+			r.incidents.forget(req.NamespacedName)
+			r.healthRules.forget(req.NamespacedName)
+			r.logClients.Forget(req.NamespacedName.String())
+		}
 		l.Error(err, "unable to fetch Kopilot")
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
 	l.Info("Reconciling Kopilot", "name", kopilot.Name, "namespace", kopilot.Namespace)
 
+	// Persist the condition right away, reconciles triggered by a Secret
+	// change usually return before the status is updated below.
+	if r.checkSecrets(ctx, l, &kopilot) {
+		if err := r.Status().Update(ctx, &kopilot); err != nil {
+			l.Error(err, "failed to update Kopilot status")
+			return ctrl.Result{}, err
+		}
+	}
+
 	schedule, err := cron.ParseStandard(kopilot.Spec.Schedule)
 	if err != nil {
 		l.Error(err, "unable to parse schedule", "schedule", kopilot.Spec.Schedule)
@@ -78,6 +128,31 @@ func (r *KopilotReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	}
 
 	now := time.Now()
+
+	if hasTarget(kopilot.Spec, targetPods) {
+		r.captureFailedJobPods(ctx, l, &kopilot, now)
+	}
+
+	// In watch mode, analyze the pods queued by the pod informer first.
+	// The cron schedule is still honored below as a periodic sweep.
+	var triggerRequeue time.Duration
+	if isWatchMode(kopilot.Spec) && hasTarget(kopilot.Spec, targetPods) {
+		var podKeys []types.NamespacedName
+		podKeys, triggerRequeue = r.triggers.pop(req.NamespacedName, triggerMinInterval(kopilot.Spec), now)
+		if len(podKeys) > 0 {
+			run := &runStatus{}
+			unhealthyPods := r.getTriggeredUnhealthyPods(ctx, l, &kopilot, podKeys, run)
+			if err := r.analyzeUnhealthyPods(ctx, l, &kopilot, unhealthyPods, now, run); err != nil {
+				l.Error(err, "failed to analyze unhealthy pods")
+			}
+			r.triggers.markAnalyzed(req.NamespacedName, podKeys, now)
+			applyRunStatus(&kopilot, run)
+			if err := r.Status().Update(ctx, &kopilot); err != nil {
+				l.Error(err, "failed to update Kopilot status")
+			}
+		}
+	}
+
 	var lastCheckTime time.Time
 	if kopilot.Status.LastCheckTime != nil {
 		lastCheckTime = kopilot.Status.LastCheckTime.Time
@@ -88,136 +163,534 @@ func (r *KopilotReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	expectedNextCheckTime := schedule.Next(lastCheckTime)
 	nextCheckDuration := expectedNextCheckTime.Sub(now)
 
+	if triggerRequeue > 0 && triggerRequeue < nextCheckDuration {
+		nextCheckDuration = triggerRequeue
+	}
+
 	if now.Before(expectedNextCheckTime) {
 		l.Info("Skipping check", "nextCheckTime", expectedNextCheckTime)
 		return ctrl.Result{RequeueAfter: nextCheckDuration}, nil
 	}
 
-	unhealthyPods := r.getUnhealthyPods(ctx, l, kopilot.Spec.LogSource)
+	run := &runStatus{}
+	sweepErr := r.sweep(ctx, l, &kopilot, now, run)
 
-	if err := r.sendUnhealthyPodsToLLM(ctx, l, unhealthyPods, kopilot.Spec.LLM, kopilot.Spec.Notification.Sinks, kopilot.Spec.KnowledgeBase); err != nil {
-		zap.L().Error("failed to send unhealthy pods to LLM", zap.Error(err))
-		return ctrl.Result{RequeueAfter: nextCheckDuration}, nil
+	// Only a sweep can tell that an incident is gone, the incidents of the
+	// targets whose detection failed are kept.
+	resolved := r.incidents.resolve(req.NamespacedName, now, run.failedTargets)
+	for _, inc := range resolved {
+		r.resolveIncident(ctx, l, &kopilot, inc, now)
+	}
+	if notifyResolved(kopilot.Spec) {
+		r.notifyResolvedIncidents(ctx, l, &kopilot, resolved, now)
 	}
+	r.pruneResolvedIncidents(ctx, l, &kopilot, now)
 
 	kopilot.Status.LastCheckTime = &metav1.Time{Time: now}
+	applyRunStatus(&kopilot, run)
 	if err := r.Status().Update(ctx, &kopilot); err != nil {
 		l.Error(err, "failed to update Kopilot status")
 	}
+	if sweepErr != nil {
+		return ctrl.Result{}, sweepErr
+	}
 
 	nextCheckTime := schedule.Next(now)
-	return ctrl.Result{RequeueAfter: nextCheckTime.Sub(now)}, nil
+	requeueAfter := nextCheckTime.Sub(now)
+	if triggerRequeue > 0 && triggerRequeue < requeueAfter {
+		requeueAfter = triggerRequeue
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// sweep analyzes the unhealthy pods and nodes and the stalled rollouts of the
+// targets of the Kopilot. An error of a target or of an analysis does not stop
+// the sweep, the errors are returned together once every target has run.
+func (r *KopilotReconciler) sweep(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, now time.Time, run *runStatus) error {
+	var errs []error
+	if hasTarget(kopilot.Spec, targetPods) {
+		errs = append(errs, r.sweepPods(ctx, l, kopilot, now, run))
+	}
+	if hasTarget(kopilot.Spec, targetNodes) {
+		errs = append(errs, r.sweepNodes(ctx, l, kopilot, now, run))
+	}
+	if hasTarget(kopilot.Spec, targetRollouts) {
+		errs = append(errs, r.sweepRollouts(ctx, l, kopilot, now, run))
+	}
+	return errors.Join(errs...)
+}
+
+func (r *KopilotReconciler) sweepPods(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, now time.Time, run *runStatus) error {
+	unhealthyPods, err := r.getUnhealthyPods(ctx, l, kopilot, run)
+	if err != nil {
+		run.failTarget(targetPods, err)
+		return err
+	}
+	if isWatchMode(kopilot.Spec) {
+		podKeys := make([]types.NamespacedName, 0, len(unhealthyPods))
+		for _, pod := range unhealthyPods {
+			if pod.Pod.Name != "" {
+				podKeys = append(podKeys, client.ObjectKeyFromObject(&pod.Pod))
+			}
+		}
+		r.triggers.markAnalyzed(client.ObjectKeyFromObject(kopilot), podKeys, now)
+	}
+
+	if err := r.analyzeUnhealthyPods(ctx, l, kopilot, unhealthyPods, now, run); err != nil {
+		l.Error(err, "failed to analyze unhealthy pods")
+		return err
+	}
+	return nil
+}
+
+func (r *KopilotReconciler) sweepNodes(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, now time.Time, run *runStatus) error {
+	unhealthyNodes, err := r.getUnhealthyNodes(ctx, l, kopilot.Spec, run)
+	if err != nil {
+		run.failTarget(targetNodes, err)
+		return err
+	}
+	if err := r.analyzeUnhealthyNodes(ctx, l, kopilot, unhealthyNodes, now, run); err != nil {
+		l.Error(err, "failed to analyze unhealthy nodes")
+		return err
+	}
+	return nil
+}
+
+func (r *KopilotReconciler) sweepRollouts(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, now time.Time, run *runStatus) error {
+	rollouts, err := r.getStalledRollouts(ctx, l, kopilot.Spec, now, run)
+	if err != nil {
+		run.failTarget(targetRollouts, err)
+		return err
+	}
+	if err := r.analyzeStalledRollouts(ctx, l, kopilot, rollouts, now, run); err != nil {
+		l.Error(err, "failed to analyze stalled rollouts")
+		return err
+	}
+	return nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *KopilotReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&kopilotv1.Kopilot{}).
+		Watches(&corev1.Pod{}, r.podEventHandler()).
+		WatchesMetadata(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.kopilotsForSecret)).
 		Named("kopilot").
 		Complete(r)
 }
 
-func (r *KopilotReconciler) getUnhealthyPods(ctx context.Context, l logr.Logger, logSource kopilotv1.LogSourceSpec) []UnHealthyPod {
-	pods, err := r.Clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+// getUnhealthyPods returns the unhealthy pods in scope, and the failed Jobs in
+// scope that have no pods left.
+func (r *KopilotReconciler) getUnhealthyPods(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, run *runStatus) ([]UnHealthyPod, error) {
+	spec := kopilot.Spec
+	scope, err := r.buildPodScope(ctx, spec)
+	if err != nil {
+		l.Error(err, "unable to resolve pod scope")
+		return nil, err
+	}
+
+	pods, err := r.listPods(ctx, scope)
 	if err != nil {
 		l.Error(err, "unable to list pods")
+		return nil, err
+	}
+
+	evaluator, err := r.healthEvaluator(ctx, kopilot)
+	if err != nil {
+		l.Error(err, "unable to compile health rules")
+		return nil, err
+	}
+	unhealthyPods := r.applyJobStatus(ctx, l, filterUnhealthyPods(evaluator, pods, time.Now()))
+	failedJobs, err := r.getFailedJobs(ctx, l, client.ObjectKeyFromObject(kopilot), scope, unhealthyPods)
+	if err != nil {
+		return nil, err
+	}
+	unhealthyPods = append(unhealthyPods, failedJobs...)
+	run.stats.PodsScanned = int32(len(pods))
+	run.stats.PodsUnhealthy = int32(len(unhealthyPods))
+	return unhealthyPods, nil
+}
+
+// getTriggeredUnhealthyPods re-fetches the pods queued by the pod informer and
+// returns the ones that are still in scope and unhealthy.
+func (r *KopilotReconciler) getTriggeredUnhealthyPods(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, podKeys []types.NamespacedName, run *runStatus) []UnHealthyPod {
+	scope, err := r.buildPodScope(ctx, kopilot.Spec)
+	if err != nil {
+		l.Error(err, "unable to resolve pod scope")
+		run.fail(err)
 		return nil
 	}
 
-	var unhealthyPods []UnHealthyPod
-	for _, pod := range pods.Items {
-		if pod.Kind == "Kopilot" {
+	var pods []corev1.Pod
+	for _, key := range podKeys {
+		pod, err := r.Clientset.CoreV1().Pods(key.Namespace).Get(ctx, key.Name, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				l.Error(err, "unable to get pod", "pod", key.Name, "namespace", key.Namespace)
+			}
 			continue
 		}
-		// fmt.Println("pod", pod.Name, "namespace", pod.Namespace, "status", pod.Status.Phase, "condition", pod.Status.Conditions)
-		if !utils.CheckPodHealthyStatus(pod.Status) {
-
-			logs := ""
-			switch logSource.Type {
-			case "Kubernetes":
-				logs, err = utils.GetPodLogsFromKubernetes(r.Clientset, pod.Name, pod.Namespace)
-				if err != nil {
-					l.Error(err, "unable to get pod logs from kubernetes, skipping", "pod", pod.Name, "namespace", pod.Namespace)
-					logs = fmt.Sprintf("Failed to retrieve logs: %v", err)
-				}
-			case "Loki":
-				logs, err = utils.GetPodLogsFromLoki(pod.Name, pod.Namespace, logSource.Loki.Address)
-				if err != nil {
-					l.Error(err, "unable to get pod logs from loki, skipping", "pod", pod.Name, "namespace", pod.Namespace)
-					logs = fmt.Sprintf("Failed to retrieve logs: %v", err)
-				}
-			default:
-				l.Error(fmt.Errorf("unknown log source type: %s", logSource.Type), "unable to get pod logs")
-				continue
-			}
-
-			unhealthyPods = append(unhealthyPods, UnHealthyPod{
-				Pod: pod,
-				Log: logs,
-			})
+		if !scope.contains(pod) {
+			continue
 		}
+		pods = append(pods, *pod)
 	}
 
+	evaluator, err := r.healthEvaluator(ctx, kopilot)
+	if err != nil {
+		l.Error(err, "unable to compile health rules")
+		run.fail(err)
+		return nil
+	}
+	unhealthyPods := r.applyJobStatus(ctx, l, filterUnhealthyPods(evaluator, pods, time.Now()))
+	run.stats.PodsScanned = int32(len(pods))
+	run.stats.PodsUnhealthy = int32(len(unhealthyPods))
 	return unhealthyPods
 }
 
-func (r *KopilotReconciler) sendUnhealthyPodsToLLM(ctx context.Context, l logr.Logger, unhealthyPods []UnHealthyPod, llmSpec kopilotv1.LLMSpec, sinks []kopilotv1.NotificationSink, knowledgeBase *kopilotv1.KnowledgeBaseSpec) error {
-	var err error
+// analyzeUnhealthyPods deduplicates the unhealthy pods against the open incidents
+// of the Kopilot, groups the remaining ones by workload, then fetches logs for
+// and analyzes each workload once.
+func (r *KopilotReconciler) analyzeUnhealthyPods(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, unhealthyPods []UnHealthyPod, now time.Time, run *runStatus) error {
+	r.syncIncidents(ctx, l, kopilot)
 
-	for _, pod := range unhealthyPods {
-		var c llm.LLMClient
-		var retriever *llm.HybridRetriever
-		if knowledgeBase != nil {
-			retriever, err = llm.NewHybridRetriever(ctx, r.Clientset, *knowledgeBase)
-			if err != nil {
-				l.Error(err, "unable to create hybrid retriever")
-				return err
-			}
+	unhealthyPods = r.resolveOwners(ctx, l, unhealthyPods)
+	if hasTarget(kopilot.Spec, targetRollouts) {
+		unhealthyPods = r.dropStalledRolloutPods(ctx, l, kopilot.Spec, unhealthyPods, now)
+	}
+
+	key := client.ObjectKeyFromObject(kopilot)
+	pods := r.incidents.observe(key, unhealthyPods, reNotifyInterval(kopilot.Spec), now)
+	if skipped := len(unhealthyPods) - len(pods); skipped > 0 {
+		l.Info("Skipping pods of already notified incidents", "count", skipped)
+	}
+
+	workloads := groupByWorkload(unhealthyPods, pods)
+	for _, w := range workloads {
+		w.representatives = r.fetchPodLogs(ctx, l, kopilot, w.representatives, run)
+		if len(w.representatives) > 0 {
+			w.representatives[0].Context = r.podContext(ctx, l, w.representatives[0].Pod)
 		}
-		switch llmSpec.WorkingMode {
-		case "single":
-			c, err = llm.NewLLMClient(ctx, r.Clientset, llmSpec, retriever)
-			if err != nil {
-				l.Error(err, "unable to create LLM client")
-				return err
-			}
+	}
 
-			result, err := c.Analyze(ctx, pod.Pod, pod.Log)
-			if err != nil {
-				l.Error(err, "unable to analyze pod", "pod", pod.Pod.Name, "namespace", pod.Pod.Namespace)
-				return err
-			}
+	return r.sendUnhealthyPodsToLLM(ctx, l, kopilot, workloads, now, run)
+}
 
-			sink, err := feishusink.NewFeishuSink(r.Clientset, *sinks[0].Feishu)
-			if err != nil {
-				l.Error(err, "unable to create feishu sink")
-				return err
-			}
-			if err := sink.SendBotMessage(pod.Pod.Namespace, pod.Pod.Name, result); err != nil {
-				l.Error(err, "unable to send result to feishu")
-				return err
-			}
-		case "multi":
-			ma, err := multiagent.NewLogMultiAgent(ctx, r.Clientset, r.DynamicClient, llmSpec, retriever, llmSpec.Language)
-			if err != nil {
-				l.Error(err, "unable to create multiagent")
-				return err
+func (r *KopilotReconciler) fetchPodLogs(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, unhealthyPods []UnHealthyPod, run *runStatus) []UnHealthyPod {
+	logSource := kopilot.Spec.LogSource
+	source, err := logsource.New(ctx, logsource.Config{
+		Spec:      logSource,
+		Clientset: r.Clientset,
+		Secrets:   r.secretsFor(kopilot),
+		Key:       client.ObjectKeyFromObject(kopilot).String(),
+		Clients:   &r.logClients,
+	})
+	if err != nil {
+		l.Error(err, "unable to create log source", "type", logSource.Type)
+		run.logsResult(err)
+		result := make([]UnHealthyPod, 0, len(unhealthyPods))
+		for _, unhealthyPod := range unhealthyPods {
+			if !unhealthyPod.LogsCaptured {
+				unhealthyPod.Log = fmt.Sprintf("Failed to retrieve logs: %v", err)
 			}
-			result, err := ma.Run(ctx, pod.Pod, pod.Log)
-			if err != nil {
-				l.Error(err, "unable to run multiagent")
-				return err
+			result = append(result, unhealthyPod)
+		}
+		return result
+	}
+
+	var result []UnHealthyPod
+	for _, unhealthyPod := range unhealthyPods {
+		pod := unhealthyPod.Pod
+		if unhealthyPod.LogsCaptured {
+			result = append(result, unhealthyPod)
+			continue
+		}
+
+		lines, err := source.Fetch(ctx, utils.NewPodRef(pod), utils.NewLogOptions(logSource, pod, time.Now()))
+		run.logsResult(err)
+		if err != nil {
+			l.Error(err, "unable to get pod logs, skipping", "type", logSource.Type, "pod", pod.Name, "namespace", pod.Namespace)
+			unhealthyPod.Log = fmt.Sprintf("Failed to retrieve logs: %v", err)
+			result = append(result, unhealthyPod)
+			continue
+		}
+
+		processed, err := preprocess.Process(lines, logSource.Preprocessing)
+		if err != nil {
+			l.Error(err, "unable to preprocess pod logs, using the raw logs", "pod", pod.Name, "namespace", pod.Namespace)
+			processed = lines
+		}
+		unhealthyPod.Log = utils.FormatPodLogs(pod, processed)
+		result = append(result, unhealthyPod)
+	}
+
+	return result
+}
+
+func (r *KopilotReconciler) sendUnhealthyPodsToLLM(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, workloads []*workload, now time.Time, run *runStatus) error {
+	var analyzed []*workload
+	for _, w := range workloads {
+		// The logs of the workload may be unavailable.
+		if len(w.representatives) > 0 {
+			analyzed = append(analyzed, w)
+		}
+	}
+
+	return r.analyzeAndNotify(ctx, l, kopilot, len(analyzed), func(i int, redaction *podRedaction) analysisItem {
+		w := analyzed[i]
+		pod := w.analysisPod()
+		llmPod := redaction.apply(pod)
+		item := analysisItem{logger: l.WithValues("pod", pod.Pod.Name, "namespace", pod.Pod.Namespace), notifiedPods: len(w.pods)}
+		// Notifications show the redacted logs, incidents the original values
+		// if they are restored.
+		for _, representative := range w.representatives {
+			item.incidents = append(item.incidents, analyzedIncident{
+				fingerprint: representative.Fingerprint,
+				podRef:      podReference(representative.Pod),
+				logs:        redaction.restored(redaction.apply(representative).Log),
+			})
+		}
+
+		classification := podClassification(llmPod)
+		item.input = llm.AnalysisInput{
+			Pod:            llmPod.Pod,
+			Logs:           llmPod.Log,
+			Classification: classification.String(),
+			PromptHint:     classification.PromptHint,
+			Context:        llmPod.Context,
+		}
+		// A failed Job without pods is analyzed through its pod template.
+		subject := pod.Pod.Name
+		if subject == "" {
+			subject = w.ownerName
+		}
+		item.analyzed = func(summary string) {
+			run.analyzed(pod.Pod.Namespace, subject, summary)
+		}
+		item.msg = sink.Message{
+			Namespace:      pod.Pod.Namespace,
+			PodName:        pod.Pod.Name,
+			PodUID:         string(pod.Pod.UID),
+			OwnerKind:      w.ownerKind,
+			OwnerName:      w.ownerName,
+			AffectedPods:   w.podNames(),
+			Fingerprint:    pod.Fingerprint,
+			Container:      classification.Container,
+			FailureReason:  classification.Reason,
+			Severity:       string(classification.Severity),
+			FailureMessage: classification.Message,
+			LogsExcerpt:    logsExcerpt(llmPod.Log),
+		}
+		return item
+	}, now, run)
+}
+
+// analysisItem is a redacted pod, node or rollout to analyze, with the
+// incidents that record the analysis and the notification of its result.
+type analysisItem struct {
+	logger    logr.Logger
+	input     llm.AnalysisInput
+	incidents []analyzedIncident
+	// msg is the notification, without the result of the analysis.
+	msg sink.Message
+	// analyzed records the summary of the analysis in the run status.
+	analyzed func(summary string)
+	// notifiedPods is the number of pods counted as notified.
+	notifiedPods int
+}
+
+// analyzedIncident is an incident that records an analysis, with its pod and
+// logs of which the redacted values are restored.
+type analyzedIncident struct {
+	fingerprint string
+	podRef      *kopilotv1.PodReference
+	logs        string
+}
+
+// analyzeAndNotify analyzes count items, built by build with a redactor of
+// their own, records the results in their incidents and notifies the sinks.
+func (r *KopilotReconciler) analyzeAndNotify(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, count int,
+	build func(i int, redaction *podRedaction) analysisItem, now time.Time, run *runStatus) error {
+	if count == 0 {
+		return nil
+	}
+	sinks := r.buildSinks(ctx, l, kopilot, now)
+
+	var redactionRules []redact.Rule
+	if kopilot.Spec.Redaction != nil {
+		// Fail closed, unredacted data must not be sent to the LLM.
+		var err error
+		redactionRules, err = r.redactionRules(ctx, kopilot)
+		if err != nil {
+			l.Error(err, "unable to load redaction rules")
+			run.fail(err)
+			return err
+		}
+	}
+
+	var errs []error
+	for i := range count {
+		redaction, err := newPodRedaction(kopilot.Spec.Redaction, redactionRules)
+		if err != nil {
+			l.Error(err, "unable to create redactor")
+			run.fail(err)
+			return err
+		}
+		item := build(i, redaction)
+		analysis, remediation, err := r.analyze(ctx, item.logger, kopilot, item.input, run)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, inc := range item.incidents {
+			r.recordIncident(ctx, l, kopilot, inc.fingerprint, inc.podRef, inc.logs,
+				redaction.restoredAnalysis(analysis), redaction.restoredRemediation(remediation), now)
+		}
+		item.analyzed(analysisSummary(analysis, remediation))
+
+		msg := item.msg
+		setAnalysisResult(&msg, analysis, remediation)
+		delivered := deliver(ctx, l, kopilot, sinks, now, func(ctx context.Context, s sink.Sink) error {
+			return s.Send(ctx, msg)
+		})
+		// Keep the incident due for notification if no sink received it.
+		if delivered > 0 {
+			for _, inc := range item.incidents {
+				r.incidents.markAnalyzed(client.ObjectKeyFromObject(kopilot), inc.fingerprint, now)
 			}
-			sink, err := feishusink.NewFeishuSink(r.Clientset, *sinks[0].Feishu)
-			if err != nil {
-				l.Error(err, "unable to create feishu sink")
-				return err
+			run.stats.PodsNotified += int32(item.notifiedPods)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// analyze runs the analysis of the input in the working mode of the Kopilot,
+// and returns the results with the placeholders of the redacted input.
+func (r *KopilotReconciler) analyze(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, input llm.AnalysisInput,
+	run *runStatus) (*kopilotv1.IncidentAnalysis, *kopilotv1.IncidentRemediation, error) {
+	llmSpec := kopilot.Spec.LLM
+
+	var retriever *llm.HybridRetriever
+	if knowledgeBase := kopilot.Spec.KnowledgeBase; knowledgeBase != nil {
+		var err error
+		retriever, err = llm.NewHybridRetriever(ctx, r.secretsFor(kopilot), *knowledgeBase)
+		run.knowledgeBaseResult(err)
+		if err != nil {
+			l.Error(err, "unable to create hybrid retriever")
+			return nil, nil, err
+		}
+	}
+
+	switch llmSpec.WorkingMode {
+	case "single":
+		c, err := llm.NewLLMClient(ctx, r.secretsFor(kopilot), llmSpec, retriever)
+		if err != nil {
+			l.Error(err, "unable to create LLM client")
+			run.llmResult(err)
+			return nil, nil, err
+		}
+
+		result, err := c.Analyze(ctx, input)
+		run.llmResult(err)
+		if err != nil {
+			l.Error(err, "unable to analyze")
+			return nil, nil, err
+		}
+
+		analysis := &kopilotv1.IncidentAnalysis{Reason: result}
+		if parsed, err := llm.ParseAnalysisResult(result); err == nil {
+			analysis = &kopilotv1.IncidentAnalysis{
+				Reason:              parsed.Reason,
+				Solution:            parsed.Solution,
+				Sink:                parsed.Sink,
+				NewRevisionAtFault:  parsed.NewRevisionAtFault,
+				RollbackRecommended: parsed.Rollback,
 			}
-			if err := sink.SendBotMessage(pod.Pod.Namespace, pod.Pod.Name, result); err != nil {
-				l.Error(err, "unable to send result to feishu")
-				return err
+		}
+		return analysis, nil, nil
+	case "multi":
+		ma, err := multiagent.NewLogMultiAgent(ctx, r.secretsFor(kopilot), r.DynamicClient, llmSpec, retriever, llmSpec.Language)
+		if err != nil {
+			l.Error(err, "unable to create multiagent")
+			run.llmResult(err)
+			return nil, nil, err
+		}
+		result, err := ma.Run(ctx, input)
+		run.llmResult(err)
+		if err != nil {
+			l.Error(err, "unable to run multiagent")
+			return nil, nil, err
+		}
+
+		remediation := &kopilotv1.IncidentRemediation{
+			AutoFixResult:   result.AutoFixResult,
+			SearchResult:    result.SearchResult,
+			HumanHelpResult: result.HumanHelpResult,
+		}
+		// The host gives a verdict on stalled rollouts.
+		var analysis *kopilotv1.IncidentAnalysis
+		if result.NewRevisionAtFault != nil || result.Rollback != nil {
+			analysis = &kopilotv1.IncidentAnalysis{
+				NewRevisionAtFault:  result.NewRevisionAtFault,
+				RollbackRecommended: result.Rollback,
 			}
 		}
+		return analysis, remediation, nil
+	}
+	return nil, nil, nil
+}
+
+// analysisSummary returns the main result of an analysis.
+func analysisSummary(analysis *kopilotv1.IncidentAnalysis, remediation *kopilotv1.IncidentRemediation) string {
+	switch {
+	case analysis != nil && analysis.Reason != "":
+		return analysis.Reason
+	case remediation != nil:
+		return remediation.HumanHelpResult
+	}
+	return ""
+}
+
+// setAnalysisResult adds the results of an analysis to a notification.
+func setAnalysisResult(msg *sink.Message, analysis *kopilotv1.IncidentAnalysis, remediation *kopilotv1.IncidentRemediation) {
+	if analysis != nil {
+		msg.Reason = analysis.Reason
+		msg.Solution = analysis.Solution
+		msg.NewRevisionAtFault = analysis.NewRevisionAtFault
+		msg.RollbackRecommended = analysis.RollbackRecommended
+	}
+	if remediation != nil {
+		msg.AutoFixResult = remediation.AutoFixResult
+		msg.SearchResult = remediation.SearchResult
+		msg.HumanHelpResult = remediation.HumanHelpResult
+	}
+}
+
+func (r *KopilotReconciler) notifyResolvedIncidents(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, resolved []*incident, now time.Time) {
+	var sinks []notificationSink
+	for _, inc := range resolved {
+		// Incidents that were never notified do not need a resolved notification.
+		if inc.lastAnalyzed.IsZero() {
+			continue
+		}
+		l.Info("Incident resolved", "namespace", inc.namespace, "owner", inc.ownerKind+"/"+inc.ownerName, "reason", inc.reason)
+
+		if sinks == nil {
+			sinks = r.buildSinks(ctx, l, kopilot, now)
+		}
+		msg := sink.ResolvedMessage{
+			Namespace:    inc.namespace,
+			PodName:      inc.pod,
+			AffectedPods: sets.List(inc.pods),
+			NodeName:     inc.nodeName(),
+			OwnerKind:    inc.ownerKind,
+			OwnerName:    inc.ownerName,
+			Fingerprint:  inc.fingerprint,
+			Reason:       inc.reason,
+			Duration:     now.Sub(inc.firstSeen),
+		}
+		deliver(ctx, l, kopilot, sinks, now, func(ctx context.Context, s sink.Sink) error {
+			return s.SendResolved(ctx, msg)
+		})
 	}
-	return nil
 }
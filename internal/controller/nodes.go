@@ -0,0 +1,279 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/Fl0rencess720/Kopilot/pkg/health"
+	"github.com/Fl0rencess720/Kopilot/pkg/llm"
+	"github.com/Fl0rencess720/Kopilot/pkg/sink"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	targetPods     = "Pods"
+	targetNodes    = "Nodes"
+	targetRollouts = "Rollouts"
+
+	// maxListedEvictedPods bounds the evicted pods listed in the node context.
+	maxListedEvictedPods = 20
+)
+
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=list
+
+type UnHealthyNode struct {
+	Node           corev1.Node
+	Classification health.Classification
+	// Context is the cluster context of the node, such as events and evicted pods.
+	Context     []llm.ContextSection
+	Fingerprint string
+}
+
+// hasTarget reports whether the Kopilot analyzes the given kind of resources.
+// Only pods are analyzed if no targets are set.
+func hasTarget(spec kopilotv1.KopilotSpec, target string) bool {
+	if len(spec.Targets) == 0 {
+		return target == targetPods
+	}
+	return slices.Contains(spec.Targets, target)
+}
+
+func (r *KopilotReconciler) getUnhealthyNodes(ctx context.Context, l logr.Logger, spec kopilotv1.KopilotSpec, run *runStatus) ([]UnHealthyNode, error) {
+	opts := metav1.ListOptions{}
+	if spec.NodeSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(spec.NodeSelector)
+		if err != nil {
+			l.Error(err, "invalid node selector")
+			return nil, err
+		}
+		opts.LabelSelector = selector.String()
+	}
+	nodes, err := r.Clientset.CoreV1().Nodes().List(ctx, opts)
+	if err != nil {
+		l.Error(err, "unable to list nodes")
+		return nil, err
+	}
+
+	now := time.Now()
+	var unhealthyNodes []UnHealthyNode
+	for _, node := range nodes.Items {
+		if c := health.EvaluateNode(&node, now); c != nil {
+			unhealthyNodes = append(unhealthyNodes, UnHealthyNode{Node: node, Classification: *c})
+		}
+	}
+	run.stats.NodesScanned = int32(len(nodes.Items))
+	run.stats.NodesUnhealthy = int32(len(unhealthyNodes))
+	return unhealthyNodes, nil
+}
+
+// analyzeUnhealthyNodes deduplicates the unhealthy nodes against the open
+// incidents of the Kopilot, then gathers the context of and analyzes each
+// remaining node.
+func (r *KopilotReconciler) analyzeUnhealthyNodes(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, unhealthyNodes []UnHealthyNode, now time.Time, run *runStatus) error {
+	r.syncIncidents(ctx, l, kopilot)
+
+	nodes := r.incidents.observeNodes(client.ObjectKeyFromObject(kopilot), unhealthyNodes, reNotifyInterval(kopilot.Spec), now)
+	if skipped := len(unhealthyNodes) - len(nodes); skipped > 0 {
+		l.Info("Skipping nodes of already notified incidents", "count", skipped)
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+	scope, err := r.buildPodScope(ctx, kopilot.Spec)
+	if err != nil {
+		l.Error(err, "unable to resolve pod scope")
+		return err
+	}
+	for i := range nodes {
+		nodes[i].Context = r.nodeContext(ctx, l, scope, nodes[i].Node)
+	}
+
+	return r.sendUnhealthyNodesToLLM(ctx, l, kopilot, nodes, now, run)
+}
+
+func (r *KopilotReconciler) sendUnhealthyNodesToLLM(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, nodes []UnHealthyNode, now time.Time, run *runStatus) error {
+	return r.analyzeAndNotify(ctx, l, kopilot, len(nodes), func(i int, redaction *podRedaction) analysisItem {
+		node := nodes[i]
+		// The images of the node and the managed fields only cost tokens.
+		node.Node.Status.Images = nil
+		node.Node.ManagedFields = nil
+		llmNode := redaction.applyNode(node)
+
+		classification := llmNode.Classification
+		return analysisItem{
+			logger: l.WithValues("node", node.Node.Name),
+			input: llm.AnalysisInput{
+				Node:           &llmNode.Node,
+				Classification: classification.String(),
+				Context:        llmNode.Context,
+			},
+			incidents: []analyzedIncident{{fingerprint: node.Fingerprint}},
+			analyzed: func(summary string) {
+				run.analyzedNode(node.Node.Name, summary)
+			},
+			msg: sink.Message{
+				NodeName:       node.Node.Name,
+				OwnerKind:      "Node",
+				OwnerName:      node.Node.Name,
+				Fingerprint:    node.Fingerprint,
+				FailureReason:  classification.Reason,
+				Severity:       string(classification.Severity),
+				FailureMessage: classification.Message,
+			},
+		}
+	}, now, run)
+}
+
+// nodeContext collects the cluster context of an unhealthy node: a
+// describe-style summary with the resources allocated to its pods, the pods
+// evicted from the node and the events of the node. Only the pods in the
+// scope of the Kopilot are included.
+func (r *KopilotReconciler) nodeContext(ctx context.Context, l logr.Logger, scope *podScope, node corev1.Node) []llm.ContextSection {
+	var pods []corev1.Pod
+	list, err := r.Clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", node.Name).String(),
+	})
+	if err != nil {
+		l.Error(err, "unable to list the pods of node", "node", node.Name)
+	} else {
+		for _, pod := range list.Items {
+			if pod.Spec.NodeName == node.Name && scope.contains(&pod) {
+				pods = append(pods, pod)
+			}
+		}
+	}
+
+	sections := []llm.ContextSection{{
+		Title:   fmt.Sprintf("Node %s", node.Name),
+		Content: describeNode(&node, pods),
+	}}
+
+	var evicted []string
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodFailed && pod.Status.Reason == "Evicted" {
+			evicted = append(evicted, fmt.Sprintf("%s/%s: %s", pod.Namespace, pod.Name, pod.Status.Message))
+		}
+	}
+	if len(evicted) > 0 {
+		content := strings.Join(evicted[:min(len(evicted), maxListedEvictedPods)], "\n")
+		if len(evicted) > maxListedEvictedPods {
+			content += fmt.Sprintf("\n... and %d more", len(evicted)-maxListedEvictedPods)
+		}
+		sections = append(sections, llm.ContextSection{
+			Title:   fmt.Sprintf("Pods evicted from node %s (%d)", node.Name, len(evicted)),
+			Content: content,
+		})
+	}
+
+	events, err := r.listNodeEvents(ctx, node.Name)
+	if err != nil {
+		l.Error(err, "unable to list events", "kind", "Node", "name", node.Name)
+	} else if len(events) > 0 {
+		sections = append(sections, llm.ContextSection{
+			Title:   fmt.Sprintf("Events of Node %s", node.Name),
+			Content: formatEvents(events),
+		})
+	}
+	return sections
+}
+
+// listNodeEvents returns the events of a node, the most recent first. The
+// kubelet records node events with the node name as UID, so they are
+// matched by name.
+func (r *KopilotReconciler) listNodeEvents(ctx context.Context, name string) ([]corev1.Event, error) {
+	list, err := r.Clientset.CoreV1().Events("").List(ctx, metav1.ListOptions{
+		FieldSelector: fields.Set{"involvedObject.kind": "Node", "involvedObject.name": name}.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var events []corev1.Event
+	for _, event := range list.Items {
+		if event.InvolvedObject.Kind == "Node" && event.InvolvedObject.Name == name {
+			events = append(events, event)
+		}
+	}
+	return sortEvents(events), nil
+}
+
+// describeNode summarizes a node like kubectl describe node: its conditions,
+// system info, capacity and the resources requested by its pods.
+func describeNode(node *corev1.Node, pods []corev1.Pod) string {
+	var b strings.Builder
+	b.WriteString(formatNode(node))
+
+	var addresses []string
+	for _, address := range node.Status.Addresses {
+		addresses = append(addresses, fmt.Sprintf("%s=%s", address.Type, address.Address))
+	}
+	if len(addresses) > 0 {
+		fmt.Fprintf(&b, "Addresses: %s\n", strings.Join(addresses, ", "))
+	}
+	info := node.Status.NodeInfo
+	fmt.Fprintf(&b, "System info: kubelet %s, container runtime %s, kernel %s, OS image %s\n",
+		info.KubeletVersion, info.ContainerRuntimeVersion, info.KernelVersion, info.OSImage)
+	capacity := node.Status.Capacity
+	fmt.Fprintf(&b, "Capacity: cpu=%s, memory=%s, ephemeral-storage=%s, pods=%s\n",
+		capacity.Cpu(), capacity.Memory(), capacity.StorageEphemeral(), capacity.Pods())
+
+	var running int
+	requests, limits := corev1.ResourceList{}, corev1.ResourceList{}
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		running++
+		for _, container := range pod.Spec.Containers {
+			addResources(requests, container.Resources.Requests)
+			addResources(limits, container.Resources.Limits)
+		}
+	}
+	allocatable := node.Status.Allocatable
+	fmt.Fprintf(&b, "Allocated resources (%d non-terminated pods in scope):\n", running)
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage} {
+		request, limit := requests[name], limits[name]
+		fmt.Fprintf(&b, "  %s: requests %s%s, limits %s%s\n", name,
+			request.String(), percentOf(request, allocatable[name]), limit.String(), percentOf(limit, allocatable[name]))
+	}
+	return b.String()
+}
+
+func addResources(total, resources corev1.ResourceList) {
+	for name, quantity := range resources {
+		sum := total[name]
+		sum.Add(quantity)
+		total[name] = sum
+	}
+}
+
+func percentOf(quantity, allocatable resource.Quantity) string {
+	if allocatable.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf(" (%d%%)", quantity.MilliValue()*100/allocatable.MilliValue())
+}
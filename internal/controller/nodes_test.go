@@ -0,0 +1,116 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/go-logr/logr"
+)
+
+var _ = Describe("Node analysis", func() {
+	It("should detect unhealthy nodes and describe them with their evicted pods and events", func() {
+		since := metav1.NewTime(time.Now().Add(-10 * time.Minute))
+		node := func(name string, conditions ...corev1.NodeCondition) *corev1.Node {
+			return &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"pool": "workers"}},
+				Status: corev1.NodeStatus{
+					Conditions:  conditions,
+					Allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4"), corev1.ResourceMemory: resource.MustParse("8Gi")},
+				},
+			}
+		}
+		ready := corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionTrue, LastTransitionTime: since, LastHeartbeatTime: metav1.Now()}
+
+		evicted := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default"},
+			Spec:       corev1.PodSpec{NodeName: "node-1"},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodFailed, Reason: "Evicted",
+				Message: "The node was low on resource: ephemeral-storage.",
+			},
+		}
+		running := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-1", Namespace: "default"},
+			Spec: corev1.PodSpec{NodeName: "node-1", Containers: []corev1.Container{{
+				Name: "api",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+				},
+			}}},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		// The pods of excluded namespaces are left out of the context.
+		excluded := *evicted.DeepCopy()
+		excluded.Namespace = "kube-system"
+
+		r := &KopilotReconciler{Clientset: fake.NewClientset(
+			node("node-1", ready, corev1.NodeCondition{
+				Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue, LastTransitionTime: since,
+				Reason: "KubeletHasDiskPressure", Message: "kubelet has disk pressure",
+			}),
+			node("node-2", ready),
+			node("node-3", corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionUnknown, LastTransitionTime: since}),
+			&evicted, &running, &excluded,
+			&corev1.Event{
+				ObjectMeta:     metav1.ObjectMeta{Name: "node-1.1", Namespace: "default"},
+				InvolvedObject: corev1.ObjectReference{Kind: "Node", Name: "node-1", UID: "node-1"},
+				Type:           corev1.EventTypeWarning,
+				Reason:         "EvictionThresholdMet",
+				Message:        "Attempting to reclaim ephemeral-storage",
+			},
+		)}
+
+		spec := kopilotv1.KopilotSpec{Targets: []string{targetNodes}}
+		Expect(hasTarget(spec, targetPods)).To(BeFalse())
+		Expect(hasTarget(kopilotv1.KopilotSpec{}, targetPods)).To(BeTrue())
+
+		run := &runStatus{}
+		nodes, err := r.getUnhealthyNodes(context.Background(), logr.Discard(), spec, run)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodes).To(HaveLen(2))
+		Expect(nodes[0].Classification.Reason).To(Equal("DiskPressure"))
+		Expect(nodes[1].Classification.Reason).To(Equal("NodeStatusUnknown"))
+		Expect(run.stats.NodesScanned).To(Equal(int32(3)))
+
+		kopilot := types.NamespacedName{Name: "kopilot", Namespace: "default"}
+		due := (&incidentTracker{}).observeNodes(kopilot, nodes, time.Hour, time.Now())
+		Expect(due).To(HaveLen(2))
+		Expect(due[0].Fingerprint).NotTo(Equal(due[1].Fingerprint))
+
+		scope, err := r.buildPodScope(context.Background(), kopilotv1.KopilotSpec{ExcludedNamespaces: []string{"kube-system"}})
+		Expect(err).NotTo(HaveOccurred())
+		sections := r.nodeContext(context.Background(), logr.Discard(), scope, nodes[0].Node)
+		Expect(sections).To(HaveLen(3))
+		Expect(sections[0].Content).To(ContainSubstring("DiskPressure=True (KubeletHasDiskPressure)"))
+		Expect(sections[0].Content).To(ContainSubstring("Allocated resources (1 non-terminated pods in scope)"))
+		Expect(sections[0].Content).To(ContainSubstring("cpu: requests 1 (25%)"))
+		Expect(sections[1].Title).To(HaveSuffix("(1)"))
+		Expect(sections[1].Content).To(ContainSubstring("default/web-1: The node was low on resource: ephemeral-storage."))
+		Expect(sections[2].Content).To(ContainSubstring("EvictionThresholdMet: Attempting to reclaim ephemeral-storage"))
+	})
+})
@@ -0,0 +1,179 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/Fl0rencess720/Kopilot/pkg/llm"
+	"github.com/Fl0rencess720/Kopilot/pkg/redact"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// redactionRules compiles the custom redaction rules of the spec and of the
+// referenced ConfigMap.
+func (r *KopilotReconciler) redactionRules(ctx context.Context, kopilot *kopilotv1.Kopilot) ([]redact.Rule, error) {
+	spec := kopilot.Spec.Redaction
+
+	var rules []redact.Rule
+	for _, rule := range spec.Rules {
+		compiled, err := redact.NewRule(rule.Name, rule.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, compiled)
+	}
+
+	if ref := spec.RulesConfigMapRef; ref != nil {
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = kopilot.Namespace
+		}
+		cm, err := r.Clientset.CoreV1().ConfigMaps(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get redaction rules ConfigMap %s/%s: %w", namespace, ref.Name, err)
+		}
+		names := make([]string, 0, len(cm.Data))
+		for name := range cm.Data {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			compiled, err := redact.NewRule(name, cm.Data[name])
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, compiled)
+		}
+	}
+	return rules, nil
+}
+
+// podRedaction redacts the pod or node and logs of a single analysis. A nil
+// podRedaction leaves the data unchanged.
+type podRedaction struct {
+	redactor *redact.Redactor
+	maskEnv  bool
+	restore  bool
+}
+
+func newPodRedaction(spec *kopilotv1.RedactionSpec, rules []redact.Rule) (*podRedaction, error) {
+	if spec == nil {
+		return nil, nil
+	}
+	redactor, err := redact.New(spec.Detectors, rules)
+	if err != nil {
+		return nil, err
+	}
+	return &podRedaction{redactor: redactor, maskEnv: spec.MaskEnv, restore: spec.RestoreInIncidents}, nil
+}
+
+// apply returns the pod with the pod YAML and logs to send to the LLM.
+func (p *podRedaction) apply(pod UnHealthyPod) UnHealthyPod {
+	if p == nil {
+		return pod
+	}
+	pod.Pod = p.redactor.RedactPod(pod.Pod, p.maskEnv)
+	pod.Log = p.redactor.Redact(pod.Log)
+	pod.Context = p.redactSections(pod.Context)
+	if pod.Classification != nil {
+		classification := *pod.Classification
+		classification.Message = p.redactor.Redact(classification.Message)
+		pod.Classification = &classification
+	}
+	return pod
+}
+
+// applyNode returns the node with the node YAML and context to send to the LLM.
+func (p *podRedaction) applyNode(node UnHealthyNode) UnHealthyNode {
+	if p == nil {
+		return node
+	}
+	node.Node = p.redactor.RedactNode(node.Node)
+	node.Context = p.redactSections(node.Context)
+	node.Classification.Message = p.redactor.Redact(node.Classification.Message)
+	return node
+}
+
+// applyRollout returns the rollout with the pod templates, pod and context to
+// send to the LLM.
+func (p *podRedaction) applyRollout(rollout StalledRollout) StalledRollout {
+	if p == nil {
+		return rollout
+	}
+	rollout.OldTemplate = p.redactTemplate(rollout.OldTemplate)
+	rollout.NewTemplate = p.redactTemplate(rollout.NewTemplate)
+	if rollout.Pod != nil {
+		pod := p.apply(*rollout.Pod)
+		rollout.Pod = &pod
+	}
+	rollout.Context = p.redactSections(rollout.Context)
+	rollout.Classification.Message = p.redactor.Redact(rollout.Classification.Message)
+	return rollout
+}
+
+func (p *podRedaction) redactTemplate(template *corev1.PodTemplateSpec) *corev1.PodTemplateSpec {
+	if template == nil {
+		return nil
+	}
+	redacted := p.redactor.RedactPodTemplate(*template, p.maskEnv)
+	return &redacted
+}
+
+func (p *podRedaction) redactSections(sections []llm.ContextSection) []llm.ContextSection {
+	redacted := make([]llm.ContextSection, 0, len(sections))
+	for _, section := range sections {
+		redacted = append(redacted, llm.ContextSection{Title: section.Title, Content: p.redactor.Redact(section.Content)})
+	}
+	return redacted
+}
+
+// restored returns s for the Incident status, with the original values
+// restored if configured.
+func (p *podRedaction) restored(s string) string {
+	if p == nil || !p.restore {
+		return s
+	}
+	return p.redactor.Restore(s)
+}
+
+// restoredAnalysis returns the analysis for the Incident status.
+func (p *podRedaction) restoredAnalysis(analysis *kopilotv1.IncidentAnalysis) *kopilotv1.IncidentAnalysis {
+	if analysis == nil || p == nil || !p.restore {
+		return analysis
+	}
+	restored := *analysis
+	restored.Reason = p.redactor.Restore(analysis.Reason)
+	restored.Solution = p.redactor.Restore(analysis.Solution)
+	return &restored
+}
+
+// restoredRemediation returns the remediation for the Incident status.
+func (p *podRedaction) restoredRemediation(remediation *kopilotv1.IncidentRemediation) *kopilotv1.IncidentRemediation {
+	if remediation == nil || p == nil || !p.restore {
+		return remediation
+	}
+	return &kopilotv1.IncidentRemediation{
+		AutoFixResult:   p.redactor.Restore(remediation.AutoFixResult),
+		SearchResult:    p.redactor.Restore(remediation.SearchResult),
+		HumanHelpResult: p.redactor.Restore(remediation.HumanHelpResult),
+	}
+}
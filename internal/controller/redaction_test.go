@@ -0,0 +1,61 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+)
+
+var _ = Describe("Redaction", func() {
+	pod := UnHealthyPod{
+		Pod: corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "app",
+			Env:  []corev1.EnvVar{{Name: "DB_PASSWORD", Value: "s3cr3t"}},
+		}}}},
+		Log: "login failed for alice@example.com from 10.0.0.12, customer C-123456\n" +
+			"retry for alice@example.com with Authorization: Bearer abcdefgh12345678",
+	}
+
+	It("keeps the placeholders in incidents unless configured", func() {
+		redaction, err := newPodRedaction(&kopilotv1.RedactionSpec{MaskEnv: true}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		llmPod := redaction.apply(pod)
+
+		Expect(llmPod.Log).NotTo(ContainSubstring("alice@example.com"))
+		Expect(llmPod.Pod.Spec.Containers[0].Env[0].Value).To(Equal("[REDACTED_ENV_1]"))
+		Expect(pod.Pod.Spec.Containers[0].Env[0].Value).To(Equal("s3cr3t"))
+		Expect(redaction.restored(llmPod.Log)).To(Equal(llmPod.Log))
+	})
+
+	It("restores the original values in incidents if configured", func() {
+		redaction, err := newPodRedaction(&kopilotv1.RedactionSpec{RestoreInIncidents: true}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		llmPod := redaction.apply(pod)
+
+		Expect(redaction.restored(llmPod.Log)).To(Equal(pod.Log))
+		analysis := &kopilotv1.IncidentAnalysis{Reason: "检查 [REDACTED_IP_1] 的网络"}
+		Expect(redaction.restoredAnalysis(analysis).Reason).To(Equal("检查 10.0.0.12 的网络"))
+		// The analysis sent to the sinks keeps the placeholders.
+		Expect(analysis.Reason).To(Equal("检查 [REDACTED_IP_1] 的网络"))
+	})
+})
@@ -0,0 +1,565 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/Fl0rencess720/Kopilot/pkg/health"
+	"github.com/Fl0rencess720/Kopilot/pkg/llm"
+	"github.com/Fl0rencess720/Kopilot/pkg/sink"
+	"github.com/go-logr/logr"
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// maxListedRevisionPods bounds the pods listed in the rollout context.
+const maxListedRevisionPods = 20
+
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=list
+// +kubebuilder:rbac:groups=apps,resources=controllerrevisions,verbs=get
+
+// StalledRollout is a Deployment or StatefulSet whose rollout of a new
+// revision stalled.
+type StalledRollout struct {
+	Kind           string
+	Object         client.Object
+	Classification health.Classification
+
+	OldRevision string
+	NewRevision string
+	// OldTemplate and NewTemplate are the pod templates of the revisions. The
+	// old template is nil if the workload has no previous revision.
+	OldTemplate *corev1.PodTemplateSpec
+	NewTemplate *corev1.PodTemplateSpec
+	// NewPods are the pods of the new revision, the most recent first.
+	NewPods []corev1.Pod
+
+	// Pod is the pod of the new revision that is analyzed with its logs, if any.
+	Pod *UnHealthyPod
+	// Context is the cluster context of the rollout, such as events and owners.
+	Context     []llm.ContextSection
+	Fingerprint string
+}
+
+func rolloutTimeout(spec kopilotv1.KopilotSpec) time.Duration {
+	if spec.Detection != nil && spec.Detection.RolloutTimeout != nil {
+		return spec.Detection.RolloutTimeout.Duration
+	}
+	return health.DefaultRolloutTimeout
+}
+
+// getStalledRollouts returns the stalled rollouts of the Deployments and
+// StatefulSets in scope, i.e. whose pod template matches the pod selector.
+func (r *KopilotReconciler) getStalledRollouts(ctx context.Context, l logr.Logger, spec kopilotv1.KopilotSpec, now time.Time, run *runStatus) ([]StalledRollout, error) {
+	scope, err := r.buildPodScope(ctx, spec)
+	if err != nil {
+		l.Error(err, "unable to resolve pod scope")
+		return nil, err
+	}
+
+	namespaces := []string{metav1.NamespaceAll}
+	if scope.namespaces != nil {
+		namespaces = sets.List(scope.namespaces)
+	}
+	var rollouts []StalledRollout
+	var scanned int32
+	for _, namespace := range namespaces {
+		if namespace != metav1.NamespaceAll && !scope.containsNamespace(namespace) {
+			continue
+		}
+		deployments, err := r.Clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			l.Error(err, "unable to list deployments", "namespace", namespace)
+			return nil, err
+		}
+		for i := range deployments.Items {
+			deployment := &deployments.Items[i]
+			if !scope.containsTemplate(deployment.Namespace, deployment.Spec.Template) {
+				continue
+			}
+			scanned++
+			c := health.EvaluateDeployment(deployment)
+			if c == nil {
+				continue
+			}
+			rollout, err := r.deploymentRollout(ctx, deployment, *c)
+			if err != nil {
+				l.Error(err, "unable to get the revisions of deployment", "deployment", deployment.Name, "namespace", deployment.Namespace)
+				continue
+			}
+			rollouts = append(rollouts, rollout)
+		}
+
+		statefulSets, err := r.Clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			l.Error(err, "unable to list statefulsets", "namespace", namespace)
+			return nil, err
+		}
+		for i := range statefulSets.Items {
+			statefulSet := &statefulSets.Items[i]
+			if !scope.containsTemplate(statefulSet.Namespace, statefulSet.Spec.Template) {
+				continue
+			}
+			scanned++
+			rollout, err := r.statefulSetRollout(ctx, statefulSet, now, rolloutTimeout(spec))
+			if err != nil {
+				l.Error(err, "unable to get the revisions of statefulset", "statefulset", statefulSet.Name, "namespace", statefulSet.Namespace)
+				continue
+			}
+			if rollout != nil {
+				rollouts = append(rollouts, *rollout)
+			}
+		}
+	}
+	run.stats.RolloutsScanned = scanned
+	run.stats.RolloutsStalled = int32(len(rollouts))
+	return rollouts, nil
+}
+
+// deploymentRollout returns the stalled rollout of a Deployment, from the
+// ReplicaSet of its current revision and the ReplicaSet of the previous one.
+func (r *KopilotReconciler) deploymentRollout(ctx context.Context, deployment *appsv1.Deployment, c health.Classification) (StalledRollout, error) {
+	rollout := StalledRollout{
+		Kind:           "Deployment",
+		Object:         deployment,
+		Classification: c,
+		NewRevision:    strconv.FormatInt(revision(deployment), 10),
+		NewTemplate:    &deployment.Spec.Template,
+	}
+	replicaSets, err := r.replicaSetRevisions(ctx, deployment)
+	if err != nil {
+		return rollout, err
+	}
+	var newReplicaSet *appsv1.ReplicaSet
+	for i := range replicaSets {
+		rs := &replicaSets[i]
+		switch {
+		case revision(rs) == revision(deployment):
+			newReplicaSet = rs
+		case revision(rs) < revision(deployment) && rollout.OldTemplate == nil:
+			rollout.OldRevision = strconv.FormatInt(revision(rs), 10)
+			rollout.OldTemplate = &rs.Spec.Template
+		}
+	}
+	if newReplicaSet == nil {
+		return rollout, nil
+	}
+	rollout.NewTemplate = &newReplicaSet.Spec.Template
+
+	pods, err := r.selectorPods(ctx, deployment.Namespace, deployment.Spec.Selector)
+	if err != nil {
+		return rollout, err
+	}
+	for _, pod := range pods {
+		if metav1.IsControlledBy(&pod, newReplicaSet) {
+			rollout.NewPods = append(rollout.NewPods, pod)
+		}
+	}
+	return rollout, nil
+}
+
+// statefulSetRollout returns the stalled rollout of a StatefulSet, or nil if
+// its update is progressing. The update last progressed when its revision was
+// created or a pod of the revision was created or became ready.
+func (r *KopilotReconciler) statefulSetRollout(ctx context.Context, statefulSet *appsv1.StatefulSet, now time.Time, timeout time.Duration) (*StalledRollout, error) {
+	status := statefulSet.Status
+	if status.UpdateRevision == "" || status.UpdateRevision == status.CurrentRevision {
+		return nil, nil
+	}
+	updateRevision, err := r.Clientset.AppsV1().ControllerRevisions(statefulSet.Namespace).Get(ctx, status.UpdateRevision, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := r.selectorPods(ctx, statefulSet.Namespace, statefulSet.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	lastProgress := updateRevision.CreationTimestamp.Time
+	var newPods []corev1.Pod
+	for _, pod := range pods {
+		if !metav1.IsControlledBy(&pod, statefulSet) || pod.Labels[appsv1.ControllerRevisionHashLabelKey] != status.UpdateRevision {
+			continue
+		}
+		newPods = append(newPods, pod)
+		if pod.CreationTimestamp.After(lastProgress) {
+			lastProgress = pod.CreationTimestamp.Time
+		}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue && condition.LastTransitionTime.After(lastProgress) {
+				lastProgress = condition.LastTransitionTime.Time
+			}
+		}
+	}
+
+	c := health.EvaluateStatefulSet(statefulSet, lastProgress, now, timeout)
+	if c == nil {
+		return nil, nil
+	}
+	rollout := &StalledRollout{
+		Kind:           "StatefulSet",
+		Object:         statefulSet,
+		Classification: *c,
+		OldRevision:    status.CurrentRevision,
+		NewRevision:    status.UpdateRevision,
+		NewPods:        newPods,
+	}
+	if rollout.NewTemplate, err = revisionTemplate(updateRevision); err != nil {
+		return nil, err
+	}
+	if status.CurrentRevision != "" {
+		currentRevision, err := r.Clientset.AppsV1().ControllerRevisions(statefulSet.Namespace).Get(ctx, status.CurrentRevision, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		if err == nil {
+			if rollout.OldTemplate, err = revisionTemplate(currentRevision); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return rollout, nil
+}
+
+// revisionTemplate returns the pod template of a StatefulSet revision. The
+// revision stores a patch that replaces the template of the spec.
+func revisionTemplate(revision *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
+	var patch struct {
+		Spec struct {
+			Template corev1.PodTemplateSpec `json:"template"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(revision.Data.Raw, &patch); err != nil {
+		return nil, fmt.Errorf("invalid controller revision %s: %w", revision.Name, err)
+	}
+	return &patch.Spec.Template, nil
+}
+
+// selectorPods returns the pods matching a workload selector, the most recent
+// first.
+func (r *KopilotReconciler) selectorPods(ctx context.Context, namespace string, labelSelector *metav1.LabelSelector) ([]corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+	list, err := r.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	var pods []corev1.Pod
+	for _, pod := range list.Items {
+		if selector.Matches(labels.Set(pod.Labels)) {
+			pods = append(pods, pod)
+		}
+	}
+	sort.SliceStable(pods, func(i, j int) bool {
+		return pods[i].CreationTimestamp.After(pods[j].CreationTimestamp.Time)
+	})
+	return pods, nil
+}
+
+// analyzeStalledRollouts deduplicates the stalled rollouts against the open
+// incidents of the Kopilot, then fetches the logs of a pod of the new revision
+// and gathers the context of each remaining rollout.
+func (r *KopilotReconciler) analyzeStalledRollouts(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, stalledRollouts []StalledRollout, now time.Time, run *runStatus) error {
+	r.syncIncidents(ctx, l, kopilot)
+
+	rollouts := r.incidents.observeRollouts(client.ObjectKeyFromObject(kopilot), stalledRollouts, reNotifyInterval(kopilot.Spec), now)
+	if skipped := len(stalledRollouts) - len(rollouts); skipped > 0 {
+		l.Info("Skipping rollouts of already notified incidents", "count", skipped)
+	}
+	if len(rollouts) == 0 {
+		return nil
+	}
+
+	evaluator, err := r.healthEvaluator(ctx, kopilot)
+	if err != nil {
+		l.Error(err, "unable to compile health rules")
+		run.fail(err)
+		return err
+	}
+	for i := range rollouts {
+		if pod := newRevisionPod(evaluator, rollouts[i].NewPods, now); pod != nil {
+			if fetched := r.fetchPodLogs(ctx, l, kopilot, []UnHealthyPod{*pod}, run); len(fetched) > 0 {
+				rollouts[i].Pod = &fetched[0]
+			}
+		}
+		rollouts[i].Context = r.rolloutContext(ctx, l, rollouts[i])
+	}
+
+	return r.sendStalledRolloutsToLLM(ctx, l, kopilot, rollouts, now, run)
+}
+
+// dropStalledRolloutPods drops the unhealthy pods of the Deployments and
+// StatefulSets whose rollout is stalled. The pods are analyzed with the
+// incident of the rollout, not in a second incident of their own.
+func (r *KopilotReconciler) dropStalledRolloutPods(ctx context.Context, l logr.Logger, spec kopilotv1.KopilotSpec, unhealthyPods []UnHealthyPod, now time.Time) []UnHealthyPod {
+	stalled := map[string]bool{}
+	isStalled := func(pod UnHealthyPod) bool {
+		key := pod.Pod.Namespace + "/" + pod.OwnerKind + "/" + pod.OwnerName
+		if result, ok := stalled[key]; ok {
+			return result
+		}
+		var err error
+		switch pod.OwnerKind {
+		case "Deployment":
+			var deployment *appsv1.Deployment
+			deployment, err = r.Clientset.AppsV1().Deployments(pod.Pod.Namespace).Get(ctx, pod.OwnerName, metav1.GetOptions{})
+			if err == nil {
+				stalled[key] = health.EvaluateDeployment(deployment) != nil
+			}
+		case "StatefulSet":
+			var statefulSet *appsv1.StatefulSet
+			statefulSet, err = r.Clientset.AppsV1().StatefulSets(pod.Pod.Namespace).Get(ctx, pod.OwnerName, metav1.GetOptions{})
+			if err == nil {
+				var rollout *StalledRollout
+				rollout, err = r.statefulSetRollout(ctx, statefulSet, now, rolloutTimeout(spec))
+				stalled[key] = err == nil && rollout != nil
+			}
+		}
+		if err != nil && !apierrors.IsNotFound(err) {
+			l.Error(err, "unable to check the rollout of the owner of pod", "kind", pod.OwnerKind, "name", pod.OwnerName, "namespace", pod.Pod.Namespace)
+		}
+		return stalled[key]
+	}
+
+	var result []UnHealthyPod
+	for _, pod := range unhealthyPods {
+		if isStalled(pod) {
+			continue
+		}
+		result = append(result, pod)
+	}
+	if skipped := len(unhealthyPods) - len(result); skipped > 0 {
+		l.Info("Skipping pods of stalled rollouts, they are analyzed with the rollout", "count", skipped)
+	}
+	return result
+}
+
+// newRevisionPod returns the pod of the new revision to analyze: the most
+// recent unhealthy one, or the most recent one if all are healthy.
+func newRevisionPod(evaluator *health.Evaluator, pods []corev1.Pod, now time.Time) *UnHealthyPod {
+	if len(pods) == 0 {
+		return nil
+	}
+	if unhealthyPods := filterUnhealthyPods(evaluator, pods, now); len(unhealthyPods) > 0 {
+		return &unhealthyPods[0]
+	}
+	return &UnHealthyPod{Pod: pods[0]}
+}
+
+func (r *KopilotReconciler) sendStalledRolloutsToLLM(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, rollouts []StalledRollout, now time.Time, run *runStatus) error {
+	return r.analyzeAndNotify(ctx, l, kopilot, len(rollouts), func(i int, redaction *podRedaction) analysisItem {
+		rollout := rollouts[i]
+		llmRollout := redaction.applyRollout(rollout)
+
+		namespace, name := rollout.Object.GetNamespace(), rollout.Object.GetName()
+		classification := llmRollout.Classification
+		input := llm.AnalysisInput{
+			Rollout: &llm.Rollout{
+				Kind:        rollout.Kind,
+				Namespace:   namespace,
+				Name:        name,
+				OldRevision: rollout.OldRevision,
+				NewRevision: rollout.NewRevision,
+			},
+			Classification: classification.String(),
+			Context:        append(templateDiff(llmRollout), llmRollout.Context...),
+		}
+		incident := analyzedIncident{fingerprint: rollout.Fingerprint}
+		if llmRollout.Pod != nil {
+			input.Pod = llmRollout.Pod.Pod
+			input.Logs = llmRollout.Pod.Log
+			incident.podRef = podReference(rollout.Pod.Pod)
+			incident.logs = redaction.restored(input.Logs)
+		}
+
+		msg := sink.Message{
+			Namespace:      namespace,
+			OwnerKind:      rollout.Kind,
+			OwnerName:      name,
+			Fingerprint:    rollout.Fingerprint,
+			FailureReason:  classification.Reason,
+			Severity:       string(classification.Severity),
+			FailureMessage: classification.Message,
+			LogsExcerpt:    logsExcerpt(input.Logs),
+		}
+		if rollout.Pod != nil {
+			msg.PodName = rollout.Pod.Pod.Name
+			msg.PodUID = string(rollout.Pod.Pod.UID)
+		}
+		for _, pod := range rollout.NewPods {
+			msg.AffectedPods = append(msg.AffectedPods, pod.Name)
+		}
+		return analysisItem{
+			logger:    l.WithValues(strings.ToLower(rollout.Kind), name, "namespace", namespace),
+			input:     input,
+			incidents: []analyzedIncident{incident},
+			analyzed: func(summary string) {
+				run.analyzedRollout(rollout.Kind, namespace, name, summary)
+			},
+			msg: msg,
+		}
+	}, now, run)
+}
+
+// rolloutContext collects the cluster context of a stalled rollout: the pods
+// of the new revision and the context of the analyzed pod, or the spec, status
+// and events of the workload if the new revision has no pods.
+func (r *KopilotReconciler) rolloutContext(ctx context.Context, l logr.Logger, rollout StalledRollout) []llm.ContextSection {
+	var sections []llm.ContextSection
+	if len(rollout.NewPods) > 0 {
+		sections = append(sections, llm.ContextSection{
+			Title:   fmt.Sprintf("Pods of revision %s (%d)", rollout.NewRevision, len(rollout.NewPods)),
+			Content: formatRevisionPods(rollout.NewPods),
+		})
+	}
+	if rollout.Pod != nil {
+		return append(sections, r.podContext(ctx, l, rollout.Pod.Pod)...)
+	}
+
+	object := rollout.Object
+	content, err := formatOwner(object)
+	if err != nil {
+		l.Error(err, "unable to format owner", "kind", rollout.Kind, "name", object.GetName())
+	} else {
+		sections = append(sections, llm.ContextSection{
+			Title:   fmt.Sprintf("%s %s (spec and status, pod template omitted)", rollout.Kind, object.GetName()),
+			Content: content,
+		})
+	}
+
+	objects := []owner{{kind: rollout.Kind, object: object}}
+	if deployment, ok := object.(*appsv1.Deployment); ok {
+		replicaSets, err := r.replicaSetRevisions(ctx, deployment)
+		if err != nil {
+			l.Error(err, "unable to list ReplicaSets", "deployment", deployment.Name, "namespace", deployment.Namespace)
+		} else if len(replicaSets) > 0 {
+			sections = append(sections, llm.ContextSection{
+				Title:   fmt.Sprintf("ReplicaSet revisions of Deployment %s", deployment.Name),
+				Content: formatRevisions(deployment, replicaSets),
+			})
+			// The ReplicaSet of the new revision records why it cannot
+			// create pods, e.g. an exceeded quota.
+			if revision(&replicaSets[0]) == revision(deployment) {
+				objects = append(objects, owner{kind: "ReplicaSet", object: &replicaSets[0]})
+			}
+		}
+	}
+	for _, o := range objects {
+		events, err := r.listEvents(ctx, object.GetNamespace(), o.object.GetUID())
+		if err != nil {
+			l.Error(err, "unable to list events", "kind", o.kind, "name", o.object.GetName(), "namespace", object.GetNamespace())
+		} else if len(events) > 0 {
+			sections = append(sections, llm.ContextSection{
+				Title:   fmt.Sprintf("Events of %s %s", o.kind, o.object.GetName()),
+				Content: formatEvents(events),
+			})
+		}
+	}
+	return sections
+}
+
+// templateDiff returns the unified diff of the pod templates of the old and
+// new revision of a rollout, or nil if the rollout has no old revision.
+func templateDiff(rollout StalledRollout) []llm.ContextSection {
+	if rollout.OldTemplate == nil || rollout.NewTemplate == nil {
+		return nil
+	}
+	before, err := formatTemplate(*rollout.OldTemplate)
+	if err != nil {
+		return nil
+	}
+	after, err := formatTemplate(*rollout.NewTemplate)
+	if err != nil {
+		return nil
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(before),
+		B:        difflib.SplitLines(after),
+		FromFile: "revision " + rollout.OldRevision,
+		ToFile:   "revision " + rollout.NewRevision,
+		Context:  3,
+	})
+	if err != nil {
+		return nil
+	}
+	if diff == "" {
+		diff = "the pod templates are identical"
+	}
+	return []llm.ContextSection{{
+		Title:   fmt.Sprintf("Pod template changes from revision %s to %s", rollout.OldRevision, rollout.NewRevision),
+		Content: diff,
+	}}
+}
+
+// formatTemplate renders a pod template without the labels that differ
+// between all revisions.
+func formatTemplate(template corev1.PodTemplateSpec) (string, error) {
+	template = *template.DeepCopy()
+	delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	delete(template.Labels, appsv1.ControllerRevisionHashLabelKey)
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&template)
+	if err != nil {
+		return "", err
+	}
+	out, err := yaml.Marshal(u)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func formatRevisionPods(pods []corev1.Pod) string {
+	var lines []string
+	for i, pod := range pods {
+		if i == maxListedRevisionPods {
+			lines = append(lines, fmt.Sprintf("... and %d more", len(pods)-i))
+			break
+		}
+		var ready, restarts int
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Ready {
+				ready++
+			}
+			restarts += int(status.RestartCount)
+		}
+		line := fmt.Sprintf("%s: %s, %d/%d containers ready, %d restarts, created %s",
+			pod.Name, pod.Status.Phase, ready, len(pod.Spec.Containers), restarts, pod.CreationTimestamp.UTC().Format(time.RFC3339))
+		if pod.Spec.NodeName != "" {
+			line += ", node " + pod.Spec.NodeName
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
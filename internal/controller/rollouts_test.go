@@ -0,0 +1,220 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/Fl0rencess720/Kopilot/pkg/health"
+	"github.com/go-logr/logr"
+)
+
+var _ = Describe("Rollout analysis", func() {
+	isController := true
+	now := time.Now()
+	labels := map[string]string{"app": "web"}
+	template := func(image string) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: image}}},
+		}
+	}
+	pod := func(name string, owner metav1.OwnerReference, podLabels map[string]string, created time.Time, status corev1.PodStatus) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name, Namespace: "default", Labels: podLabels,
+				CreationTimestamp: metav1.NewTime(created),
+				OwnerReferences:   []metav1.OwnerReference{owner},
+			},
+			Spec:   corev1.PodSpec{Containers: []corev1.Container{{Name: "web"}}},
+			Status: status,
+		}
+	}
+	crashLooping := corev1.PodStatus{
+		Phase: corev1.PodRunning,
+		ContainerStatuses: []corev1.ContainerStatus{{
+			Name: "web", RestartCount: 5,
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+		}},
+	}
+	running := corev1.PodStatus{
+		Phase:             corev1.PodRunning,
+		ContainerStatuses: []corev1.ContainerStatus{{Name: "web", Ready: true}},
+	}
+
+	It("should analyze a Deployment that exceeded its progress deadline through a pod of the new revision", func() {
+		replicas := int32(2)
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "web", Namespace: "default", UID: "deployment-uid",
+				Annotations: map[string]string{revisionAnnotation: "2"},
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: template("web:v2"),
+			},
+			Status: appsv1.DeploymentStatus{
+				UpdatedReplicas: 1, AvailableReplicas: 2, UnavailableReplicas: 1,
+				Conditions: []appsv1.DeploymentCondition{{
+					Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded",
+					Message: `ReplicaSet "web-2" has timed out progressing.`,
+				}},
+			},
+		}
+		replicaSet := func(name, rev, image string) *appsv1.ReplicaSet {
+			rsTemplate := template(image)
+			rsTemplate.Labels = map[string]string{"app": "web", appsv1.DefaultDeploymentUniqueLabelKey: name}
+			return &appsv1.ReplicaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name, Namespace: "default", UID: types.UID(name), Labels: labels,
+					Annotations: map[string]string{revisionAnnotation: rev},
+					OwnerReferences: []metav1.OwnerReference{{
+						Kind: "Deployment", Name: "web", UID: deployment.UID, Controller: &isController,
+					}},
+				},
+				Spec: appsv1.ReplicaSetSpec{Template: rsTemplate},
+			}
+		}
+		owner := func(rs string) metav1.OwnerReference {
+			return metav1.OwnerReference{Kind: "ReplicaSet", Name: rs, UID: types.UID(rs), Controller: &isController}
+		}
+
+		r := &KopilotReconciler{Clientset: fake.NewClientset(
+			deployment,
+			replicaSet("web-1", "1", "web:v1"),
+			replicaSet("web-2", "2", "web:v2"),
+			pod("web-1-a", owner("web-1"), labels, now.Add(-time.Hour), running),
+			pod("web-2-a", owner("web-2"), labels, now.Add(-20*time.Minute), crashLooping),
+		)}
+
+		spec := kopilotv1.KopilotSpec{Targets: []string{targetRollouts}}
+		run := &runStatus{}
+		rollouts, err := r.getStalledRollouts(context.Background(), logr.Discard(), spec, now, run)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rollouts).To(HaveLen(1))
+		Expect(run.stats.RolloutsScanned).To(Equal(int32(1)))
+
+		rollout := rollouts[0]
+		Expect(rollout.Classification.Reason).To(Equal("ProgressDeadlineExceeded"))
+		Expect(rollout.OldRevision).To(Equal("1"))
+		Expect(rollout.NewRevision).To(Equal("2"))
+		Expect(rollout.NewPods).To(HaveLen(1))
+		Expect(rollout.NewPods[0].Name).To(Equal("web-2-a"))
+
+		analyzed := newRevisionPod(health.NewEvaluator(health.Thresholds{}), rollout.NewPods, now)
+		Expect(analyzed).NotTo(BeNil())
+		Expect(podClassification(*analyzed).Reason).To(Equal("CrashLoopBackOff"))
+
+		diff := templateDiff(rollout)
+		Expect(diff).To(HaveLen(1))
+		Expect(diff[0].Title).To(Equal("Pod template changes from revision 1 to 2"))
+		Expect(diff[0].Content).To(MatchRegexp(`(?m)^-.*image: web:v1$`))
+		Expect(diff[0].Content).To(MatchRegexp(`(?m)^\+.*image: web:v2$`))
+		Expect(diff[0].Content).NotTo(ContainSubstring(appsv1.DefaultDeploymentUniqueLabelKey))
+	})
+
+	It("should detect a StatefulSet update that made no progress, but not a ready partitioned canary", func() {
+		replicas, partition := int32(3), int32(2)
+		statefulSet := func(name string, partition int32, updated, ready int32) *appsv1.StatefulSet {
+			return &appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name)},
+				Spec: appsv1.StatefulSetSpec{
+					Replicas: &replicas,
+					Selector: &metav1.LabelSelector{MatchLabels: labels},
+					Template: template("db:v2"),
+					UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+						Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+						RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: &partition},
+					},
+				},
+				Status: appsv1.StatefulSetStatus{
+					CurrentRevision: name + "-1", UpdateRevision: name + "-2",
+					UpdatedReplicas: updated, ReadyReplicas: ready,
+				},
+			}
+		}
+		controllerRevision := func(name, image string) *appsv1.ControllerRevision {
+			raw, err := json.Marshal(map[string]any{"spec": map[string]any{"template": template(image)}})
+			Expect(err).NotTo(HaveOccurred())
+			return &appsv1.ControllerRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", CreationTimestamp: metav1.NewTime(now.Add(-time.Hour))},
+				Data:       runtime.RawExtension{Raw: raw},
+			}
+		}
+
+		stalled := statefulSet("db", 0, 1, 2)
+		canary := statefulSet("canary", partition, 1, 3)
+		r := &KopilotReconciler{Clientset: fake.NewClientset(
+			stalled, canary,
+			controllerRevision("db-1", "db:v1"), controllerRevision("db-2", "db:v2"),
+			controllerRevision("canary-1", "db:v1"), controllerRevision("canary-2", "db:v2"),
+			pod("db-2", metav1.OwnerReference{Kind: "StatefulSet", Name: "db", UID: "db", Controller: &isController},
+				map[string]string{"app": "web", appsv1.ControllerRevisionHashLabelKey: "db-2"}, now.Add(-30*time.Minute), crashLooping),
+		)}
+
+		rollout, err := r.statefulSetRollout(context.Background(), stalled, now, 10*time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rollout).NotTo(BeNil())
+		Expect(rollout.Classification.Reason).To(Equal("RolloutStalled"))
+		Expect(rollout.NewPods).To(HaveLen(1))
+		Expect(templateDiff(*rollout)[0].Content).To(MatchRegexp(`(?m)^\+.*image: db:v2$`))
+
+		rollout, err = r.statefulSetRollout(context.Background(), canary, now, 10*time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rollout).To(BeNil())
+	})
+
+	It("should drop the unhealthy pods of a Deployment with a stalled rollout", func() {
+		deployment := func(name string, conditions ...appsv1.DeploymentCondition) *appsv1.Deployment {
+			return &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Status:     appsv1.DeploymentStatus{Conditions: conditions},
+			}
+		}
+		unhealthyPod := func(name, ownerName string) UnHealthyPod {
+			return UnHealthyPod{
+				Pod:       *pod(name, metav1.OwnerReference{Kind: "ReplicaSet", Name: ownerName + "-1"}, labels, now, crashLooping),
+				OwnerKind: "Deployment",
+				OwnerName: ownerName,
+			}
+		}
+		r := &KopilotReconciler{Clientset: fake.NewClientset(
+			deployment("web", appsv1.DeploymentCondition{
+				Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded",
+			}),
+			deployment("api"),
+		)}
+
+		pods := r.dropStalledRolloutPods(context.Background(), logr.Discard(), kopilotv1.KopilotSpec{},
+			[]UnHealthyPod{unhealthyPod("web-1-a", "web"), unhealthyPod("api-1-a", "api"), unhealthyPod("web-1-b", "web")}, now)
+		Expect(pods).To(HaveLen(1))
+		Expect(pods[0].Pod.Name).To(Equal("api-1-a"))
+	})
+})
@@ -0,0 +1,162 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// podScope describes which pods a Kopilot instance is responsible for.
+type podScope struct {
+	podSelector labels.Selector
+	// namespaces is the set of namespaces in scope. A nil set means all namespaces.
+	namespaces sets.Set[string]
+	excluded   sets.Set[string]
+}
+
+// buildPodScope resolves the pod selector, namespace selector and namespace
+// allow/deny lists of a Kopilot into a podScope.
+func (r *KopilotReconciler) buildPodScope(ctx context.Context, spec kopilotv1.KopilotSpec) (*podScope, error) {
+	scope, err := newPodScope(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if spec.NamespaceSelector != nil {
+		nsSelector, err := metav1.LabelSelectorAsSelector(spec.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace selector: %w", err)
+		}
+		namespaceList, err := r.Clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: nsSelector.String()})
+		if err != nil {
+			return nil, fmt.Errorf("unable to list namespaces: %w", err)
+		}
+		selected := sets.New[string]()
+		for _, ns := range namespaceList.Items {
+			selected.Insert(ns.Name)
+		}
+		if scope.namespaces == nil {
+			scope.namespaces = selected
+		} else {
+			scope.namespaces = scope.namespaces.Intersection(selected)
+		}
+	}
+
+	return scope, nil
+}
+
+// newPodScope resolves the pod selector and namespace allow/deny lists of a
+// Kopilot into a podScope, without its namespace selector.
+func newPodScope(spec kopilotv1.KopilotSpec) (*podScope, error) {
+	scope := &podScope{
+		podSelector: labels.Everything(),
+		excluded:    sets.New(spec.ExcludedNamespaces...),
+	}
+
+	if spec.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(spec.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector: %w", err)
+		}
+		scope.podSelector = selector
+	}
+
+	if len(spec.Namespaces) > 0 {
+		scope.namespaces = sets.New(spec.Namespaces...)
+	}
+	return scope, nil
+}
+
+// inPodScope reports whether the pod is in the scope of a Kopilot. The
+// namespace selector is matched against the namespace from the informer
+// cache, so that it is cheap enough for every pod event.
+func (r *KopilotReconciler) inPodScope(ctx context.Context, spec kopilotv1.KopilotSpec, pod *corev1.Pod) (bool, error) {
+	scope, err := newPodScope(spec)
+	if err != nil {
+		return false, err
+	}
+	if !scope.contains(pod) {
+		return false, nil
+	}
+	if spec.NamespaceSelector == nil {
+		return true, nil
+	}
+
+	nsSelector, err := metav1.LabelSelectorAsSelector(spec.NamespaceSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid namespace selector: %w", err)
+	}
+	var namespace corev1.Namespace
+	if err := r.Get(ctx, client.ObjectKey{Name: pod.Namespace}, &namespace); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return nsSelector.Matches(labels.Set(namespace.Labels)), nil
+}
+
+// containsNamespace reports whether pods in the given namespace are in scope.
+func (s *podScope) containsNamespace(namespace string) bool {
+	if s.excluded.Has(namespace) {
+		return false
+	}
+	return s.namespaces == nil || s.namespaces.Has(namespace)
+}
+
+// contains reports whether the given pod is in scope.
+func (s *podScope) contains(pod *corev1.Pod) bool {
+	return s.containsNamespace(pod.Namespace) && s.podSelector.Matches(labels.Set(pod.Labels))
+}
+
+// containsTemplate reports whether the pods of a workload with the given pod
+// template in the given namespace are in scope.
+func (s *podScope) containsTemplate(namespace string, template corev1.PodTemplateSpec) bool {
+	return s.containsNamespace(namespace) && s.podSelector.Matches(labels.Set(template.Labels))
+}
+
+// listPods lists all pods in scope.
+func (r *KopilotReconciler) listPods(ctx context.Context, scope *podScope) ([]corev1.Pod, error) {
+	listOptions := metav1.ListOptions{LabelSelector: scope.podSelector.String()}
+
+	namespaces := []string{metav1.NamespaceAll}
+	if scope.namespaces != nil {
+		namespaces = sets.List(scope.namespaces)
+	}
+
+	var pods []corev1.Pod
+	for _, namespace := range namespaces {
+		if namespace != metav1.NamespaceAll && !scope.containsNamespace(namespace) {
+			continue
+		}
+		podList, err := r.Clientset.CoreV1().Pods(namespace).List(ctx, listOptions)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list pods in namespace %q: %w", namespace, err)
+		}
+		for _, pod := range podList.Items {
+			if scope.containsNamespace(pod.Namespace) {
+				pods = append(pods, pod)
+			}
+		}
+	}
+	return pods, nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/Fl0rencess720/Kopilot/internal/controller/utils"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// secretsFor returns the secret getter of a Kopilot. References without a
+// namespace are resolved in the namespace of the Kopilot.
+func (r *KopilotReconciler) secretsFor(kopilot *kopilotv1.Kopilot) utils.SecretGetter {
+	return r.secrets.ForNamespace(r.Clientset, kopilot.Namespace)
+}
+
+// secretKeyRefs returns the secret references used by the spec.
+func secretKeyRefs(spec kopilotv1.KopilotSpec) []kopilotv1.SecretKeyRef {
+	var refs []kopilotv1.SecretKeyRef
+
+	switch spec.LLM.Model {
+	case "gemini":
+		refs = append(refs, spec.LLM.Gemini.APIKeySecretRef)
+	case "deepseek":
+		refs = append(refs, spec.LLM.DeepSeek.APIKeySecretRef)
+	case "openai":
+		if spec.LLM.OpenAI != nil && spec.LLM.OpenAI.APIKeySecretRef != nil {
+			refs = append(refs, *spec.LLM.OpenAI.APIKeySecretRef)
+		}
+	}
+
+	if loki := spec.LogSource.Loki; loki != nil && spec.LogSource.Type == "Loki" {
+		if auth := loki.Auth; auth != nil {
+			for _, ref := range []*kopilotv1.SecretKeyRef{auth.UsernameSecretRef, auth.PasswordSecretRef, auth.TokenSecretRef} {
+				if ref != nil {
+					refs = append(refs, *ref)
+				}
+			}
+		}
+		if tls := loki.TLS; tls != nil {
+			for _, ref := range []*kopilotv1.SecretKeyRef{tls.CASecretRef, tls.CertSecretRef, tls.KeySecretRef} {
+				if ref != nil {
+					refs = append(refs, *ref)
+				}
+			}
+		}
+		for _, header := range loki.Headers {
+			if header.ValueSecretRef != nil {
+				refs = append(refs, *header.ValueSecretRef)
+			}
+		}
+	}
+
+	if es := spec.LogSource.Elasticsearch; es != nil && spec.LogSource.Type == "Elasticsearch" {
+		for _, ref := range []*kopilotv1.SecretKeyRef{es.UsernameSecretRef, es.PasswordSecretRef, es.APIKeySecretRef} {
+			if ref != nil {
+				refs = append(refs, *ref)
+			}
+		}
+	}
+
+	if kb := spec.KnowledgeBase; kb != nil {
+		refs = append(refs, kb.UsernameSecretRef, kb.PasswordSecretRef)
+		if kb.EmbeddingProvider == "ark" {
+			refs = append(refs, kb.ArkSpec.APIKeySecretRef)
+		}
+	}
+
+	for _, s := range spec.Notification.Sinks {
+		switch {
+		case s.Feishu != nil:
+			refs = append(refs, s.Feishu.WebhookSecretRef, s.Feishu.SignatureSecretRef)
+		case s.Slack != nil:
+			if s.Slack.WebhookSecretRef != nil {
+				refs = append(refs, *s.Slack.WebhookSecretRef)
+			}
+			if s.Slack.BotTokenSecretRef != nil {
+				refs = append(refs, *s.Slack.BotTokenSecretRef)
+			}
+		case s.Webhook != nil:
+			refs = append(refs, s.Webhook.URLSecretRef)
+			if s.Webhook.SigningSecretRef != nil {
+				refs = append(refs, *s.Webhook.SigningSecretRef)
+			}
+			for _, header := range s.Webhook.Headers {
+				refs = append(refs, header.ValueSecretRef)
+			}
+		}
+	}
+
+	return refs
+}
+
+// checkSecrets resolves all secret references of the Kopilot and records the
+// result in the SecretsResolved condition. It returns whether the condition changed.
+func (r *KopilotReconciler) checkSecrets(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot) bool {
+	secrets := r.secretsFor(kopilot)
+
+	var missing []string
+	for _, ref := range secretKeyRefs(kopilot.Spec) {
+		if ref.Name == "" {
+			continue
+		}
+		if _, err := secrets.GetSecret(ctx, ref); err != nil {
+			l.Error(err, "unable to resolve secret", "secret", utils.SecretKey(ref, kopilot.Namespace), "key", ref.Key)
+			missing = append(missing, fmt.Sprintf("%s[%s]", utils.SecretKey(ref, kopilot.Namespace), ref.Key))
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:               kopilotv1.ConditionSecretsResolved,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Resolved",
+		Message:            "All referenced secrets are resolved",
+		ObservedGeneration: kopilot.Generation,
+	}
+	if len(missing) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "SecretNotFound"
+		condition.Message = "Unable to resolve " + strings.Join(missing, ", ")
+	}
+	changed := meta.SetStatusCondition(&kopilot.Status.Conditions, condition)
+	setReadyCondition(&kopilot.Status, kopilot.Generation)
+	return changed
+}
+
+// kopilotsForSecret drops the cached data of a changed Secret and returns the
+// Kopilots that reference it.
+func (r *KopilotReconciler) kopilotsForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	key := client.ObjectKeyFromObject(obj)
+	r.secrets.Invalidate(key)
+
+	var kopilots kopilotv1.KopilotList
+	if err := r.List(ctx, &kopilots); err != nil {
+		logf.FromContext(ctx).Error(err, "unable to list Kopilots")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, kopilot := range kopilots.Items {
+		for _, ref := range secretKeyRefs(kopilot.Spec) {
+			if utils.SecretKey(ref, kopilot.Namespace) == key {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&kopilot)})
+				break
+			}
+		}
+	}
+	return requests
+}
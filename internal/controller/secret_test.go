@@ -0,0 +1,85 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/go-logr/logr"
+)
+
+var _ = Describe("Secret resolution", func() {
+	ctx := context.Background()
+
+	var (
+		clientset *fake.Clientset
+		r         *KopilotReconciler
+		kopilot   *kopilotv1.Kopilot
+	)
+
+	BeforeEach(func() {
+		clientset = fake.NewClientset(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "llm", Namespace: "monitoring"},
+			Data:       map[string][]byte{"apiKey": []byte("v1")},
+		})
+		r = &KopilotReconciler{Clientset: clientset}
+		kopilot = &kopilotv1.Kopilot{
+			ObjectMeta: metav1.ObjectMeta{Name: "kopilot", Namespace: "monitoring"},
+			Spec: kopilotv1.KopilotSpec{
+				LLM: kopilotv1.LLMSpec{
+					Model:  "gemini",
+					Gemini: kopilotv1.GeminiSpec{APIKeySecretRef: kopilotv1.SecretKeyRef{Name: "llm", Key: "apiKey"}},
+				},
+			},
+		}
+	})
+
+	It("defaults to the namespace of the Kopilot and caches values until invalidated", func() {
+		ref := kopilotv1.SecretKeyRef{Name: "llm", Key: "apiKey"}
+		Expect(r.secretsFor(kopilot).GetSecret(ctx, ref)).To(Equal("v1"))
+
+		secret, err := clientset.CoreV1().Secrets("monitoring").Get(ctx, "llm", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		secret.Data["apiKey"] = []byte("v2")
+		_, err = clientset.CoreV1().Secrets("monitoring").Update(ctx, secret, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.secretsFor(kopilot).GetSecret(ctx, ref)).To(Equal("v1"))
+
+		r.secrets.Invalidate(types.NamespacedName{Namespace: "monitoring", Name: "llm"})
+		Expect(r.secretsFor(kopilot).GetSecret(ctx, ref)).To(Equal("v2"))
+	})
+
+	It("reports missing secrets in the SecretsResolved condition", func() {
+		Expect(r.checkSecrets(ctx, logr.Discard(), kopilot)).To(BeTrue())
+		Expect(meta.IsStatusConditionTrue(kopilot.Status.Conditions, kopilotv1.ConditionSecretsResolved)).To(BeTrue())
+
+		kopilot.Spec.LLM.Gemini.APIKeySecretRef.Key = "missing"
+		Expect(r.checkSecrets(ctx, logr.Discard(), kopilot)).To(BeTrue())
+		condition := meta.FindStatusCondition(kopilot.Status.Conditions, kopilotv1.ConditionSecretsResolved)
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Message).To(ContainSubstring("monitoring/llm[missing]"))
+	})
+})
@@ -0,0 +1,124 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/Fl0rencess720/Kopilot/pkg/sink"
+	"github.com/Fl0rencess720/Kopilot/pkg/sink/feishusink"
+	"github.com/Fl0rencess720/Kopilot/pkg/sink/slacksink"
+	"github.com/Fl0rencess720/Kopilot/pkg/sink/webhooksink"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// notificationSink is a configured sink together with its name.
+type notificationSink struct {
+	name string
+	sink sink.Sink
+}
+
+// buildSinks builds all sinks configured in the Kopilot. Sinks that cannot be
+// built are reported in the status and skipped.
+func (r *KopilotReconciler) buildSinks(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, now time.Time) []notificationSink {
+	pruneSinkStatuses(&kopilot.Status, kopilot.Spec.Notification.Sinks)
+
+	var sinks []notificationSink
+	for _, spec := range kopilot.Spec.Notification.Sinks {
+		s, err := r.newSink(ctx, kopilot, spec)
+		if err != nil {
+			l.Error(err, "unable to create sink", "sink", spec.Name)
+			setSinkStatus(&kopilot.Status, spec.Name, err, now)
+			continue
+		}
+		sinks = append(sinks, notificationSink{name: spec.Name, sink: s})
+	}
+	return sinks
+}
+
+func (r *KopilotReconciler) newSink(ctx context.Context, kopilot *kopilotv1.Kopilot, spec kopilotv1.NotificationSink) (sink.Sink, error) {
+	secrets := r.secretsFor(kopilot)
+	switch {
+	case spec.Feishu != nil:
+		return feishusink.NewFeishuSink(ctx, secrets, *spec.Feishu)
+	case spec.Slack != nil:
+		return slacksink.NewSlackSink(ctx, secrets, *spec.Slack)
+	case spec.Webhook != nil:
+		return webhooksink.NewWebhookSink(ctx, secrets, *spec.Webhook)
+	default:
+		return nil, fmt.Errorf("no sink type configured for sink %s", spec.Name)
+	}
+}
+
+// deliver sends a notification to every sink independently, so that a failing
+// sink does not block the others. It returns the number of successful deliveries.
+func deliver(ctx context.Context, l logr.Logger, kopilot *kopilotv1.Kopilot, sinks []notificationSink, now time.Time, send func(context.Context, sink.Sink) error) int {
+	delivered := 0
+	for _, s := range sinks {
+		err := send(ctx, s.sink)
+		if err != nil {
+			l.Error(err, "unable to send notification", "sink", s.name)
+		} else {
+			delivered++
+		}
+		setSinkStatus(&kopilot.Status, s.name, err, now)
+	}
+	return delivered
+}
+
+func setSinkStatus(status *kopilotv1.KopilotStatus, name string, err error, now time.Time) {
+	var sinkStatus *kopilotv1.SinkStatus
+	for i := range status.Sinks {
+		if status.Sinks[i].Name == name {
+			sinkStatus = &status.Sinks[i]
+			break
+		}
+	}
+	if sinkStatus == nil {
+		status.Sinks = append(status.Sinks, kopilotv1.SinkStatus{Name: name})
+		sinkStatus = &status.Sinks[len(status.Sinks)-1]
+	}
+
+	if err != nil {
+		sinkStatus.Healthy = false
+		sinkStatus.LastFailureTime = &metav1.Time{Time: now}
+		sinkStatus.LastError = err.Error()
+		return
+	}
+	sinkStatus.Healthy = true
+	sinkStatus.LastSuccessTime = &metav1.Time{Time: now}
+	sinkStatus.LastError = ""
+}
+
+// pruneSinkStatuses drops the statuses of sinks that are no longer configured.
+func pruneSinkStatuses(status *kopilotv1.KopilotStatus, specs []kopilotv1.NotificationSink) {
+	configured := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		configured[spec.Name] = true
+	}
+	sinks := status.Sinks[:0]
+	for _, sinkStatus := range status.Sinks {
+		if configured[sinkStatus.Name] {
+			sinks = append(sinks, sinkStatus)
+		}
+	}
+	status.Sinks = sinks
+}
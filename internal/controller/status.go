@@ -0,0 +1,229 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// maxAnalysisSummaryLength bounds the length of Status.LastAnalysisResult.
+const maxAnalysisSummaryLength = 256
+
+// runStatus collects the outcome of a single reconcile run.
+type runStatus struct {
+	stats kopilotv1.RunStatistics
+
+	logsFetched  int
+	logSourceErr error
+
+	llmOK  bool
+	llmErr error
+
+	knowledgeBaseOK  bool
+	knowledgeBaseErr error
+
+	// lastErr is the last error of the run.
+	lastErr error
+	// failedTargets are the targets whose detection failed in the run.
+	failedTargets sets.Set[string]
+	// summary is a short summary of the last analysis of the run.
+	summary string
+}
+
+func (run *runStatus) fail(err error) {
+	run.lastErr = err
+}
+
+// failTarget records that the detection of a target failed, so that the run
+// does not resolve the incidents of the target.
+func (run *runStatus) failTarget(target string, err error) {
+	if run.failedTargets == nil {
+		run.failedTargets = sets.New[string]()
+	}
+	run.failedTargets.Insert(target)
+	run.fail(err)
+}
+
+func (run *runStatus) logsResult(err error) {
+	if err != nil {
+		run.logSourceErr = err
+		run.fail(err)
+		return
+	}
+	run.logsFetched++
+}
+
+func (run *runStatus) llmResult(err error) {
+	if err != nil {
+		run.llmErr = err
+		run.fail(err)
+		return
+	}
+	run.llmOK = true
+}
+
+func (run *runStatus) knowledgeBaseResult(err error) {
+	if err != nil {
+		run.knowledgeBaseErr = err
+		run.fail(err)
+		return
+	}
+	run.knowledgeBaseOK = true
+}
+
+func (run *runStatus) analyzed(namespace, name, reason string) {
+	run.stats.PodsAnalyzed++
+	run.summarize(namespace+"/"+name, reason)
+}
+
+func (run *runStatus) analyzedNode(name, reason string) {
+	run.stats.NodesAnalyzed++
+	run.summarize("node/"+name, reason)
+}
+
+func (run *runStatus) analyzedRollout(kind, namespace, name, reason string) {
+	run.stats.RolloutsAnalyzed++
+	run.summarize(strings.ToLower(kind)+"/"+namespace+"/"+name, reason)
+}
+
+func (run *runStatus) summarize(subject, reason string) {
+	run.summary = truncate(fmt.Sprintf("%s: %s", subject, strings.Join(strings.Fields(reason), " ")), maxAnalysisSummaryLength)
+}
+
+// applyRunStatus records the outcome of a run in the status of the Kopilot.
+// Conditions of components that were not exercised in the run keep their value.
+func applyRunStatus(kopilot *kopilotv1.Kopilot, run *runStatus) {
+	status := &kopilot.Status
+	generation := kopilot.Generation
+
+	stats := run.stats
+	status.LastRun = &stats
+	if run.summary != "" {
+		status.LastAnalysisResult = run.summary
+	}
+	if run.lastErr != nil {
+		status.LastError = run.lastErr.Error()
+	} else {
+		status.LastError = ""
+	}
+
+	switch {
+	case run.logsFetched > 0:
+		setCondition(status, generation, kopilotv1.ConditionLogSourceReachable, nil, "LogsFetched", "Logs were fetched from the log source")
+	case run.logSourceErr != nil:
+		setCondition(status, generation, kopilotv1.ConditionLogSourceReachable, run.logSourceErr, "LogsUnavailable", "")
+	}
+
+	switch {
+	case run.llmErr != nil:
+		setCondition(status, generation, kopilotv1.ConditionLLMReachable, run.llmErr, "AnalysisFailed", "")
+	case run.llmOK:
+		setCondition(status, generation, kopilotv1.ConditionLLMReachable, nil, "AnalysisSucceeded", "The LLM analysis succeeded")
+	}
+
+	switch {
+	case kopilot.Spec.KnowledgeBase == nil:
+		setCondition(status, generation, kopilotv1.ConditionKnowledgeBaseReady, nil, "NotConfigured", "No knowledge base is configured")
+	case run.knowledgeBaseErr != nil:
+		setCondition(status, generation, kopilotv1.ConditionKnowledgeBaseReady, run.knowledgeBaseErr, "LoadFailed", "")
+	case run.knowledgeBaseOK:
+		setCondition(status, generation, kopilotv1.ConditionKnowledgeBaseReady, nil, "Loaded", "The knowledge base is loaded")
+	}
+
+	if len(status.Sinks) > 0 {
+		var unhealthy []string
+		for _, s := range status.Sinks {
+			if !s.Healthy {
+				unhealthy = append(unhealthy, s.Name)
+			}
+		}
+		if len(unhealthy) > 0 {
+			setCondition(status, generation, kopilotv1.ConditionSinksHealthy,
+				fmt.Errorf("unhealthy sinks: %s", strings.Join(unhealthy, ", ")), "DeliveryFailed", "")
+		} else {
+			setCondition(status, generation, kopilotv1.ConditionSinksHealthy, nil, "Delivered", "All sinks delivered the last notification")
+		}
+	}
+
+	setReadyCondition(status, generation)
+}
+
+// setReadyCondition derives the Ready condition from the other conditions.
+// The Ready reason is the reason of the first failing condition.
+func setReadyCondition(status *kopilotv1.KopilotStatus, generation int64) {
+	for _, conditionType := range []string{
+		kopilotv1.ConditionSecretsResolved,
+		kopilotv1.ConditionLogSourceReachable,
+		kopilotv1.ConditionLLMReachable,
+		kopilotv1.ConditionKnowledgeBaseReady,
+		kopilotv1.ConditionSinksHealthy,
+	} {
+		condition := meta.FindStatusCondition(status.Conditions, conditionType)
+		if condition == nil || condition.Status != metav1.ConditionFalse {
+			continue
+		}
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               kopilotv1.ConditionReady,
+			Status:             metav1.ConditionFalse,
+			Reason:             condition.Reason,
+			Message:            fmt.Sprintf("%s: %s", conditionType, condition.Message),
+			ObservedGeneration: generation,
+		})
+		return
+	}
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               kopilotv1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Ready",
+		Message:            "Kopilot is operational",
+		ObservedGeneration: generation,
+	})
+}
+
+func setCondition(status *kopilotv1.KopilotStatus, generation int64, conditionType string, err error, reason, message string) {
+	condition := metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: generation,
+	}
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Message = truncate(err.Error(), maxAnalysisSummaryLength)
+	}
+	meta.SetStatusCondition(&status.Conditions, condition)
+}
+
+// truncate shortens s to at most n bytes without splitting a rune.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	end := n
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end] + "…"
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/go-logr/logr"
+)
+
+var _ = Describe("Run status", func() {
+	It("records counters and derives Ready from the failing condition", func() {
+		kopilot := &kopilotv1.Kopilot{}
+		run := &runStatus{stats: kopilotv1.RunStatistics{PodsScanned: 10, PodsUnhealthy: 2}}
+		run.logsResult(nil)
+		run.llmResult(errors.New("429 Too Many Requests"))
+
+		applyRunStatus(kopilot, run)
+
+		Expect(kopilot.Status.LastRun).To(Equal(&kopilotv1.RunStatistics{PodsScanned: 10, PodsUnhealthy: 2}))
+		Expect(kopilot.Status.LastError).To(Equal("429 Too Many Requests"))
+		Expect(meta.IsStatusConditionTrue(kopilot.Status.Conditions, kopilotv1.ConditionLogSourceReachable)).To(BeTrue())
+		ready := meta.FindStatusCondition(kopilot.Status.Conditions, kopilotv1.ConditionReady)
+		Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+		Expect(ready.Reason).To(Equal("AnalysisFailed"))
+
+		run = &runStatus{}
+		run.llmResult(nil)
+		run.analyzed("default", "web-1", "镜像拉取失败")
+		applyRunStatus(kopilot, run)
+
+		Expect(kopilot.Status.LastError).To(BeEmpty())
+		Expect(kopilot.Status.LastAnalysisResult).To(Equal("default/web-1: 镜像拉取失败"))
+		Expect(meta.IsStatusConditionTrue(kopilot.Status.Conditions, kopilotv1.ConditionReady)).To(BeTrue())
+	})
+
+	It("keeps the pods with the error as logs if the log source cannot be created", func() {
+		kopilot := &kopilotv1.Kopilot{Spec: kopilotv1.KopilotSpec{LogSource: kopilotv1.LogSourceSpec{Type: "unknown"}}}
+		pods := []UnHealthyPod{
+			{Pod: corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default"}}},
+			{Pod: corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-2", Namespace: "default"}}},
+		}
+		run := &runStatus{}
+
+		pods = (&KopilotReconciler{}).fetchPodLogs(context.Background(), logr.Discard(), kopilot, pods, run)
+
+		Expect(pods).To(HaveLen(2))
+		for _, pod := range pods {
+			Expect(pod.Log).To(HavePrefix("Failed to retrieve logs: unknown log source type"))
+		}
+		Expect(run.logSourceErr).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,242 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	defaultTriggerDebounce    = 30 * time.Second
+	defaultTriggerMinInterval = 10 * time.Minute
+)
+
+// podTriggers keeps track of the pods that were reported unhealthy by the pod
+// informer and are waiting to be analyzed by a Kopilot in watch mode.
+type podTriggers struct {
+	mu sync.Mutex
+	// pending maps a Kopilot to the pods queued for analysis.
+	pending map[types.NamespacedName]map[types.NamespacedName]struct{}
+	// lastAnalyzed maps a Kopilot to the last analysis time of each pod.
+	lastAnalyzed map[types.NamespacedName]map[types.NamespacedName]time.Time
+}
+
+// add queues a pod for analysis by the given Kopilot and returns how long to
+// wait before reconciling it. It returns false if the pod is already queued.
+func (t *podTriggers) add(kopilot, pod types.NamespacedName, debounce, minInterval time.Duration, now time.Time) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pending == nil {
+		t.pending = map[types.NamespacedName]map[types.NamespacedName]struct{}{}
+	}
+	if t.pending[kopilot] == nil {
+		t.pending[kopilot] = map[types.NamespacedName]struct{}{}
+	}
+	if _, ok := t.pending[kopilot][pod]; ok {
+		return 0, false
+	}
+	t.pending[kopilot][pod] = struct{}{}
+
+	delay := debounce
+	if last, ok := t.lastAnalyzed[kopilot][pod]; ok {
+		if wait := last.Add(minInterval).Sub(now); wait > delay {
+			delay = wait
+		}
+	}
+	return delay, true
+}
+
+// pop returns the pods queued for the given Kopilot whose minimum interval has
+// elapsed and removes them from the queue. If other pods are still waiting for
+// their minimum interval, it also returns how long until the next one is due.
+func (t *podTriggers) pop(kopilot types.NamespacedName, minInterval time.Duration, now time.Time) ([]types.NamespacedName, time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var pods []types.NamespacedName
+	var next time.Duration
+	for pod := range t.pending[kopilot] {
+		if last, ok := t.lastAnalyzed[kopilot][pod]; ok {
+			if wait := last.Add(minInterval).Sub(now); wait > 0 {
+				if next == 0 || wait < next {
+					next = wait
+				}
+				continue
+			}
+		}
+		pods = append(pods, pod)
+		delete(t.pending[kopilot], pod)
+	}
+	return pods, next
+}
+
+// markAnalyzed records that the given pods have been analyzed by the Kopilot.
+func (t *podTriggers) markAnalyzed(kopilot types.NamespacedName, pods []types.NamespacedName, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.lastAnalyzed == nil {
+		t.lastAnalyzed = map[types.NamespacedName]map[types.NamespacedName]time.Time{}
+	}
+	if t.lastAnalyzed[kopilot] == nil {
+		t.lastAnalyzed[kopilot] = map[types.NamespacedName]time.Time{}
+	}
+	for _, pod := range pods {
+		t.lastAnalyzed[kopilot][pod] = now
+	}
+}
+
+// forgetPod drops the last analysis times of a deleted pod.
+func (t *podTriggers) forgetPod(pod types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for kopilot := range t.lastAnalyzed {
+		delete(t.lastAnalyzed[kopilot], pod)
+	}
+}
+
+// forget drops all state kept for the given Kopilot.
+func (t *podTriggers) forget(kopilot types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.pending, kopilot)
+	delete(t.lastAnalyzed, kopilot)
+}
+
+func isWatchMode(spec kopilotv1.KopilotSpec) bool {
+	return spec.Trigger != nil && spec.Trigger.Mode == "watch"
+}
+
+func triggerDebounce(spec kopilotv1.KopilotSpec) time.Duration {
+	if spec.Trigger != nil && spec.Trigger.Debounce != nil {
+		return spec.Trigger.Debounce.Duration
+	}
+	return defaultTriggerDebounce
+}
+
+func triggerMinInterval(spec kopilotv1.KopilotSpec) time.Duration {
+	if spec.Trigger != nil && spec.Trigger.MinInterval != nil {
+		return spec.Trigger.MinInterval.Duration
+	}
+	return defaultTriggerMinInterval
+}
+
+// podEventHandler returns an event handler that queues pods which turned
+// unhealthy for every Kopilot in watch mode whose scope contains them, and the
+// failed pods of Jobs for the capture of their logs. The last analysis times
+// of deleted pods are dropped.
+func (r *KopilotReconciler) podEventHandler() handler.EventHandler {
+	return handler.Funcs{
+		// A pod seen for the first time, e.g. after a restart of the
+		// operator, has no old state and is treated as healthy before.
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			pod, ok := e.Object.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			r.enqueueUnhealthyPod(ctx, nil, pod, q)
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			oldPod, ok := e.ObjectOld.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			newPod, ok := e.ObjectNew.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			r.enqueueUnhealthyPod(ctx, oldPod, newPod, q)
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			r.triggers.forgetPod(client.ObjectKeyFromObject(e.Object))
+		},
+	}
+}
+
+// enqueueUnhealthyPod queues the pod for the Kopilots by whose thresholds it
+// turned from healthy, or from no old state, to unhealthy. The pod of a Job is also queued for the
+// capture of its logs by every Kopilot, so that the Job can be analyzed with
+// them after its pods are gone.
+func (r *KopilotReconciler) enqueueUnhealthyPod(ctx context.Context, oldPod, pod *corev1.Pod, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	l := logf.FromContext(ctx)
+
+	var kopilots kopilotv1.KopilotList
+	if err := r.List(ctx, &kopilots); err != nil {
+		l.Error(err, "unable to list Kopilots")
+		return
+	}
+
+	now := time.Now()
+	podKey := client.ObjectKeyFromObject(pod)
+	ref := metav1.GetControllerOf(pod)
+	jobPod := ref != nil && ref.Kind == "Job"
+	for _, kopilot := range kopilots.Items {
+		if !hasTarget(kopilot.Spec, targetPods) || (!isWatchMode(kopilot.Spec) && !jobPod) {
+			continue
+		}
+		inScope, err := r.inPodScope(ctx, kopilot.Spec, pod)
+		if err != nil {
+			l.Error(err, "unable to resolve pod scope", "kopilot", kopilot.Name, "namespace", kopilot.Namespace)
+			continue
+		}
+		if !inScope {
+			continue
+		}
+		evaluator, err := r.triggerHealthEvaluator(&kopilot)
+		if err != nil {
+			l.Error(err, "unable to compile health rules", "kopilot", kopilot.Name, "namespace", kopilot.Namespace)
+			continue
+		}
+		// Most updates are of healthy pods, the old pod is only evaluated for
+		// the unhealthy ones.
+		if evaluator.Evaluate(pod, now) == nil || (oldPod != nil && evaluator.Evaluate(oldPod, now) != nil) {
+			continue
+		}
+
+		kopilotKey := client.ObjectKeyFromObject(&kopilot)
+		if jobPod && r.failedJobPods.add(kopilotKey, podKey) && !isWatchMode(kopilot.Spec) {
+			// In watch mode, the logs are captured when the queued analysis is reconciled.
+			l.Info("Job pod failed, capturing its logs", "pod", pod.Name, "namespace", pod.Namespace, "kopilot", kopilot.Name)
+			q.Add(reconcile.Request{NamespacedName: kopilotKey})
+		}
+		if !isWatchMode(kopilot.Spec) {
+			continue
+		}
+		delay, added := r.triggers.add(kopilotKey, podKey, triggerDebounce(kopilot.Spec), triggerMinInterval(kopilot.Spec), now)
+		if !added {
+			continue
+		}
+		l.Info("Pod became unhealthy, queueing analysis", "pod", pod.Name, "namespace", pod.Namespace, "kopilot", kopilot.Name, "after", delay)
+		q.AddAfter(reconcile.Request{NamespacedName: kopilotKey}, delay)
+	}
+}
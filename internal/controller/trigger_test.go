@@ -0,0 +1,92 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+)
+
+var _ = Describe("Pod triggers", func() {
+	It("should queue pods that are created unhealthy in a namespace matched by the namespace selector", func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(kopilotv1.AddToScheme(scheme)).To(Succeed())
+
+		kopilot := &kopilotv1.Kopilot{
+			ObjectMeta: metav1.ObjectMeta{Name: "kopilot", Namespace: "monitoring"},
+			Spec: kopilotv1.KopilotSpec{
+				Targets:           []string{targetPods},
+				Trigger:           &kopilotv1.TriggerSpec{Mode: "watch"},
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "web"}},
+			},
+		}
+		r := &KopilotReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			kopilot,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "web", Labels: map[string]string{"team": "web"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "batch"}},
+		).Build()}
+
+		q := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+		defer q.ShutDown()
+		handler := r.podEventHandler()
+		for _, namespace := range []string{"web", "batch"} {
+			pod := crashingPod("web-5d4f8-a").Pod
+			pod.Namespace = namespace
+			handler.Create(context.Background(), event.CreateEvent{Object: &pod}, q)
+		}
+
+		key := types.NamespacedName{Name: "kopilot", Namespace: "monitoring"}
+		pods, _ := r.triggers.pop(key, time.Minute, time.Now())
+		Expect(pods).To(ConsistOf(types.NamespacedName{Name: "web-5d4f8-a", Namespace: "web"}))
+	})
+
+	It("should drop the last analysis times of deleted pods", func() {
+		r := &KopilotReconciler{}
+		now := time.Now()
+		pod := crashingPod("web-5d4f8-a").Pod
+		podKey := types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}
+		other := types.NamespacedName{Name: "web-5d4f8-b", Namespace: pod.Namespace}
+		for _, kopilot := range []string{"a", "b"} {
+			r.triggers.markAnalyzed(types.NamespacedName{Name: kopilot, Namespace: "monitoring"}, []types.NamespacedName{podKey, other}, now)
+		}
+
+		q := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+		defer q.ShutDown()
+		r.podEventHandler().Delete(context.Background(), event.DeleteEvent{Object: &pod}, q)
+
+		Expect(r.triggers.lastAnalyzed).To(HaveLen(2))
+		for _, analyzed := range r.triggers.lastAnalyzed {
+			Expect(analyzed).To(HaveLen(1))
+			Expect(analyzed).To(HaveKey(other))
+		}
+	})
+})
@@ -1,95 +1,195 @@
 package utils
 
 import (
-	"bytes"
-	"context"
 	"fmt"
-	"io"
+	"slices"
 	"strings"
+	"time"
 
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
 	logsource "github.com/Fl0rencess720/Kopilot/pkg/logSource"
-	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
 )
 
-func CheckPodHealthyStatus(status corev1.PodStatus) bool {
-	if status.Phase != corev1.PodRunning && status.Phase != corev1.PodSucceeded {
-		return false
+// GetPodFailureReason returns the name of the failing container, if any,
+// and the reason why the pod is unhealthy.
+func GetPodFailureReason(status corev1.PodStatus) (string, string) {
+	for _, initStatus := range status.InitContainerStatuses {
+		if initStatus.State.Waiting != nil && initStatus.State.Waiting.Reason != "" &&
+			initStatus.State.Waiting.Reason != "PodInitializing" {
+			return initStatus.Name, initStatus.State.Waiting.Reason
+		}
+		if initStatus.State.Terminated != nil && initStatus.State.Terminated.ExitCode != 0 {
+			return initStatus.Name, terminatedReason(initStatus.State.Terminated)
+		}
 	}
 
-	if status.Phase == corev1.PodRunning {
-		for _, containerStatus := range status.ContainerStatuses {
-			if !containerStatus.Ready {
-				return false
-			}
-
-			if containerStatus.State.Waiting != nil {
-				reason := containerStatus.State.Waiting.Reason
-				if reason == "CrashLoopBackOff" || reason == "Error" ||
-					reason == "ImagePullBackOff" || reason == "ErrImagePull" {
-					return false
-				}
-			}
-			if containerStatus.State.Terminated != nil &&
-				containerStatus.State.Terminated.ExitCode != 0 {
-				return false
-			}
+	for _, containerStatus := range status.ContainerStatuses {
+		if containerStatus.State.Waiting != nil && containerStatus.State.Waiting.Reason != "" {
+			return containerStatus.Name, containerStatus.State.Waiting.Reason
 		}
-
-		for _, initStatus := range status.InitContainerStatuses {
-			if initStatus.State.Waiting != nil {
-				reason := initStatus.State.Waiting.Reason
-				if reason == "CrashLoopBackOff" || reason == "Error" ||
-					reason == "ImagePullBackOff" || reason == "ErrImagePull" {
-					return false
-				}
-			}
+		if containerStatus.State.Terminated != nil && containerStatus.State.Terminated.ExitCode != 0 {
+			return containerStatus.Name, terminatedReason(containerStatus.State.Terminated)
+		}
+		if containerStatus.State.Running != nil && !containerStatus.Ready {
+			return containerStatus.Name, "NotReady"
 		}
 	}
 
-	return true
+	if status.Reason != "" {
+		return "", status.Reason
+	}
+	return "", string(status.Phase)
 }
 
-func GetPodLogsFromKubernetes(clientset kubernetes.Interface, podName string, namespace string) (string, error) {
-	pod, err := clientset.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
-	if err != nil {
-		return "", fmt.Errorf("failed to get pod: %v", err)
+func terminatedReason(state *corev1.ContainerStateTerminated) string {
+	if state.Reason != "" {
+		return state.Reason
+	}
+	return "Error"
+}
+
+// GetPodOwner returns the kind and name of the workload that controls the pod.
+// Pods owned by a ReplicaSet are attributed to its Deployment, and pods without
+// a controller are their own owner.
+func GetPodOwner(pod corev1.Pod) (string, string) {
+	owner := metav1.GetControllerOf(&pod)
+	if owner == nil {
+		return "Pod", pod.Name
+	}
+	if owner.Kind == "ReplicaSet" {
+		if hash := pod.Labels["pod-template-hash"]; hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+			return "Deployment", strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+	}
+	return owner.Kind, owner.Name
+}
+
+const (
+	defaultLogTailLines int64 = 100
+	// maxLogTailLines is the maximum of the tailLines field of the CRD.
+	maxLogTailLines    int64 = 5000
+	defaultLogMaxBytes int64 = 64 * 1024
+	// logWindowGrace extends a time window past the termination of a container
+	// to include the last lines shipped late by the log collector.
+	logWindowGrace = 30 * time.Second
+)
+
+// NewLogOptions returns the log options of the log source for a pod. The time
+// window of the failing container ends at its last termination, if any.
+func NewLogOptions(spec kopilotv1.LogSourceSpec, pod corev1.Pod, now time.Time) logsource.Options {
+	opts := logsource.Options{TailLines: defaultLogTailLines, MaxBytes: defaultLogMaxBytes}
+	if spec.TailLines != nil && *spec.TailLines > 0 {
+		opts.TailLines = min(*spec.TailLines, maxLogTailLines)
+	}
+	if spec.MaxBytes != nil && *spec.MaxBytes > 0 {
+		opts.MaxBytes = *spec.MaxBytes
 	}
 
-	hasRunningContainer := false
-	for _, containerStatus := range pod.Status.ContainerStatuses {
-		if containerStatus.State.Running != nil {
-			hasRunningContainer = true
-			break
+	switch {
+	case spec.TimeWindow != nil && spec.TimeWindow.Duration > 0:
+		end := now
+		if finishedAt := GetPodLastTerminationTime(pod.Status); !finishedAt.IsZero() {
+			end = finishedAt
+			opts.Until = finishedAt.Add(logWindowGrace)
+			opts.Container, _ = GetPodFailureReason(pod.Status)
 		}
+		opts.Since = end.Add(-spec.TimeWindow.Duration)
+	case spec.SinceSeconds != nil && *spec.SinceSeconds > 0:
+		opts.Since = now.Add(-time.Duration(*spec.SinceSeconds) * time.Second)
 	}
+	return opts
+}
 
-	if !hasRunningContainer {
-		return generateStatusMessage(pod.Status), nil
+// GetPodLastTerminationTime returns when the failing container of the pod last
+// terminated, or the zero time if it has not terminated.
+func GetPodLastTerminationTime(status corev1.PodStatus) time.Time {
+	container, _ := GetPodFailureReason(status)
+	if container == "" {
+		return time.Time{}
+	}
+	for _, containerStatus := range slices.Concat(status.InitContainerStatuses, status.ContainerStatuses) {
+		if containerStatus.Name != container {
+			continue
+		}
+		if terminated := containerStatus.State.Terminated; terminated != nil {
+			return terminated.FinishedAt.Time
+		}
+		if terminated := containerStatus.LastTerminationState.Terminated; terminated != nil {
+			return terminated.FinishedAt.Time
+		}
 	}
+	return time.Time{}
+}
 
-	var tailLines int64 = 20
-	req := clientset.CoreV1().Pods(namespace).GetLogs(podName, &corev1.PodLogOptions{TailLines: &tailLines})
+// NewPodRef returns the reference of a pod for a log source.
+func NewPodRef(pod corev1.Pod) logsource.PodRef {
+	ref := logsource.PodRef{Namespace: pod.Namespace, Name: pod.Name}
+	for _, container := range slices.Concat(pod.Spec.InitContainers, pod.Spec.Containers) {
+		ref.Containers = append(ref.Containers, container.Name)
+	}
+	return ref
+}
 
-	podLogs, err := req.Stream(context.TODO())
-	if err != nil {
-		return fmt.Sprintf("Failed to retrieve logs: %v", err), nil
+// FormatPodLogs renders log lines for the LLM with a section per container
+// instance, labeled by the container name and restart count. Without any lines
+// the container states of the pod are described instead.
+func FormatPodLogs(pod corev1.Pod, lines []logsource.LogLine) string {
+	if len(lines) == 0 {
+		return generateStatusMessage(pod.Status)
 	}
-	defer func() {
-		if err := podLogs.Close(); err != nil {
-			zap.L().Error("Error closing podLogs", zap.Error(err))
+
+	kinds := map[string]string{}
+	for _, container := range pod.Spec.InitContainers {
+		kinds[container.Name] = "init container"
+		if container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			kinds[container.Name] = "sidecar container"
 		}
-	}()
+	}
+	restarts := map[string]int32{}
+	for _, containerStatus := range slices.Concat(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses) {
+		restarts[containerStatus.Name] = containerStatus.RestartCount
+	}
 
-	buf := new(bytes.Buffer)
-	_, err = io.Copy(buf, podLogs)
-	if err != nil {
-		return fmt.Sprintf("Failed to read logs: %v", err), nil
+	type section struct {
+		container string
+		previous  bool
+	}
+	var order []section
+	texts := map[section][]string{}
+	for _, line := range lines {
+		key := section{line.Container, line.Previous}
+		if _, ok := texts[key]; !ok {
+			order = append(order, key)
+		}
+		text := line.Text
+		switch {
+		case line.Template:
+			text = fmt.Sprintf("[%d similar lines] %s", line.Count, text)
+		case line.Count > 1:
+			text = fmt.Sprintf("[repeated %d times] %s", line.Count, text)
+		}
+		texts[key] = append(texts[key], text)
 	}
 
-	return buf.String(), nil
+	var b strings.Builder
+	for _, key := range order {
+		if key.container != "" {
+			kind := kinds[key.container]
+			if kind == "" {
+				kind = "container"
+			}
+			instance := ""
+			if key.previous {
+				instance = "previous instance, "
+			}
+			fmt.Fprintf(&b, "=== %s: %s (%srestarts: %d) ===\n", kind, key.container, instance, restarts[key.container])
+		}
+		b.WriteString(strings.Join(texts[key], "\n"))
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
 }
 
 func generateStatusMessage(status corev1.PodStatus) string {
@@ -112,17 +212,3 @@ func generateStatusMessage(status corev1.PodStatus) string {
 
 	return strings.Join(messages, "\n")
 }
-
-func GetPodLogsFromLoki(podName string, namespace string, address string) (string, error) {
-	loki := logsource.NewLokiClient(address)
-	logs, err := loki.FetchLogs(logsource.LokiQueryOptions{
-		PodName:   podName,
-		Namespace: namespace,
-		Limit:     20,
-	})
-	if err != nil {
-		zap.L().Error("Failed to fetch logs from Loki", zap.Error(err))
-		return "", err
-	}
-	return logs, nil
-}
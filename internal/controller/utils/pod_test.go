@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	logsource "github.com/Fl0rencess720/Kopilot/pkg/logSource"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func crashedPod(finishedAt time.Time) corev1.Pod {
+	return corev1.Pod{Status: corev1.PodStatus{
+		Phase: corev1.PodRunning,
+		ContainerStatuses: []corev1.ContainerStatus{{
+			Name:  "app",
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+				ExitCode: 1, FinishedAt: metav1.NewTime(finishedAt),
+			}},
+		}},
+	}}
+}
+
+func TestNewLogOptions(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	finishedAt := now.Add(-time.Hour)
+	int64Ptr := func(v int64) *int64 { return &v }
+
+	tests := []struct {
+		name string
+		spec kopilotv1.LogSourceSpec
+		pod  corev1.Pod
+		want logsource.Options
+	}{
+		{
+			name: "defaults",
+			want: logsource.Options{TailLines: defaultLogTailLines, MaxBytes: defaultLogMaxBytes},
+		},
+		{
+			name: "tail lines and max bytes",
+			spec: kopilotv1.LogSourceSpec{TailLines: int64Ptr(500), MaxBytes: int64Ptr(4096)},
+			want: logsource.Options{TailLines: 500, MaxBytes: 4096},
+		},
+		{
+			name: "tail lines clamped to the maximum",
+			spec: kopilotv1.LogSourceSpec{TailLines: int64Ptr(100000)},
+			want: logsource.Options{TailLines: maxLogTailLines, MaxBytes: defaultLogMaxBytes},
+		},
+		{
+			name: "since seconds",
+			spec: kopilotv1.LogSourceSpec{SinceSeconds: int64Ptr(600)},
+			want: logsource.Options{TailLines: defaultLogTailLines, MaxBytes: defaultLogMaxBytes, Since: now.Add(-10 * time.Minute)},
+		},
+		{
+			name: "time window ending at the last termination",
+			spec: kopilotv1.LogSourceSpec{TimeWindow: &metav1.Duration{Duration: 5 * time.Minute}, SinceSeconds: int64Ptr(600)},
+			pod:  crashedPod(finishedAt),
+			want: logsource.Options{
+				TailLines: defaultLogTailLines, MaxBytes: defaultLogMaxBytes,
+				Since: finishedAt.Add(-5 * time.Minute), Until: finishedAt.Add(logWindowGrace), Container: "app",
+			},
+		},
+		{
+			name: "time window ending now without a termination",
+			spec: kopilotv1.LogSourceSpec{TimeWindow: &metav1.Duration{Duration: 5 * time.Minute}},
+			want: logsource.Options{TailLines: defaultLogTailLines, MaxBytes: defaultLogMaxBytes, Since: now.Add(-5 * time.Minute)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewLogOptions(tt.spec, tt.pod, now); got != tt.want {
+				t.Errorf("NewLogOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetPodLastTerminationTime(t *testing.T) {
+	finishedAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		status corev1.PodStatus
+		want   time.Time
+	}{
+		{
+			name:   "last termination of a crash looping container",
+			status: crashedPod(finishedAt).Status,
+			want:   finishedAt,
+		},
+		{
+			name: "terminated init container",
+			status: corev1.PodStatus{
+				InitContainerStatuses: []corev1.ContainerStatus{{
+					Name: "init",
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+						ExitCode: 2, FinishedAt: metav1.NewTime(finishedAt),
+					}},
+				}},
+			},
+			want: finishedAt,
+		},
+		{
+			name: "waiting container that never terminated",
+			status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  "app",
+					State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+				}},
+			},
+		},
+		{
+			name:   "pod without a failing container",
+			status: corev1.PodStatus{Phase: corev1.PodPending, Reason: "Unschedulable"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetPodLastTerminationTime(tt.status); !got.Equal(tt.want) {
+				t.Errorf("GetPodLastTerminationTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,18 +2,101 @@ package utils
 
 import (
 	"context"
+	"fmt"
+	"sync"
 
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
 	"go.uber.org/zap"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 )
 
-func GetSecret(clientset kubernetes.Interface, key, namespace string, name string) (string, error) {
-	secret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+// SecretGetter reads the value referenced by a SecretKeyRef.
+type SecretGetter interface {
+	GetSecret(ctx context.Context, ref kopilotv1.SecretKeyRef) (string, error)
+}
+
+// SecretResolver reads Secrets and caches their data until they are invalidated,
+// e.g. by a watch on the Secret. The zero value is ready to use.
+type SecretResolver struct {
+	mu    sync.Mutex
+	cache map[types.NamespacedName]map[string][]byte
+	// generations counts the invalidations of every Secret, so that data read
+	// before an invalidation is not cached after it.
+	generations map[types.NamespacedName]uint64
+}
+
+// ForNamespace returns a SecretGetter that resolves references without a
+// namespace in the given namespace, i.e. the namespace of the owning Kopilot.
+func (r *SecretResolver) ForNamespace(clientset kubernetes.Interface, namespace string) SecretGetter {
+	return &namespacedSecretGetter{resolver: r, clientset: clientset, namespace: namespace}
+}
+
+// Invalidate drops the cached data of the given Secret.
+func (r *SecretResolver) Invalidate(key types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.cache, key)
+	if r.generations == nil {
+		r.generations = map[types.NamespacedName]uint64{}
+	}
+	r.generations[key]++
+}
+
+func (r *SecretResolver) get(ctx context.Context, clientset kubernetes.Interface, key types.NamespacedName) (map[string][]byte, error) {
+	r.mu.Lock()
+	data, ok := r.cache[key]
+	generation := r.generations[key]
+	r.mu.Unlock()
+	if ok {
+		return data, nil
+	}
+
+	secret, err := clientset.CoreV1().Secrets(key.Namespace).Get(ctx, key.Name, metav1.GetOptions{})
+	if err != nil {
+		zap.L().Error("get secret failed", zap.String("secret", key.String()), zap.Error(err))
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.generations[key] != generation {
+		// The Secret changed while it was read, the data may be stale.
+		return secret.Data, nil
+	}
+	if r.cache == nil {
+		r.cache = map[types.NamespacedName]map[string][]byte{}
+	}
+	r.cache[key] = secret.Data
+	return secret.Data, nil
+}
+
+type namespacedSecretGetter struct {
+	resolver  *SecretResolver
+	clientset kubernetes.Interface
+	namespace string
+}
+
+func (g *namespacedSecretGetter) GetSecret(ctx context.Context, ref kopilotv1.SecretKeyRef) (string, error) {
+	key := SecretKey(ref, g.namespace)
+	data, err := g.resolver.get(ctx, g.clientset, key)
 	if err != nil {
-		zap.L().Error("get secret failed", zap.Error(err))
 		return "", err
 	}
-	s := string(secret.Data[key])
-	return s, nil
+	value, ok := data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found in secret %s", ref.Key, key)
+	}
+	return string(value), nil
+}
+
+// SecretKey returns the Secret referenced by ref, defaulting to the given namespace.
+func SecretKey(ref kopilotv1.SecretKeyRef, defaultNamespace string) types.NamespacedName {
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	return types.NamespacedName{Namespace: namespace, Name: ref.Name}
 }
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"context"
+	"testing"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestSecretResolverInvalidateDuringGet(t *testing.T) {
+	key := types.NamespacedName{Namespace: "default", Name: "token"}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+		Data:       map[string][]byte{"token": []byte("old")},
+	}
+	clientset := fake.NewClientset(secret)
+	resolver := &SecretResolver{}
+
+	// The Secret is updated and invalidated while the first read is in flight.
+	invalidated := false
+	clientset.PrependReactor("get", "secrets", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if !invalidated {
+			invalidated = true
+			resolver.Invalidate(key)
+		}
+		return false, nil, nil
+	})
+
+	getter := resolver.ForNamespace(clientset, "default")
+	ref := kopilotv1.SecretKeyRef{Name: key.Name, Key: "token"}
+	value, err := getter.GetSecret(context.Background(), ref)
+	if err != nil || value != "old" {
+		t.Fatalf("GetSecret() = %q, %v, want %q", value, err, "old")
+	}
+
+	secret.Data["token"] = []byte("new")
+	if _, err := clientset.CoreV1().Secrets(key.Namespace).Update(context.Background(), secret, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	value, err = getter.GetSecret(context.Background(), ref)
+	if err != nil || value != "new" {
+		t.Errorf("GetSecret() after invalidation = %q, %v, want %q", value, err, "new")
+	}
+}
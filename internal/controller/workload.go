@@ -0,0 +1,203 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Fl0rencess720/Kopilot/internal/controller/utils"
+	"github.com/Fl0rencess720/Kopilot/pkg/llm"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// maxRepresentatives bounds the pods of a workload whose logs are analyzed.
+	maxRepresentatives = 3
+	// maxListedPods bounds the pods listed in the workload statistics.
+	maxListedPods = 20
+)
+
+// workload is the unit of analysis: the unhealthy pods of a controller owner,
+// e.g. all crashing replicas of a Deployment.
+type workload struct {
+	namespace string
+	ownerKind string
+	ownerName string
+	// pods are all unhealthy pods of the workload.
+	pods []UnHealthyPod
+	// representatives are the pods to analyze, one per incident that is due.
+	representatives []UnHealthyPod
+}
+
+// resolveOwners sets the top-level controller owner and the revision of the
+// unhealthy pods, e.g. the Deployment of a ReplicaSet or the CronJob of a Job.
+// Pods whose owners cannot be fetched are attributed by their owner reference.
+func (r *KopilotReconciler) resolveOwners(ctx context.Context, l logr.Logger, unhealthyPods []UnHealthyPod) []UnHealthyPod {
+	// The replicas of a workload share their owners.
+	chains := map[types.UID][]owner{}
+	for i := range unhealthyPods {
+		pod := &unhealthyPods[i]
+		pod.OwnerKind, pod.OwnerName = utils.GetPodOwner(pod.Pod)
+		pod.Revision = pod.Pod.Labels[appsv1.ControllerRevisionHashLabelKey]
+
+		ref := metav1.GetControllerOf(&pod.Pod)
+		if ref == nil {
+			continue
+		}
+		chain, ok := chains[ref.UID]
+		if !ok {
+			var err error
+			chain, err = r.ownerChain(ctx, &pod.Pod)
+			if err != nil {
+				l.Error(err, "unable to get the owners of pod", "pod", pod.Pod.Name, "namespace", pod.Pod.Namespace)
+			}
+			chains[ref.UID] = chain
+		}
+		if len(chain) == 0 {
+			continue
+		}
+		top := chain[len(chain)-1]
+		pod.OwnerKind, pod.OwnerName = top.kind, top.object.GetName()
+		if chain[0].kind == "ReplicaSet" {
+			pod.Revision = chain[0].object.GetAnnotations()[revisionAnnotation]
+		}
+	}
+	return unhealthyPods
+}
+
+// groupByWorkload groups the pods that are due for analysis by workload. The
+// workloads list all their unhealthy pods, and at most maxRepresentatives of
+// the due pods are analyzed per workload.
+func groupByWorkload(unhealthyPods, due []UnHealthyPod) []*workload {
+	key := func(pod UnHealthyPod) string {
+		return pod.Pod.Namespace + "/" + pod.OwnerKind + "/" + pod.OwnerName
+	}
+
+	var workloads []*workload
+	index := map[string]*workload{}
+	for _, pod := range due {
+		w, ok := index[key(pod)]
+		if !ok {
+			w = &workload{namespace: pod.Pod.Namespace, ownerKind: pod.OwnerKind, ownerName: pod.OwnerName}
+			index[key(pod)] = w
+			workloads = append(workloads, w)
+		}
+		if len(w.representatives) < maxRepresentatives {
+			w.representatives = append(w.representatives, pod)
+		}
+	}
+	for _, pod := range unhealthyPods {
+		if w, ok := index[key(pod)]; ok {
+			w.pods = append(w.pods, pod)
+		}
+	}
+	return workloads
+}
+
+// analysisPod returns the pod to analyze for the workload: the first
+// representative, with the logs of all representatives and the statistics of
+// the workload as context.
+func (w *workload) analysisPod() UnHealthyPod {
+	pod := w.representatives[0]
+	if len(w.representatives) > 1 {
+		var b strings.Builder
+		for _, representative := range w.representatives {
+			fmt.Fprintf(&b, "##### pod %s (%s) #####\n%s\n", representative.Pod.Name, podClassification(representative).Reason, representative.Log)
+		}
+		pod.Log = strings.TrimRight(b.String(), "\n")
+	}
+	pod.Context = append([]llm.ContextSection{{
+		Title:   fmt.Sprintf("Unhealthy pods of %s %s", w.ownerKind, w.ownerName),
+		Content: w.statistics(),
+	}}, pod.Context...)
+	return pod
+}
+
+// podNames returns the names of all unhealthy pods of the workload.
+func (w *workload) podNames() []string {
+	names := make([]string, 0, len(w.pods))
+	for _, pod := range w.pods {
+		if pod.Pod.Name != "" {
+			names = append(names, pod.Pod.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// statistics describes how many pods are affected, why, on which nodes and
+// in which revisions.
+func (w *workload) statistics() string {
+	reasons, nodes, revisions := map[string]int{}, map[string]int{}, map[string]int{}
+	for _, pod := range w.pods {
+		reasons[podClassification(pod).Reason]++
+		if pod.Pod.Spec.NodeName != "" {
+			nodes[pod.Pod.Spec.NodeName]++
+		}
+		if pod.Revision != "" {
+			revisions[pod.Revision]++
+		}
+	}
+
+	var b strings.Builder
+	names := w.podNames()
+	fmt.Fprintf(&b, "unhealthy pods: %d", len(names))
+	if len(names) > maxListedPods {
+		fmt.Fprintf(&b, " (%s, ...)\n", strings.Join(names[:maxListedPods], ", "))
+	} else {
+		fmt.Fprintf(&b, " (%s)\n", strings.Join(names, ", "))
+	}
+	fmt.Fprintf(&b, "reasons: %s\n", formatCounts(reasons))
+	if len(nodes) > 0 {
+		fmt.Fprintf(&b, "nodes: %s\n", formatCounts(nodes))
+	}
+	if len(revisions) > 0 {
+		fmt.Fprintf(&b, "revisions: %s\n", formatCounts(revisions))
+	}
+	var analyzed []string
+	for _, pod := range w.representatives {
+		analyzed = append(analyzed, fmt.Sprintf("%s (%s)", pod.Pod.Name, podClassification(pod).Reason))
+	}
+	fmt.Fprintf(&b, "analyzed pods: %s", strings.Join(analyzed, ", "))
+	return b.String()
+}
+
+// formatCounts renders counts as "a: 3, b: 1", the largest first.
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+": "+strconv.Itoa(counts[key]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/go-logr/logr"
+)
+
+var _ = Describe("Workload grouping", func() {
+	It("should analyze the replicas of a workload once and list all of them", func() {
+		r := &KopilotReconciler{Clientset: fake.NewClientset()}
+		kopilot := types.NamespacedName{Name: "kopilot", Namespace: "default"}
+
+		var pods []UnHealthyPod
+		for _, name := range []string{"web-5d4f8-a", "web-5d4f8-b", "web-5d4f8-c"} {
+			pod := crashingPod(name)
+			pod.Pod.Spec.NodeName = "node-1"
+			pods = append(pods, pod)
+		}
+		// A failure of another container is another incident of the workload.
+		pods[2].Pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name: "sidecar",
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137},
+			},
+		}}
+		pods = r.resolveOwners(context.Background(), logr.Discard(), pods)
+		Expect(pods[0].OwnerKind).To(Equal("Deployment"))
+		Expect(pods[0].OwnerName).To(Equal("web"))
+
+		due := (&incidentTracker{}).observe(kopilot, pods, time.Hour, time.Now())
+		Expect(due).To(HaveLen(2))
+
+		workloads := groupByWorkload(pods, due)
+		Expect(workloads).To(HaveLen(1))
+		Expect(workloads[0].representatives).To(HaveLen(2))
+		Expect(workloads[0].podNames()).To(Equal([]string{"web-5d4f8-a", "web-5d4f8-b", "web-5d4f8-c"}))
+
+		pod := workloads[0].analysisPod()
+		Expect(pod.Log).To(ContainSubstring("##### pod web-5d4f8-c (OOMKilled) #####"))
+		Expect(pod.Context[0].Content).To(ContainSubstring("unhealthy pods: 3"))
+		Expect(pod.Context[0].Content).To(ContainSubstring("reasons: CrashLoopBackOff: 2, OOMKilled: 1"))
+		Expect(pod.Context[0].Content).To(ContainSubstring("nodes: node-1: 3"))
+	})
+})
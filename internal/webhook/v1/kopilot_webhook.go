@@ -0,0 +1,143 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/Fl0rencess720/Kopilot/pkg/health"
+	logsource "github.com/Fl0rencess720/Kopilot/pkg/logSource"
+)
+
+// log is for logging in this package.
+var kopilotlog = logf.Log.WithName("kopilot-resource")
+
+// SetupKopilotWebhookWithManager registers the webhook for Kopilot in the manager.
+func SetupKopilotWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&kopilotv1.Kopilot{}).
+		WithValidator(&KopilotCustomValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-kopilot-fl0rencess720-v1-kopilot,mutating=false,failurePolicy=fail,sideEffects=None,groups=kopilot.fl0rencess720,resources=kopilots,verbs=create;update,versions=v1,name=vkopilot-v1.kb.io,admissionReviewVersions=v1
+
+// KopilotCustomValidator struct is responsible for validating the Kopilot resource
+// when it is created or updated.
+//
+// NOTE: The +kubebuilder:object:generate=false marker prevents controller-gen from generating DeepCopy methods,
+// as this struct is used only for temporary operations and does not need to be deeply copied.
+// +kubebuilder:object:generate=false
+type KopilotCustomValidator struct{}
+
+var _ webhook.CustomValidator = &KopilotCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type Kopilot.
+func (v *KopilotCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	kopilot, ok := obj.(*kopilotv1.Kopilot)
+	if !ok {
+		return nil, fmt.Errorf("expected a Kopilot object but got %T", obj)
+	}
+	kopilotlog.Info("Validation for Kopilot upon creation", "name", kopilot.GetName())
+
+	return validateKopilot(kopilot)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type Kopilot.
+func (v *KopilotCustomValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	kopilot, ok := newObj.(*kopilotv1.Kopilot)
+	if !ok {
+		return nil, fmt.Errorf("expected a Kopilot object for the newObj but got %T", newObj)
+	}
+	kopilotlog.Info("Validation for Kopilot upon update", "name", kopilot.GetName())
+
+	return validateKopilot(kopilot)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type Kopilot.
+func (v *KopilotCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func validateKopilot(kopilot *kopilotv1.Kopilot) (admission.Warnings, error) {
+	var warnings admission.Warnings
+	var allErrs field.ErrorList
+
+	specPath := field.NewPath("spec")
+
+	w, errs := validateLogSource(kopilot.Spec.LogSource, specPath.Child("logSource"))
+	warnings = append(warnings, w...)
+	allErrs = append(allErrs, errs...)
+
+	if redaction := kopilot.Spec.Redaction; redaction != nil {
+		for i, rule := range redaction.Rules {
+			if _, err := regexp.Compile(rule.Pattern); err != nil {
+				allErrs = append(allErrs, field.Invalid(specPath.Child("redaction", "rules").Index(i).Child("pattern"), rule.Pattern, err.Error()))
+			}
+		}
+	}
+
+	if detection := kopilot.Spec.Detection; detection != nil {
+		for i, rule := range detection.Rules {
+			if _, err := health.CompileRule(rule); err != nil {
+				allErrs = append(allErrs, field.Invalid(specPath.Child("detection", "rules").Index(i).Child("expression"), rule.Expression, err.Error()))
+			}
+		}
+	}
+
+	if len(allErrs) == 0 {
+		return warnings, nil
+	}
+	return warnings, apierrors.NewInvalid(
+		schema.GroupKind{Group: kopilotv1.GroupVersion.Group, Kind: "Kopilot"},
+		kopilot.Name, allErrs)
+}
+
+func validateLogSource(logSource kopilotv1.LogSourceSpec, fldPath *field.Path) (admission.Warnings, field.ErrorList) {
+	var warnings admission.Warnings
+	var allErrs field.ErrorList
+
+	if logSource.Loki != nil && logSource.Loki.LogQLQuery != "" {
+		queryPath := fldPath.Child("loki", "logqlQuery")
+		w, err := logsource.ValidateLogQLQuery(logSource.Loki.LogQLQuery)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(queryPath, logSource.Loki.LogQLQuery, err.Error()))
+		}
+		for _, msg := range w {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", queryPath, msg))
+		}
+	}
+
+	if p := logSource.Preprocessing; p != nil && p.ErrorPattern != "" {
+		if _, err := regexp.Compile(p.ErrorPattern); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("preprocessing", "errorPattern"), p.ErrorPattern, err.Error()))
+		}
+	}
+
+	return warnings, allErrs
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+)
+
+var _ = Describe("Kopilot Webhook", func() {
+	var (
+		obj       *kopilotv1.Kopilot
+		validator KopilotCustomValidator
+	)
+
+	BeforeEach(func() {
+		obj = &kopilotv1.Kopilot{
+			Spec: kopilotv1.KopilotSpec{
+				LogSource: kopilotv1.LogSourceSpec{
+					Type: "Loki",
+					Loki: &kopilotv1.LokiSource{Address: "http://loki:3100"},
+				},
+			},
+		}
+		validator = KopilotCustomValidator{}
+	})
+
+	Context("When creating or updating Kopilot under Validating Webhook", func() {
+		It("Should admit an empty LogQL query", func() {
+			Expect(validator.ValidateCreate(context.Background(), obj)).To(BeNil())
+		})
+
+		It("Should admit a templated LogQL query", func() {
+			obj.Spec.LogSource.Loki.LogQLQuery = `{k8s_namespace_name="{namespace}", k8s_pod_name=~"{pod}.*"} |= "error"`
+			Expect(validator.ValidateCreate(context.Background(), obj)).To(BeNil())
+		})
+
+		It("Should warn when the LogQL query does not select the pod", func() {
+			obj.Spec.LogSource.Loki.LogQLQuery = `{app="web"}`
+			warnings, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(HaveLen(1))
+		})
+
+		It("Should deny invalid LogQL queries", func() {
+			for _, query := range []string{
+				`namespace="{namespace}"`,
+				`{pod={pod}}`,
+				`{pod="{pod}"`,
+				`{pod="{name}"}`,
+				`{pod=~"{pod}("}`,
+				`{pod="{pod}"} |= "error`,
+			} {
+				obj.Spec.LogSource.Loki.LogQLQuery = query
+				_, err := validator.ValidateUpdate(context.Background(), obj, obj)
+				Expect(err).To(HaveOccurred(), query)
+			}
+		})
+
+		It("Should compile the health rules", func() {
+			obj.Spec.Detection = &kopilotv1.DetectionSpec{Rules: []kopilotv1.HealthRule{{
+				Name:       "TooManyRestarts",
+				Expression: `pod.status.containerStatuses.exists(c, c.restartCount > 5)`,
+			}, {
+				Name:       "Ignored",
+				Expression: `has(pod.metadata.annotations) && 'kopilot.io/ignore' in pod.metadata.annotations`,
+				Action:     "ignore",
+			}}}
+			Expect(validator.ValidateCreate(context.Background(), obj)).To(BeNil())
+
+			for _, expression := range []string{
+				`pod.status.containerStatuses.exists(c, c.restartCount > `,
+				`containerStatuses.size()`,
+				`unknown.field == 1`,
+			} {
+				obj.Spec.Detection.Rules[0].Expression = expression
+				_, err := validator.ValidateCreate(context.Background(), obj)
+				Expect(err).To(HaveOccurred(), expression)
+			}
+		})
+	})
+})
@@ -0,0 +1,165 @@
+package health
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/google/cel-go/cel"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// celCostLimit bounds the cost of evaluating a rule against a single pod.
+const celCostLimit = 1_000_000
+
+var celEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("pod", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("containerStatuses", cel.ListType(cel.DynType)),
+		cel.Variable("events", cel.ListType(cel.DynType)),
+		cel.Variable("now", cel.TimestampType),
+	)
+})
+
+// CELRule is a compiled user-defined health rule.
+type CELRule struct {
+	Name       string
+	Severity   Severity
+	PromptHint string
+	// Ignore marks the matched pods as healthy.
+	Ignore bool
+
+	program    cel.Program
+	usesEvents bool
+}
+
+// CompileRule compiles the CEL expression of a health rule.
+func CompileRule(rule kopilotv1.HealthRule) (*CELRule, error) {
+	env, err := celEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+	ast, issues := env.Compile(rule.Expression)
+	if issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression of rule %s: %w", rule.Name, issues.Err())
+	}
+	if t := ast.OutputType(); !t.IsExactType(cel.BoolType) && !t.IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("expression of rule %s must evaluate to a bool, not %s", rule.Name, t)
+	}
+	program, err := env.Program(ast, cel.CostLimit(celCostLimit))
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression of rule %s: %w", rule.Name, err)
+	}
+
+	compiled := &CELRule{
+		Name:       rule.Name,
+		Severity:   SeverityWarning,
+		PromptHint: rule.PromptHint,
+		Ignore:     rule.Action == "ignore",
+		program:    program,
+	}
+	if rule.Severity != "" {
+		compiled.Severity = Severity(rule.Severity)
+	}
+	for _, ref := range ast.NativeRep().ReferenceMap() {
+		if ref.Name == "events" {
+			compiled.usesEvents = true
+		}
+	}
+	return compiled, nil
+}
+
+// EventsFunc returns the events of a pod.
+type EventsFunc func(pod *corev1.Pod) ([]corev1.Event, error)
+
+// WithRules returns an evaluator that evaluates the given rules before the
+// built-in rules of e. If replace is set, the built-in rules are not evaluated.
+// events is only called for rules that use events; they are skipped if events
+// is nil.
+func (e *Evaluator) WithRules(rules []*CELRule, replace bool, events EventsFunc) *Evaluator {
+	evaluator := *e
+	evaluator.custom = rules
+	evaluator.replace = replace
+	evaluator.events = events
+	return &evaluator
+}
+
+// evaluateCustom evaluates the user-defined rules. It returns whether a rule
+// matched, and the classification if the pod is unhealthy.
+func (e *Evaluator) evaluateCustom(pod *corev1.Pod, now time.Time) (*Classification, bool) {
+	activation, err := e.activation(pod, now)
+	if err != nil {
+		zap.L().Error("unable to evaluate health rules", zap.String("pod", pod.Name), zap.String("namespace", pod.Namespace), zap.Error(err))
+		return nil, false
+	}
+
+	for _, rule := range e.custom {
+		if rule.usesEvents {
+			if e.events == nil {
+				continue
+			}
+			if activation["events"] == nil {
+				activation["events"] = e.podEvents(pod)
+			}
+		}
+		out, _, err := rule.program.Eval(activation)
+		if err != nil {
+			// e.g. a field that is not set, rules should guard them with has().
+			zap.L().Debug("unable to evaluate health rule", zap.String("rule", rule.Name), zap.String("pod", pod.Name), zap.Error(err))
+			continue
+		}
+		if matched, ok := out.Value().(bool); !ok || !matched {
+			continue
+		}
+		if rule.Ignore {
+			return nil, true
+		}
+		return &Classification{
+			Reason:     rule.Name,
+			Severity:   rule.Severity,
+			Message:    fmt.Sprintf("matched health rule %s", rule.Name),
+			PromptHint: rule.PromptHint,
+		}, true
+	}
+	return nil, false
+}
+
+func (e *Evaluator) activation(pod *corev1.Pod, now time.Time) (map[string]any, error) {
+	podObject, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []any
+	for _, status := range containerStatuses(pod) {
+		object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, object)
+	}
+	return map[string]any{
+		"pod":               podObject,
+		"containerStatuses": statuses,
+		"now":               now,
+	}, nil
+}
+
+func (e *Evaluator) podEvents(pod *corev1.Pod) []any {
+	events := []any{}
+	list, err := e.events(pod)
+	if err != nil {
+		zap.L().Error("unable to list pod events for health rules", zap.String("pod", pod.Name), zap.String("namespace", pod.Namespace), zap.Error(err))
+		return events
+	}
+	for i := range list {
+		object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&list[i])
+		if err != nil {
+			continue
+		}
+		events = append(events, object)
+	}
+	return events
+}
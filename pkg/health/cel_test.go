@@ -0,0 +1,85 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func compileRules(t *testing.T, rules ...kopilotv1.HealthRule) []*CELRule {
+	t.Helper()
+	var compiled []*CELRule
+	for _, rule := range rules {
+		c, err := CompileRule(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled
+}
+
+func TestCustomRules(t *testing.T) {
+	now := time.Now()
+	rules := compileRules(t, kopilotv1.HealthRule{
+		Name:       "Ignored",
+		Expression: `has(pod.metadata.annotations) && 'kopilot.io/ignore' in pod.metadata.annotations`,
+		Action:     "ignore",
+	}, kopilotv1.HealthRule{
+		Name:       "BackOff",
+		Expression: `containerStatuses.exists(c, has(c.state.waiting) && c.state.waiting.reason.endsWith('BackOff'))`,
+		Severity:   "critical",
+		PromptHint: "check the entrypoint",
+	})
+	ignored := crashingPod("ignored")
+	ignored.Annotations = map[string]string{"kopilot.io/ignore": "true"}
+	crashing := crashingPod("crashing")
+
+	evaluator := NewEvaluator(Thresholds{}).WithRules(rules, false, nil)
+	if c := evaluator.Evaluate(&ignored, now); c != nil {
+		t.Errorf("Evaluate(ignored) = %v, want healthy", c)
+	}
+	c := evaluator.Evaluate(&crashing, now)
+	if c == nil || c.Reason != "BackOff" || c.Severity != SeverityCritical || c.PromptHint != "check the entrypoint" {
+		t.Errorf("Evaluate(crashing) = %+v, want the BackOff rule", c)
+	}
+
+	// Pods that no rule matches fall back to the built-in rules, unless the
+	// rules replace them.
+	rules = compileRules(t, kopilotv1.HealthRule{Name: "Never", Expression: `false`})
+	if c := NewEvaluator(Thresholds{}).WithRules(rules, false, nil).Evaluate(&crashing, now); c == nil || c.Reason != "CrashLoopBackOff" {
+		t.Errorf("Evaluate() = %v, want the built-in classification", c)
+	}
+	if c := NewEvaluator(Thresholds{}).WithRules(rules, true, nil).Evaluate(&crashing, now); c != nil {
+		t.Errorf("Evaluate() = %v, want healthy with the built-in rules replaced", c)
+	}
+}
+
+func TestCustomRulesWithEvents(t *testing.T) {
+	now := time.Now()
+	rules := compileRules(t, kopilotv1.HealthRule{
+		Name:       "MountFailed",
+		Expression: `events.exists(e, e.reason == 'FailedMount')`,
+	})
+	pod := corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}}
+	pod.UID = "pod-uid"
+
+	calls := 0
+	events := func(p *corev1.Pod) ([]corev1.Event, error) {
+		calls++
+		return []corev1.Event{{InvolvedObject: corev1.ObjectReference{UID: p.UID}, Reason: "FailedMount"}}, nil
+	}
+	if c := NewEvaluator(Thresholds{}).WithRules(rules, true, events).Evaluate(&pod, now); c == nil || c.Reason != "MountFailed" {
+		t.Errorf("Evaluate() = %v, want the MountFailed rule", c)
+	}
+	if calls != 1 {
+		t.Errorf("events called %d times, want 1", calls)
+	}
+
+	// Rules that use events are skipped without an events function.
+	if c := NewEvaluator(Thresholds{}).WithRules(rules, true, nil).Evaluate(&pod, now); c != nil {
+		t.Errorf("Evaluate() = %v, want the rule skipped", c)
+	}
+}
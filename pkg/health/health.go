@@ -0,0 +1,347 @@
+package health
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Severity is the severity of a failure.
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityWarning  Severity = "warning"
+)
+
+// Classification describes why a pod is unhealthy.
+type Classification struct {
+	// Reason is a short CamelCase reason, e.g. OOMKilled or RestartStorm.
+	Reason string
+	// Container is the failing container, if the failure is specific to one.
+	Container string
+	Severity  Severity
+	// Message explains the classification.
+	Message string
+	// PromptHint is a hint for the analysis from the matched health rule.
+	PromptHint string
+}
+
+func (c Classification) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "reason=%s severity=%s", c.Reason, c.Severity)
+	if c.Container != "" {
+		fmt.Fprintf(&b, " container=%s", c.Container)
+	}
+	if c.Message != "" {
+		fmt.Fprintf(&b, ": %s", c.Message)
+	}
+	return b.String()
+}
+
+// Thresholds configure when a pod is considered unhealthy.
+type Thresholds struct {
+	// RestartCount is the number of restarts of a container, the last one
+	// within RecentWindow, from which it is unhealthy.
+	RestartCount int32
+	// RestartsPerHour is the restart rate since the pod started from which a
+	// container is unhealthy.
+	RestartsPerHour float64
+	// PendingTimeout is how long a pod may stay Pending.
+	PendingTimeout time.Duration
+	// NotReadyTimeout is how long a running container may stay not ready.
+	NotReadyTimeout time.Duration
+	// RecentWindow bounds how long ago a termination, e.g. OOMKilled, counts.
+	RecentWindow time.Duration
+}
+
+// DefaultThresholds are used for thresholds that are not set.
+var DefaultThresholds = Thresholds{
+	RestartCount:    5,
+	RestartsPerHour: 6,
+	PendingTimeout:  10 * time.Minute,
+	NotReadyTimeout: 5 * time.Minute,
+	RecentWindow:    time.Hour,
+}
+
+// Rule classifies a pod, or returns nil if it does not apply.
+type Rule func(pod *corev1.Pod, now time.Time) *Classification
+
+// Evaluator evaluates the health of pods with a list of rules. The first rule
+// that applies classifies the pod.
+type Evaluator struct {
+	rules []Rule
+
+	// custom are the user-defined rules evaluated before the built-in rules.
+	custom  []*CELRule
+	replace bool
+	events  EventsFunc
+}
+
+// NewEvaluator returns an evaluator with the built-in rules.
+func NewEvaluator(thresholds Thresholds) *Evaluator {
+	t := thresholds
+	if t.RestartCount <= 0 {
+		t.RestartCount = DefaultThresholds.RestartCount
+	}
+	if t.RestartsPerHour <= 0 {
+		t.RestartsPerHour = DefaultThresholds.RestartsPerHour
+	}
+	if t.PendingTimeout <= 0 {
+		t.PendingTimeout = DefaultThresholds.PendingTimeout
+	}
+	if t.NotReadyTimeout <= 0 {
+		t.NotReadyTimeout = DefaultThresholds.NotReadyTimeout
+	}
+	if t.RecentWindow <= 0 {
+		t.RecentWindow = DefaultThresholds.RecentWindow
+	}
+
+	return &Evaluator{rules: []Rule{
+		failedPod,
+		waitingContainer,
+		recentOOMKill(t.RecentWindow),
+		failedContainer,
+		restartStorm(t.RestartCount, t.RestartsPerHour, t.RecentWindow),
+		stuckPending(t.PendingTimeout),
+		notReady(t.NotReadyTimeout),
+	}}
+}
+
+// Evaluate returns the classification of an unhealthy pod, or nil if the pod is healthy.
+func (e *Evaluator) Evaluate(pod *corev1.Pod, now time.Time) *Classification {
+	if pod.Status.Phase == corev1.PodSucceeded {
+		return nil
+	}
+	if len(e.custom) > 0 {
+		if c, matched := e.evaluateCustom(pod, now); matched {
+			return c
+		}
+	}
+	if e.replace {
+		return nil
+	}
+	for _, rule := range e.rules {
+		if c := rule(pod, now); c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// criticalWaitingReasons are waiting reasons that do not resolve without intervention.
+var criticalWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+	"ErrImageNeverPull":          true,
+}
+
+func failedPod(pod *corev1.Pod, _ time.Time) *Classification {
+	if pod.Status.Phase != corev1.PodFailed {
+		return nil
+	}
+	c := &Classification{Reason: "Failed", Severity: SeverityCritical, Message: pod.Status.Message}
+	if pod.Status.Reason != "" {
+		c.Reason = pod.Status.Reason
+	}
+	if c.Reason == "Evicted" {
+		c.Severity = SeverityWarning
+	}
+	if container, state := terminatedContainer(pod); state != nil {
+		c.Container = container
+		if c.Message == "" {
+			c.Message = terminationMessage(state)
+		}
+	}
+	return c
+}
+
+func waitingContainer(pod *corev1.Pod, _ time.Time) *Classification {
+	for _, status := range containerStatuses(pod) {
+		waiting := status.State.Waiting
+		if waiting == nil || !criticalWaitingReasons[waiting.Reason] {
+			continue
+		}
+		c := &Classification{Reason: waiting.Reason, Container: status.Name, Severity: SeverityCritical, Message: waiting.Message}
+		// A crash loop caused by the memory limit is classified as such.
+		if last := status.LastTerminationState.Terminated; waiting.Reason == "CrashLoopBackOff" && last != nil {
+			if last.Reason == "OOMKilled" {
+				c.Reason = "OOMKilled"
+			}
+			c.Message = fmt.Sprintf("back-off restarting after %d restarts, last termination: %s", status.RestartCount, terminationMessage(last))
+		}
+		return c
+	}
+	return nil
+}
+
+func recentOOMKill(window time.Duration) Rule {
+	return func(pod *corev1.Pod, now time.Time) *Classification {
+		for _, status := range containerStatuses(pod) {
+			for _, terminated := range []*corev1.ContainerStateTerminated{status.State.Terminated, status.LastTerminationState.Terminated} {
+				if terminated == nil || terminated.Reason != "OOMKilled" || now.Sub(terminated.FinishedAt.Time) > window {
+					continue
+				}
+				return &Classification{
+					Reason:    "OOMKilled",
+					Container: status.Name,
+					Severity:  SeverityCritical,
+					Message:   fmt.Sprintf("killed for exceeding its memory limit %s ago, %d restarts", now.Sub(terminated.FinishedAt.Time).Round(time.Second), status.RestartCount),
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func failedContainer(pod *corev1.Pod, _ time.Time) *Classification {
+	for _, status := range containerStatuses(pod) {
+		terminated := status.State.Terminated
+		if terminated == nil || terminated.ExitCode == 0 {
+			continue
+		}
+		reason := terminated.Reason
+		if reason == "" {
+			reason = "Error"
+		}
+		return &Classification{Reason: reason, Container: status.Name, Severity: SeverityCritical, Message: terminationMessage(terminated)}
+	}
+	return nil
+}
+
+func restartStorm(count int32, perHour float64, window time.Duration) Rule {
+	return func(pod *corev1.Pod, now time.Time) *Classification {
+		for _, status := range containerStatuses(pod) {
+			if status.RestartCount < 2 {
+				continue
+			}
+			last := status.LastTerminationState.Terminated
+			if last == nil || now.Sub(last.FinishedAt.Time) > window {
+				continue
+			}
+
+			var rate float64
+			if pod.Status.StartTime != nil {
+				hours := now.Sub(pod.Status.StartTime.Time).Hours()
+				rate = float64(status.RestartCount) / max(hours, 1.0/60)
+			}
+			if status.RestartCount < count && rate < perHour {
+				continue
+			}
+			return &Classification{
+				Reason:    "RestartStorm",
+				Container: status.Name,
+				Severity:  SeverityWarning,
+				Message:   fmt.Sprintf("%d restarts (%.1f per hour), last termination: %s", status.RestartCount, rate, terminationMessage(last)),
+			}
+		}
+		return nil
+	}
+}
+
+func stuckPending(timeout time.Duration) Rule {
+	return func(pod *corev1.Pod, now time.Time) *Classification {
+		if pod.Status.Phase != corev1.PodPending {
+			return nil
+		}
+		pending := now.Sub(pod.CreationTimestamp.Time)
+		if pending < timeout {
+			return nil
+		}
+
+		c := &Classification{Reason: "PendingTooLong", Severity: SeverityCritical}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse {
+				// e.g. insufficient resources, node affinity or unbound PersistentVolumeClaims.
+				c.Reason = "Unschedulable"
+				c.Message = fmt.Sprintf("not scheduled for %s: %s", pending.Round(time.Second), condition.Message)
+				return c
+			}
+		}
+		for _, status := range containerStatuses(pod) {
+			if status.State.Waiting != nil {
+				c.Container = status.Name
+				c.Message = fmt.Sprintf("pending for %s, container waiting: %s %s", pending.Round(time.Second), status.State.Waiting.Reason, status.State.Waiting.Message)
+				return c
+			}
+		}
+		c.Message = fmt.Sprintf("pending for %s", pending.Round(time.Second))
+		return c
+	}
+}
+
+func notReady(timeout time.Duration) Rule {
+	return func(pod *corev1.Pod, now time.Time) *Classification {
+		if pod.Status.Phase != corev1.PodRunning {
+			return nil
+		}
+		var since time.Time
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionFalse {
+				since = condition.LastTransitionTime.Time
+			}
+		}
+		if !since.IsZero() && now.Sub(since) < timeout {
+			return nil
+		}
+
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Ready || status.State.Running == nil {
+				continue
+			}
+			c := &Classification{Reason: "NotReady", Container: status.Name, Severity: SeverityWarning, Message: "running but not ready"}
+			if container := findContainer(pod, status.Name); container != nil && container.ReadinessProbe != nil {
+				c.Reason = "ReadinessProbeFailed"
+				c.Message = "readiness probe failing"
+			}
+			if !since.IsZero() {
+				c.Message += fmt.Sprintf(" for %s", now.Sub(since).Round(time.Second))
+			}
+			return c
+		}
+		return nil
+	}
+}
+
+// containerStatuses returns the statuses of the init and regular containers.
+func containerStatuses(pod *corev1.Pod) []corev1.ContainerStatus {
+	return slices.Concat(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses)
+}
+
+func terminatedContainer(pod *corev1.Pod) (string, *corev1.ContainerStateTerminated) {
+	for _, status := range containerStatuses(pod) {
+		if status.State.Terminated != nil && status.State.Terminated.ExitCode != 0 {
+			return status.Name, status.State.Terminated
+		}
+	}
+	return "", nil
+}
+
+func findContainer(pod *corev1.Pod, name string) *corev1.Container {
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == name {
+			return &pod.Spec.Containers[i]
+		}
+	}
+	return nil
+}
+
+func terminationMessage(state *corev1.ContainerStateTerminated) string {
+	reason := state.Reason
+	if reason == "" {
+		reason = "Error"
+	}
+	msg := fmt.Sprintf("%s (exit code %d)", reason, state.ExitCode)
+	if state.Message != "" {
+		msg += ": " + strings.TrimSpace(state.Message)
+	}
+	return msg
+}
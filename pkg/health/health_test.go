@@ -0,0 +1,133 @@
+package health
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func crashingPod(name string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "app",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			}},
+		},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Now()
+
+	oomKilled := crashingPod("oom")
+	oomKilled.Status.ContainerStatuses[0].RestartCount = 3
+	oomKilled.Status.ContainerStatuses[0].LastTerminationState.Terminated = &corev1.ContainerStateTerminated{
+		Reason:     "OOMKilled",
+		ExitCode:   137,
+		FinishedAt: metav1.NewTime(now.Add(-time.Minute)),
+	}
+	restarting := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "restarting", CreationTimestamp: metav1.NewTime(now.Add(-24 * time.Hour))},
+		Status: corev1.PodStatus{
+			Phase:     corev1.PodRunning,
+			StartTime: &metav1.Time{Time: now.Add(-24 * time.Hour)},
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:         "app",
+				Ready:        true,
+				RestartCount: 3,
+				State:        corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					Reason:     "Error",
+					ExitCode:   1,
+					FinishedAt: metav1.NewTime(now.Add(-10 * time.Minute)),
+				}},
+			}},
+		},
+	}
+	pending := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pending", CreationTimestamp: metav1.NewTime(now.Add(-3 * time.Minute))},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			Conditions: []corev1.PodCondition{{
+				Type:    corev1.PodScheduled,
+				Status:  corev1.ConditionFalse,
+				Message: `persistentvolumeclaim "data" not found`,
+			}},
+		},
+	}
+	lowThresholds := Thresholds{RestartCount: 3, PendingTimeout: time.Minute}
+
+	tests := []struct {
+		name       string
+		thresholds Thresholds
+		pod        corev1.Pod
+		// want is the classification without its message, or nil for a healthy pod.
+		want *Classification
+		// message is a substring of the message.
+		message string
+	}{
+		{
+			name: "crash loop",
+			pod:  crashingPod("web"),
+			want: &Classification{Reason: "CrashLoopBackOff", Container: "app", Severity: SeverityCritical},
+		},
+		{
+			name:    "crash loop caused by the memory limit",
+			pod:     oomKilled,
+			want:    &Classification{Reason: "OOMKilled", Container: "app", Severity: SeverityCritical},
+			message: "OOMKilled (exit code 137)",
+		},
+		{
+			name: "restarts below the default thresholds",
+			pod:  restarting,
+		},
+		{
+			name:       "restarts above the restart count",
+			thresholds: lowThresholds,
+			pod:        restarting,
+			want:       &Classification{Reason: "RestartStorm", Container: "app", Severity: SeverityWarning},
+			message:    "3 restarts",
+		},
+		{
+			name: "pending within the default timeout",
+			pod:  pending,
+		},
+		{
+			name:       "unschedulable beyond the pending timeout",
+			thresholds: lowThresholds,
+			pod:        pending,
+			want:       &Classification{Reason: "Unschedulable", Severity: SeverityCritical},
+			message:    "persistentvolumeclaim",
+		},
+		{
+			name: "succeeded",
+			pod:  corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodSucceeded}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewEvaluator(tt.thresholds).Evaluate(&tt.pod, now)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("Evaluate() = %v, want healthy", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("Evaluate() = healthy, want %v", tt.want)
+			}
+			if got.Reason != tt.want.Reason || got.Container != tt.want.Container || got.Severity != tt.want.Severity {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+			if !strings.Contains(got.Message, tt.message) {
+				t.Errorf("message = %q, want it to contain %q", got.Message, tt.message)
+			}
+		})
+	}
+}
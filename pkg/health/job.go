@@ -0,0 +1,42 @@
+package health
+
+import (
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// EvaluateJob returns the classification of a failed Job, or nil if the Job
+// has not failed. The reason is the reason of the Failed condition, e.g.
+// BackoffLimitExceeded or DeadlineExceeded.
+func EvaluateJob(job *batchv1.Job) *Classification {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type != batchv1.JobFailed || condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		c := &Classification{
+			Reason:   condition.Reason,
+			Severity: SeverityCritical,
+			Message:  fmt.Sprintf("job %s failed with %d failed pods", job.Name, job.Status.Failed),
+		}
+		if c.Reason == "" {
+			c.Reason = "JobFailed"
+		}
+		if condition.Message != "" {
+			c.Message += ": " + condition.Message
+		}
+		return c
+	}
+	return nil
+}
+
+// JobComplete reports whether the Job completed successfully.
+func JobComplete(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobComplete && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
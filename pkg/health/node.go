@@ -0,0 +1,94 @@
+package health
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// nodeConditionGrace is how long a node condition must hold before the
+	// node is unhealthy, so that kubelet restarts are not reported.
+	nodeConditionGrace = time.Minute
+	// maxClockSkew is how far the heartbeat of a node may be ahead of now.
+	maxClockSkew = time.Minute
+)
+
+// nodePressureConditions are the resource pressure conditions of the kubelet.
+var nodePressureConditions = map[corev1.NodeConditionType]bool{
+	corev1.NodeMemoryPressure: true,
+	corev1.NodeDiskPressure:   true,
+	corev1.NodePIDPressure:    true,
+}
+
+// EvaluateNode returns the classification of an unhealthy node, or nil if the
+// node is healthy. A NotReady node takes precedence over resource pressure,
+// which takes precedence over the conditions of other node agents such as the
+// node-problem-detector, e.g. KernelDeadlock or ReadonlyFilesystem.
+func EvaluateNode(node *corev1.Node, now time.Time) *Classification {
+	var pressure, other *Classification
+	for _, condition := range node.Status.Conditions {
+		if now.Sub(condition.LastTransitionTime.Time) < nodeConditionGrace {
+			continue
+		}
+		switch {
+		case condition.Type == corev1.NodeReady:
+			switch condition.Status {
+			case corev1.ConditionFalse:
+				return nodeClassification("NodeNotReady", SeverityCritical, condition, now)
+			case corev1.ConditionUnknown:
+				// The kubelet stopped posting the node status.
+				return nodeClassification("NodeStatusUnknown", SeverityCritical, condition, now)
+			}
+		case condition.Status != corev1.ConditionTrue:
+			// The other conditions report a problem when they are True.
+		case condition.Type == corev1.NodeNetworkUnavailable:
+			return nodeClassification(string(condition.Type), SeverityCritical, condition, now)
+		case nodePressureConditions[condition.Type]:
+			if pressure == nil {
+				pressure = nodeClassification(string(condition.Type), SeverityWarning, condition, now)
+			}
+		default:
+			if other == nil {
+				other = nodeClassification(string(condition.Type), SeverityWarning, condition, now)
+			}
+		}
+	}
+	if pressure != nil {
+		return pressure
+	}
+	if other != nil {
+		return other
+	}
+	return clockSkew(node, now)
+}
+
+func nodeClassification(reason string, severity Severity, condition corev1.NodeCondition, now time.Time) *Classification {
+	msg := fmt.Sprintf("%s=%s for %s", condition.Type, condition.Status, now.Sub(condition.LastTransitionTime.Time).Round(time.Second))
+	if condition.Reason != "" {
+		msg += fmt.Sprintf(" (%s)", condition.Reason)
+	}
+	if condition.Message != "" {
+		msg += ": " + condition.Message
+	}
+	return &Classification{Reason: reason, Severity: severity, Message: msg}
+}
+
+// clockSkew detects a node clock that is ahead, from the heartbeats the
+// kubelet stamps with its own clock.
+func clockSkew(node *corev1.Node, now time.Time) *Classification {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type != corev1.NodeReady {
+			continue
+		}
+		if skew := condition.LastHeartbeatTime.Sub(now); skew > maxClockSkew {
+			return &Classification{
+				Reason:   "ClockSkew",
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("the node heartbeat is %s ahead of the controller clock", skew.Round(time.Second)),
+			}
+		}
+	}
+	return nil
+}
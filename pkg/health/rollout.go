@@ -0,0 +1,81 @@
+package health
+
+import (
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultRolloutTimeout is how long a StatefulSet rolling update may make no
+// progress if no timeout is set.
+const DefaultRolloutTimeout = 10 * time.Minute
+
+// EvaluateDeployment returns the classification of a Deployment whose rollout
+// exceeded its progress deadline, or nil if the rollout is progressing.
+func EvaluateDeployment(deployment *appsv1.Deployment) *Classification {
+	if deployment.Spec.Paused {
+		return nil
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type != appsv1.DeploymentProgressing || condition.Status != corev1.ConditionFalse ||
+			condition.Reason != "ProgressDeadlineExceeded" {
+			continue
+		}
+		status := deployment.Status
+		msg := fmt.Sprintf("%d/%d replicas updated, %d available, %d unavailable",
+			status.UpdatedReplicas, replicas(deployment.Spec.Replicas), status.AvailableReplicas, status.UnavailableReplicas)
+		if condition.Message != "" {
+			msg += ": " + condition.Message
+		}
+		return &Classification{Reason: condition.Reason, Severity: SeverityCritical, Message: msg}
+	}
+	return nil
+}
+
+// EvaluateStatefulSet returns the classification of a StatefulSet whose
+// rolling update made no progress since lastProgress for longer than the
+// timeout, or nil if the update is progressing or complete. A partitioned
+// update whose updated pods are ready is a deliberate canary, not a stall.
+func EvaluateStatefulSet(statefulSet *appsv1.StatefulSet, lastProgress, now time.Time, timeout time.Duration) *Classification {
+	if timeout <= 0 {
+		timeout = DefaultRolloutTimeout
+	}
+	strategy := statefulSet.Spec.UpdateStrategy
+	if strategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+		return nil
+	}
+	status := statefulSet.Status
+	if status.ObservedGeneration < statefulSet.Generation ||
+		status.UpdateRevision == "" || status.UpdateRevision == status.CurrentRevision {
+		return nil
+	}
+
+	desired := replicas(statefulSet.Spec.Replicas)
+	var partition int32
+	if strategy.RollingUpdate != nil && strategy.RollingUpdate.Partition != nil {
+		partition = *strategy.RollingUpdate.Partition
+	}
+	if status.UpdatedReplicas >= desired-partition && status.ReadyReplicas >= desired {
+		return nil
+	}
+	stalled := now.Sub(lastProgress)
+	if stalled < timeout {
+		return nil
+	}
+
+	msg := fmt.Sprintf("update to revision %s made no progress for %s: %d/%d replicas updated, %d ready",
+		status.UpdateRevision, stalled.Round(time.Second), status.UpdatedReplicas, desired-partition, status.ReadyReplicas)
+	if partition > 0 {
+		msg += fmt.Sprintf(", partition %d", partition)
+	}
+	return &Classification{Reason: "RolloutStalled", Severity: SeverityCritical, Message: msg}
+}
+
+func replicas(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
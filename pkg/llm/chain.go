@@ -6,12 +6,13 @@ import (
 	"strings"
 
 	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/prompt"
 	"github.com/cloudwego/eino/components/retriever"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
 )
 
-func newRunnableWithRetriever(ctx context.Context, cm model.BaseChatModel, retriever retriever.Retriever) (compose.Runnable[map[string]any, *schema.Message], error) {
+func newRunnableWithRetriever(ctx context.Context, template prompt.ChatTemplate, cm model.BaseChatModel, retriever retriever.Retriever) (compose.Runnable[map[string]any, *schema.Message], error) {
 
 	chain := compose.NewChain[map[string]any, *schema.Message]()
 
@@ -43,7 +44,7 @@ func newRunnableWithRetriever(ctx context.Context, cm model.BaseChatModel, retri
 		return msgs, nil
 	})
 	chain.
-		AppendChatTemplate(KubernetesLogAnalyzeSystemPrompt).
+		AppendChatTemplate(template).
 		AppendParallel(parallel).
 		AppendLambda(ragReplacer).
 		AppendChatModel(cm)
@@ -55,12 +56,12 @@ func newRunnableWithRetriever(ctx context.Context, cm model.BaseChatModel, retri
 	return runnable, nil
 }
 
-func newRunnable(ctx context.Context, cm model.BaseChatModel) (compose.Runnable[map[string]any, *schema.Message], error) {
+func newRunnable(ctx context.Context, template prompt.ChatTemplate, cm model.BaseChatModel) (compose.Runnable[map[string]any, *schema.Message], error) {
 	chain := compose.NewChain[map[string]any, *schema.Message]()
 	chain.
-		AppendChatTemplate(KubernetesLogAnalyzeSystemPrompt).
+		AppendChatTemplate(template).
 		AppendLambda(compose.InvokableLambda(func(_ context.Context, inputs []*schema.Message) ([]*schema.Message, error) {
-			inputs[1].Content = fmt.Sprintf("日志内容: %s\n运维文档: %s\n", inputs[1].Content, "无运维文档")
+			inputs[1].Content = fmt.Sprintf("%s\n运维文档: %s\n", inputs[1].Content, "无运维文档")
 			return inputs, nil
 		})).
 		AppendChatModel(cm)
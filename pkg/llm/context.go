@@ -0,0 +1,22 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContextSection is a labeled section of cluster context for an analysis,
+// e.g. the events of the pod or the spec of its owner.
+type ContextSection struct {
+	Title   string
+	Content string
+}
+
+// FormatContext renders the sections with a header per section.
+func FormatContext(sections []ContextSection) string {
+	var b strings.Builder
+	for _, section := range sections {
+		fmt.Fprintf(&b, "=== %s ===\n%s\n", section.Title, strings.TrimRight(section.Content, "\n"))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -10,8 +10,6 @@ import (
 	"github.com/getkin/kin-openapi/openapi3"
 	"go.uber.org/zap"
 	"google.golang.org/genai"
-	"gopkg.in/yaml.v3"
-	corev1 "k8s.io/api/core/v1"
 )
 
 type GeminiClient struct {
@@ -40,8 +38,13 @@ func NewGeminiClient(model, apiKey, language string, thinking bool, retriever *H
 	}, nil
 }
 
-func (c *GeminiClient) Analyze(ctx context.Context, pod corev1.Pod, logs string) (string, error) {
-	cm, err := c.GetModel(ctx, KubernetesLogAnalyzeResponseSchema)
+func (c *GeminiClient) Analyze(ctx context.Context, in AnalysisInput) (string, error) {
+	template, input, responseSchema, err := in.prompt(c.language)
+	if err != nil {
+		return "", err
+	}
+
+	cm, err := c.GetModel(ctx, responseSchema)
 	if err != nil {
 		zap.L().Error("NewChatModel of gemini failed", zap.Error(err))
 		return "", err
@@ -49,30 +52,19 @@ func (c *GeminiClient) Analyze(ctx context.Context, pod corev1.Pod, logs string)
 	var runnable compose.Runnable[map[string]any, *schema.Message]
 
 	if c.retriever != nil {
-		runnable, err = newRunnableWithRetriever(ctx, cm, c.retriever)
+		runnable, err = newRunnableWithRetriever(ctx, template, cm, c.retriever)
 		if err != nil {
 			zap.L().Error("newChainWithRetriever failed", zap.Error(err))
 			return "", err
 		}
 	} else {
-		runnable, err = newRunnable(ctx, cm)
+		runnable, err = newRunnable(ctx, template, cm)
 		if err != nil {
 			zap.L().Error("newChain failed", zap.Error(err))
 			return "", err
 		}
 	}
 
-	podYaml, err := yaml.Marshal(pod)
-	if err != nil {
-		zap.L().Error("Marshal pod to yaml failed", zap.Error(err))
-		return "", err
-	}
-
-	input := map[string]any{
-		"pod_yaml": string(podYaml),
-		"logs":     logs,
-		"lang":     GetLanguageName(c.language),
-	}
 	result, err := runnable.Invoke(ctx, input)
 	if err != nil {
 		zap.L().Error("Invoke chain failed", zap.Error(err))
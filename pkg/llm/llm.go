@@ -7,33 +7,121 @@ import (
 	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
 	"github.com/Fl0rencess720/Kopilot/internal/controller/utils"
 	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/prompt"
 	"github.com/getkin/kin-openapi/openapi3"
 	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/client-go/kubernetes"
 )
 
+// AnalysisInput is what is known about an unhealthy pod, about an unhealthy
+// node if Node is set, or about a stalled rollout if Rollout is set.
+type AnalysisInput struct {
+	Pod  corev1.Pod
+	Node *corev1.Node
+	// Rollout is the stalled rollout. Pod is a pod of its new revision, if any.
+	Rollout *Rollout
+	Logs    string
+	// Classification is the failure classification of the health evaluator.
+	Classification string
+	// PromptHint is a hint of the health rule that classified the pod.
+	PromptHint string
+	// Context is the cluster context of the pod, such as events and owners.
+	Context []ContextSection
+}
+
+// Rollout is a stalled rollout of a Deployment or StatefulSet.
+type Rollout struct {
+	Kind        string `yaml:"kind"`
+	Namespace   string `yaml:"namespace"`
+	Name        string `yaml:"name"`
+	OldRevision string `yaml:"oldRevision,omitempty"`
+	NewRevision string `yaml:"newRevision"`
+}
+
+// Resource returns the analyzed resource: the node, the pod, or the rollout
+// if it has no pod of the new revision.
+func (in AnalysisInput) Resource() any {
+	switch {
+	case in.Node != nil:
+		return in.Node
+	case in.Rollout != nil && in.Pod.Name == "":
+		return in.Rollout
+	}
+	return in.Pod
+}
+
+// prompt returns the prompt template, its variables and the response schema
+// for the input.
+func (in AnalysisInput) prompt(language string) (prompt.ChatTemplate, map[string]any, *openapi3.Schema, error) {
+	resourceYaml, err := yaml.Marshal(in.Resource())
+	if err != nil {
+		zap.L().Error("Marshal resource to yaml failed", zap.Error(err))
+		return nil, nil, nil, err
+	}
+
+	variables := map[string]any{
+		"logs":           in.Logs,
+		"classification": in.Classification,
+		"prompt_hint":    in.PromptHint,
+		"context":        FormatContext(in.Context),
+		"lang":           GetLanguageName(language),
+	}
+	switch {
+	case in.Node != nil:
+		variables["node_yaml"] = string(resourceYaml)
+		return KubernetesNodeAnalyzeSystemPrompt, variables, KubernetesLogAnalyzeResponseSchema, nil
+	case in.Rollout != nil:
+		rolloutYaml, err := yaml.Marshal(in.Rollout)
+		if err != nil {
+			zap.L().Error("Marshal rollout to yaml failed", zap.Error(err))
+			return nil, nil, nil, err
+		}
+		variables["rollout_yaml"] = string(rolloutYaml)
+		if in.Pod.Name != "" {
+			variables["pod_yaml"] = string(resourceYaml)
+		}
+		return KubernetesRolloutAnalyzeSystemPrompt, variables, KubernetesRolloutAnalyzeResponseSchema, nil
+	}
+	variables["pod_yaml"] = string(resourceYaml)
+	return KubernetesLogAnalyzeSystemPrompt, variables, KubernetesLogAnalyzeResponseSchema, nil
+}
+
 type LLMClient interface {
-	Analyze(ctx context.Context, pod corev1.Pod, logs string) (string, error)
+	Analyze(ctx context.Context, in AnalysisInput) (string, error)
 	GetModel(ctx context.Context, responseSchema *openapi3.Schema) (model.ToolCallingChatModel, error)
 }
 
-func NewLLMClient(ctx context.Context, clientset kubernetes.Interface, llmSpec kopilotv1.LLMSpec, retriever *HybridRetriever) (LLMClient, error) {
+func NewLLMClient(ctx context.Context, secrets utils.SecretGetter, llmSpec kopilotv1.LLMSpec, retriever *HybridRetriever) (LLMClient, error) {
 	switch llmSpec.Model {
 	case "gemini":
-		apikey, err := utils.GetSecret(clientset, llmSpec.Gemini.APIKeySecretRef.Key, "default", llmSpec.Gemini.APIKeySecretRef.Name)
+		apikey, err := secrets.GetSecret(ctx, llmSpec.Gemini.APIKeySecretRef)
 		if err != nil {
 			zap.L().Error("unable to get LLM API key", zap.Error(err))
 			return nil, err
 		}
 		return NewGeminiClient(llmSpec.Gemini.ModelName, apikey, llmSpec.Language, llmSpec.Gemini.Thinking, retriever)
 	case "deepseek":
-		apikey, err := utils.GetSecret(clientset, llmSpec.DeepSeek.APIKeySecretRef.Key, "default", llmSpec.DeepSeek.APIKeySecretRef.Name)
+		apikey, err := secrets.GetSecret(ctx, llmSpec.DeepSeek.APIKeySecretRef)
 		if err != nil {
 			zap.L().Error("unable to get LLM API key", zap.Error(err))
 			return nil, err
 		}
 		return NewDeepSeekClient(llmSpec.DeepSeek.ModelName, apikey, llmSpec.Language, retriever)
+	case "openai":
+		if llmSpec.OpenAI == nil {
+			return nil, fmt.Errorf("openai must be set when model is openai")
+		}
+		var apikey string
+		if ref := llmSpec.OpenAI.APIKeySecretRef; ref != nil {
+			var err error
+			apikey, err = secrets.GetSecret(ctx, *ref)
+			if err != nil {
+				zap.L().Error("unable to get LLM API key", zap.Error(err))
+				return nil, err
+			}
+		}
+		return NewOpenAIClient(*llmSpec.OpenAI, apikey, llmSpec.Language, retriever)
 	default:
 		return nil, fmt.Errorf("unsupported LLM model: %s", llmSpec.Model)
 	}
@@ -20,6 +20,10 @@ type state struct {
 	hasKnowledgeBase bool
 	messages         []*schema.Message
 	language         string
+	// newRevisionAtFault and rollback are the last verdict of the host on a
+	// stalled rollout.
+	newRevisionAtFault *bool
+	rollback           *bool
 }
 
 type HostDecision struct {
@@ -28,6 +32,8 @@ type HostDecision struct {
 		AutoFix string `json:"autofix"`
 		Search  string `json:"search"`
 	} `json:"context"`
+	NewRevisionAtFault *bool `json:"new_revision_at_fault,omitempty"`
+	Rollback           *bool `json:"rollback,omitempty"`
 }
 
 const (
@@ -73,6 +79,7 @@ func buildGraphRunnable(ctx context.Context, config *LogMultiAgentConfig) (compo
 
 	_ = graph.AddChatModelNode(nodeKeyHost, config.Host,
 		compose.WithStatePreHandler(hostPreHandle),
+		compose.WithStatePostHandler(hostPostHandle),
 		compose.WithNodeName(nodeKeyHost))
 
 	autoFixerOpts = append(autoFixerOpts, compose.WithStatePreHandler(autoFixerPreHandle),
@@ -92,6 +99,10 @@ func buildGraphRunnable(ctx context.Context, config *LogMultiAgentConfig) (compo
 	_ = graph.AddGraphNode(nodeKeySearcher, searcherAgent, searcherOpts...)
 
 	humanHelperOpts = append(humanHelperOpts, compose.WithStatePreHandler(humanHelperPreHandle),
+		compose.WithStatePostHandler(func(ctx context.Context, output *schema.Message, state *state) (*schema.Message, error) {
+			state.humanHelpResult = output.Content
+			return output, nil
+		}),
 		compose.WithNodeName(nodeKeyHumanHelper))
 
 	_ = graph.AddGraphNode(nodeKeyHumanHelper, humanHelperAgent, humanHelperOpts...)
@@ -175,6 +186,21 @@ func hostPreHandle(ctx context.Context, input []*schema.Message, state *state) (
 	}, nil
 }
 
+// hostPostHandle keeps the verdict of the host on a stalled rollout.
+func hostPostHandle(ctx context.Context, output *schema.Message, state *state) (*schema.Message, error) {
+	var decision HostDecision
+	if err := json.Unmarshal([]byte(output.Content), &decision); err != nil {
+		return output, nil
+	}
+	if decision.NewRevisionAtFault != nil {
+		state.newRevisionAtFault = decision.NewRevisionAtFault
+	}
+	if decision.Rollback != nil {
+		state.rollback = decision.Rollback
+	}
+	return output, nil
+}
+
 func autoFixerPreHandle(ctx context.Context, input []*schema.Message, state *state) ([]*schema.Message, error) {
 	msg := []*schema.Message{}
 	if state.hasKnowledgeBase {
@@ -5,14 +5,13 @@ import (
 	"fmt"
 
 	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/Fl0rencess720/Kopilot/internal/controller/utils"
 	"github.com/Fl0rencess720/Kopilot/pkg/llm"
 	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
 	"gopkg.in/yaml.v3"
-	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/kubernetes"
 )
 
 type LogMultiAgent struct {
@@ -30,8 +29,8 @@ type LogMultiAgentConfig struct {
 	language      string
 }
 
-func NewLogMultiAgent(ctx context.Context, clientset kubernetes.Interface, dynamicClient dynamic.Interface, llmSpec kopilotv1.LLMSpec, retriever *llm.HybridRetriever, language string) (*LogMultiAgent, error) {
-	maLLM, err := llm.NewLLMClient(ctx, clientset, llmSpec, nil)
+func NewLogMultiAgent(ctx context.Context, secrets utils.SecretGetter, dynamicClient dynamic.Interface, llmSpec kopilotv1.LLMSpec, retriever *llm.HybridRetriever, language string) (*LogMultiAgent, error) {
+	maLLM, err := llm.NewLLMClient(ctx, secrets, llmSpec, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -71,23 +70,32 @@ func NewLogMultiAgent(ctx context.Context, clientset kubernetes.Interface, dynam
 	return ma, nil
 }
 
-func (ma *LogMultiAgent) Run(ctx context.Context, pod corev1.Pod, logs string) (string, error) {
-	resourceYaml, err := yaml.Marshal(pod)
+func (ma *LogMultiAgent) Run(ctx context.Context, input llm.AnalysisInput) (*SinkMessageContent, error) {
+	resourceYaml, err := yaml.Marshal(input.Resource())
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	classification := input.Classification
+	if input.PromptHint != "" {
+		classification += "\n分析提示: " + input.PromptHint
+	}
+	content := fmt.Sprintf("资源 yaml: %s\n故障分类: %s\n", string(resourceYaml), classification)
+	if rollout := input.Rollout; rollout != nil {
+		content += fmt.Sprintf("发布信息: %s %s/%s 从版本 %s 发布到版本 %s 时停滞, 请判断新版本是否是故障原因以及是否需要回滚\n",
+			rollout.Kind, rollout.Namespace, rollout.Name, rollout.OldRevision, rollout.NewRevision)
+	}
+	if len(input.Context) > 0 {
+		content += fmt.Sprintf("集群上下文:\n%s\n", llm.FormatContext(input.Context))
+	}
+	if input.Logs != "" {
+		content += fmt.Sprintf("日志内容: %s", input.Logs)
 	}
-	content := fmt.Sprintf("资源 yaml: %s\n日志内容: %s", string(resourceYaml), logs)
 	in := []*schema.Message{{
 		Content: content,
 	}}
 	output, err := ma.runnable.Invoke(ctx, in)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	result := ""
-	result += fmt.Sprintf("初始日志：%s\n", output.OriginalInput)
-	result += fmt.Sprintf("自动修复结果: %s\n", output.AutoFixResult)
-	result += fmt.Sprintf("搜索结果: %s\n", output.SearchResult)
-	result += fmt.Sprintf("问题文档: %s\n", output.HumanHelpResult)
-	return result, nil
+	return output, nil
 }
@@ -38,6 +38,9 @@ var (
 		Searcher: 网络搜索,当自动修复失败后,请使用此选项,此时context字段必须为空
 		HumanHelper:  寻求人类帮助,当自动修复失败且已经进行过网络搜索后,请将自动修复失败所返回的上下文和网络搜索结果整理后写入context字段
 		Finish: 任务结束,当你认为问题已经解决时,请使用该选项,例如当自动修复成功或成功寻求人类帮助后,则可以选择Finish
+		当用户输入包含发布信息时,还需返回以下字段:
+			new_revision_at_fault: 新版本是否是故障原因, 布尔值
+			rollback: 是否建议回滚到旧版本, 布尔值
 		请使用{{.lang}}回答
 		`)
 
@@ -66,6 +69,16 @@ var (
 					},
 				},
 			},
+			"new_revision_at_fault": {
+				Value: &openapi3.Schema{
+					Type: "boolean",
+				},
+			},
+			"rollback": {
+				Value: &openapi3.Schema{
+					Type: "boolean",
+				},
+			},
 		},
 		Required: []string{"option", "context"},
 	}
@@ -12,18 +12,24 @@ type SinkMessageContent struct {
 	AutoFixResult   string `json:"autoFixResult"`
 	SearchResult    string `json:"searchResult"`
 	HumanHelpResult string `json:"humanHelpResult"`
+	// NewRevisionAtFault and Rollback are the verdict of the host on a
+	// stalled rollout.
+	NewRevisionAtFault *bool `json:"newRevisionAtFault,omitempty"`
+	Rollback           *bool `json:"rollback,omitempty"`
 }
 
-func buildSinkMsg(ctx context.Context, input *schema.Message) (SinkMessageContent, error) {
-	sinkMessageContent := SinkMessageContent{}
+func buildSinkMsg(ctx context.Context, input *schema.Message) (*SinkMessageContent, error) {
+	sinkMessageContent := &SinkMessageContent{}
 	if err := compose.ProcessState(ctx, func(ctx context.Context, state *state) error {
 		sinkMessageContent.OriginalInput = input.Content
 		sinkMessageContent.AutoFixResult = state.autoFixResult
 		sinkMessageContent.SearchResult = state.searchResult
 		sinkMessageContent.HumanHelpResult = state.humanHelpResult
+		sinkMessageContent.NewRevisionAtFault = state.newRevisionAtFault
+		sinkMessageContent.Rollback = state.rollback
 		return nil
 	}); err != nil {
-		return SinkMessageContent{}, err
+		return nil, err
 	}
 	return sinkMessageContent, nil
 }
@@ -0,0 +1,120 @@
+package llm
+
+import (
+	"context"
+	"strconv"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	aclopenai "github.com/cloudwego/eino-ext/libs/acl/openai"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+	"go.uber.org/zap"
+)
+
+type OpenAIClient struct {
+	baseURL     string
+	model       string
+	apiKey      string
+	maxTokens   *int
+	temperature *float32
+	jsonMode    string
+	language    string
+	retriever   *HybridRetriever
+}
+
+func NewOpenAIClient(spec kopilotv1.OpenAISpec, apiKey, language string, retriever *HybridRetriever) (*OpenAIClient, error) {
+	c := &OpenAIClient{
+		baseURL:   spec.BaseURL,
+		model:     spec.ModelName,
+		apiKey:    apiKey,
+		jsonMode:  spec.JSONMode,
+		language:  language,
+		retriever: retriever,
+	}
+	if spec.MaxTokens != nil {
+		maxTokens := int(*spec.MaxTokens)
+		c.maxTokens = &maxTokens
+	}
+	if spec.Temperature != "" {
+		temperature, err := strconv.ParseFloat(spec.Temperature, 32)
+		if err != nil {
+			zap.L().Error("parse temperature failed", zap.Error(err))
+			return nil, err
+		}
+		t := float32(temperature)
+		c.temperature = &t
+	}
+	return c, nil
+}
+
+func (c *OpenAIClient) Analyze(ctx context.Context, in AnalysisInput) (string, error) {
+	template, input, responseSchema, err := in.prompt(c.language)
+	if err != nil {
+		return "", err
+	}
+
+	cm, err := c.GetModel(ctx, responseSchema)
+	if err != nil {
+		zap.L().Error("NewChatModel of openai failed", zap.Error(err))
+		return "", err
+	}
+
+	var runnable compose.Runnable[map[string]any, *schema.Message]
+
+	if c.retriever != nil {
+		runnable, err = newRunnableWithRetriever(ctx, template, cm, c.retriever)
+		if err != nil {
+			zap.L().Error("newChainWithRetriever failed", zap.Error(err))
+			return "", err
+		}
+	} else {
+		runnable, err = newRunnable(ctx, template, cm)
+		if err != nil {
+			zap.L().Error("newChain failed", zap.Error(err))
+			return "", err
+		}
+	}
+
+	result, err := runnable.Invoke(ctx, input)
+	if err != nil {
+		zap.L().Error("Invoke chain failed", zap.Error(err))
+		return "", err
+	}
+	return result.Content, nil
+}
+
+func (c *OpenAIClient) GetModel(ctx context.Context, responseSchema *openapi3.Schema) (model.ToolCallingChatModel, error) {
+	var responseFormat *aclopenai.ChatCompletionResponseFormat
+	if responseSchema != nil {
+		switch c.jsonMode {
+		case "json_object":
+			responseFormat = &aclopenai.ChatCompletionResponseFormat{
+				Type: aclopenai.ChatCompletionResponseFormatTypeJSONObject,
+			}
+		case "json_schema":
+			responseFormat = &aclopenai.ChatCompletionResponseFormat{
+				Type: aclopenai.ChatCompletionResponseFormatTypeJSONSchema,
+				JSONSchema: &aclopenai.ChatCompletionResponseFormatJSONSchema{
+					Name:   "response",
+					Schema: responseSchema,
+				},
+			}
+		}
+	}
+
+	cm, err := openai.NewChatModel(ctx, &openai.ChatModelConfig{
+		APIKey:         c.apiKey,
+		BaseURL:        c.baseURL,
+		Model:          c.model,
+		MaxTokens:      c.maxTokens,
+		Temperature:    c.temperature,
+		ResponseFormat: responseFormat,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cm, nil
+}
@@ -29,8 +29,75 @@ var (
         "sink": true  
         }
 		请使用{{.lang}}回答
-		以下是该Pod的yaml, 日志内容和运维文档:`),
-		schema.UserMessage("Pod yaml: {{.pod_yaml}}\n日志内容：{{.logs}}"),
+		故障分类是根据Pod状态预先判断的故障类型, 请结合它进行分析。
+		集群上下文包括Pod及其所属工作负载的事件、所属工作负载的spec和status、版本历史、CronJob的调度和历史Job以及节点状态, 请结合它们判断根本原因。
+		以下是该Pod的yaml, 故障分类, 集群上下文, 日志内容和运维文档:`),
+		schema.UserMessage("Pod yaml: {{.pod_yaml}}\n故障分类：{{.classification}}\n{{if .prompt_hint}}分析提示：{{.prompt_hint}}\n{{end}}{{if .context}}集群上下文：\n{{.context}}\n{{end}}日志内容：{{.logs}}"),
+	)
+
+	KubernetesNodeAnalyzeSystemPrompt = prompt.FromMessages(
+		schema.GoTemplate,
+		schema.SystemMessage(
+			`你是一个Kubernetes运维专家,请根据输入的节点状态评估节点故障的严重程度。
+		节点故障(如NotReady、DiskPressure、MemoryPressure、PIDPressure、时钟偏差)往往会导致其上的Pod被驱逐或反复失败,
+		请找出节点层面的根本原因, 而不是逐个分析受影响的Pod。
+		对于严重程度高的故障，请给出原因分析和解决方案，并判断是否需要上报。
+		你需要从运维文档找到对于给出节点问题的解决方案，若运维文档为空或没有找到合适的解决方案，
+		则由你自己给出合适的解决方案。
+
+        以下是返回结果的格式要求：  
+  
+        返回结果应该仅以JSON格式返回;
+        返回字段包括：  
+            reason: 原因分析  
+            solution: 解决方案  
+            sink: 是否需要上报,如果需要上报,值为true,否则为false  
+        请根据以下示例格式返回结果：  
+        {  
+        "reason": "error reason",  
+        "solution": "error solution",  
+        "sink": true  
+        }
+		请使用{{.lang}}回答
+		故障分类是根据节点状况预先判断的故障类型, 请结合它进行分析。
+		集群上下文包括节点的describe信息(状况、容量、已分配资源、污点)、从该节点驱逐的Pod和节点事件, 请结合它们判断根本原因。
+		以下是该节点的yaml, 故障分类, 集群上下文和运维文档:`),
+		schema.UserMessage("Node yaml: {{.node_yaml}}\n故障分类：{{.classification}}\n{{if .prompt_hint}}分析提示：{{.prompt_hint}}\n{{end}}{{if .context}}集群上下文：\n{{.context}}\n{{end}}"),
+	)
+
+	KubernetesRolloutAnalyzeSystemPrompt = prompt.FromMessages(
+		schema.GoTemplate,
+		schema.SystemMessage(
+			`你是一个Kubernetes运维专家,请分析一个停滞的工作负载发布(Deployment的ProgressDeadlineExceeded或StatefulSet滚动更新停滞)。
+		发布停滞时单个Pod看起来可能是健康的, 请结合新旧版本的Pod模板差异、新版本Pod的事件和日志,
+		判断新版本是否是故障原因, 以及是否建议回滚到旧版本。
+		对于严重程度高的故障，请给出原因分析和解决方案，并判断是否需要上报。
+		你需要从运维文档找到对于给出问题的解决方案，若运维文档为空或没有找到合适的解决方案，
+		则由你自己给出合适的解决方案。
+
+        以下是返回结果的格式要求：  
+  
+        返回结果应该仅以JSON格式返回;
+        返回字段包括：  
+            reason: 原因分析  
+            solution: 解决方案  
+            sink: 是否需要上报,如果需要上报,值为true,否则为false  
+            new_revision_at_fault: 新版本是否是故障原因,如果是,值为true,否则为false  
+            rollback: 是否建议回滚到旧版本,如果建议,值为true,否则为false  
+        请根据以下示例格式返回结果：  
+        {  
+        "reason": "error reason",  
+        "solution": "error solution",  
+        "sink": true,  
+        "new_revision_at_fault": true,  
+        "rollback": true  
+        }
+		请使用{{.lang}}回答
+		故障分类是根据工作负载的发布状态预先判断的故障类型, 请结合它进行分析。
+		集群上下文包括新旧版本Pod模板的差异、新版本Pod及工作负载的事件、工作负载的spec和status以及版本历史, 请结合它们判断根本原因。
+		若没有新版本的Pod, 则说明新版本的Pod未能创建, 请重点分析工作负载的事件。
+		以下是发布信息, 新版本Pod的yaml, 故障分类, 集群上下文, 日志内容和运维文档:`),
+		schema.UserMessage("发布信息: {{.rollout_yaml}}\n{{if .pod_yaml}}新版本Pod yaml: {{.pod_yaml}}\n{{end}}故障分类：{{.classification}}\n{{if .context}}集群上下文：\n{{.context}}\n{{end}}{{if .logs}}日志内容：{{.logs}}{{end}}"),
 	)
 
 	KubernetesLogAnalyzeResponseSchema = &openapi3.Schema{
@@ -54,4 +121,36 @@ var (
 		},
 		Required: []string{"reason", "solution", "sink"},
 	}
+
+	KubernetesRolloutAnalyzeResponseSchema = &openapi3.Schema{
+		Type: "object",
+		Properties: map[string]*openapi3.SchemaRef{
+			"reason": {
+				Value: &openapi3.Schema{
+					Type: "string",
+				},
+			},
+			"solution": {
+				Value: &openapi3.Schema{
+					Type: "string",
+				},
+			},
+			"sink": {
+				Value: &openapi3.Schema{
+					Type: "boolean",
+				},
+			},
+			"new_revision_at_fault": {
+				Value: &openapi3.Schema{
+					Type: "boolean",
+				},
+			},
+			"rollback": {
+				Value: &openapi3.Schema{
+					Type: "boolean",
+				},
+			},
+		},
+		Required: []string{"reason", "solution", "sink", "new_revision_at_fault", "rollback"},
+	}
 )
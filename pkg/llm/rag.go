@@ -14,7 +14,6 @@ import (
 	"github.com/milvus-io/milvus/client/v2/index"
 	"github.com/milvus-io/milvus/client/v2/milvusclient"
 	"go.uber.org/zap"
-	"k8s.io/client-go/kubernetes"
 )
 
 type Embedder interface {
@@ -32,14 +31,14 @@ type HybridRetriever struct {
 	collectionName string
 }
 
-func newMilvusClient(ctx context.Context, clientset kubernetes.Interface, knowledgeBase kopilotv1.KnowledgeBaseSpec) (*milvusclient.Client, error) {
+func newMilvusClient(ctx context.Context, secrets utils.SecretGetter, knowledgeBase kopilotv1.KnowledgeBaseSpec) (*milvusclient.Client, error) {
 
-	username, err := utils.GetSecret(clientset, knowledgeBase.UsernameSecretRef.Key, knowledgeBase.UsernameSecretRef.Namespace, knowledgeBase.UsernameSecretRef.Name)
+	username, err := secrets.GetSecret(ctx, knowledgeBase.UsernameSecretRef)
 	if err != nil {
 		username = ""
 	}
 
-	password, err := utils.GetSecret(clientset, knowledgeBase.PasswordSecretRef.Key, knowledgeBase.PasswordSecretRef.Namespace, knowledgeBase.PasswordSecretRef.Name)
+	password, err := secrets.GetSecret(ctx, knowledgeBase.PasswordSecretRef)
 	if err != nil {
 		password = ""
 	}
@@ -56,12 +55,12 @@ func newMilvusClient(ctx context.Context, clientset kubernetes.Interface, knowle
 	return client, nil
 }
 
-func NewHybridRetriever(ctx context.Context, clientset kubernetes.Interface, knowledgeBase kopilotv1.KnowledgeBaseSpec) (*HybridRetriever, error) {
-	embedder, err := newEmbedder(ctx, clientset, knowledgeBase)
+func NewHybridRetriever(ctx context.Context, secrets utils.SecretGetter, knowledgeBase kopilotv1.KnowledgeBaseSpec) (*HybridRetriever, error) {
+	embedder, err := newEmbedder(ctx, secrets, knowledgeBase)
 	if err != nil {
 		return nil, err
 	}
-	milvusClient, err := newMilvusClient(ctx, clientset, knowledgeBase)
+	milvusClient, err := newMilvusClient(ctx, secrets, knowledgeBase)
 	if err != nil {
 		return nil, err
 	}
@@ -77,10 +76,10 @@ func NewHybridRetriever(ctx context.Context, clientset kubernetes.Interface, kno
 	return hr, nil
 }
 
-func newEmbedder(ctx context.Context, clientset kubernetes.Interface, knowledgeBaseSpec kopilotv1.KnowledgeBaseSpec) (Embedder, error) {
+func newEmbedder(ctx context.Context, secrets utils.SecretGetter, knowledgeBaseSpec kopilotv1.KnowledgeBaseSpec) (Embedder, error) {
 	switch knowledgeBaseSpec.EmbeddingProvider {
 	case "ark":
-		apikey, err := utils.GetSecret(clientset, knowledgeBaseSpec.ArkSpec.APIKeySecretRef.Key, "default", knowledgeBaseSpec.ArkSpec.APIKeySecretRef.Name)
+		apikey, err := secrets.GetSecret(ctx, knowledgeBaseSpec.ArkSpec.APIKeySecretRef)
 		if err != nil {
 			zap.L().Error("unable to get LLM API key", zap.Error(err))
 			return nil, err
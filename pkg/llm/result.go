@@ -0,0 +1,23 @@
+package llm
+
+import (
+	"encoding/json"
+)
+
+// AnalysisResult is the structured output of KubernetesLogAnalyzeSystemPrompt.
+// The rollout fields are only set by KubernetesRolloutAnalyzeSystemPrompt.
+type AnalysisResult struct {
+	Reason             string `json:"reason"`
+	Solution           string `json:"solution"`
+	Sink               bool   `json:"sink"`
+	NewRevisionAtFault *bool  `json:"new_revision_at_fault,omitempty"`
+	Rollback           *bool  `json:"rollback,omitempty"`
+}
+
+func ParseAnalysisResult(content string) (*AnalysisResult, error) {
+	var result AnalysisResult
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,149 @@
+package logsource
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+)
+
+// TLSOptions is a resolved TLS configuration.
+type TLSOptions struct {
+	CA                 []byte
+	Cert               []byte
+	Key                []byte
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// ResolveTLSOptions reads the PEM data referenced by spec.
+func ResolveTLSOptions(ctx context.Context, secrets SecretGetter, spec *kopilotv1.TLSConfig) (TLSOptions, error) {
+	if spec == nil {
+		return TLSOptions{}, nil
+	}
+	opts := TLSOptions{ServerName: spec.ServerName, InsecureSkipVerify: spec.InsecureSkipVerify}
+	for _, r := range []struct {
+		ref  *kopilotv1.SecretKeyRef
+		data *[]byte
+	}{
+		{spec.CASecretRef, &opts.CA},
+		{spec.CertSecretRef, &opts.Cert},
+		{spec.KeySecretRef, &opts.Key},
+	} {
+		if r.ref == nil {
+			continue
+		}
+		value, err := secrets.GetSecret(ctx, *r.ref)
+		if err != nil {
+			return TLSOptions{}, err
+		}
+		*r.data = []byte(value)
+	}
+	return opts, nil
+}
+
+func (o TLSOptions) fingerprint() string {
+	h := sha256.New()
+	for _, b := range [][]byte{o.CA, o.Cert, o.Key, []byte(o.ServerName), []byte(fmt.Sprint(o.InsecureSkipVerify))} {
+		fmt.Fprintf(h, "%d:", len(b))
+		h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (o TLSOptions) config() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+	if len(o.CA) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(o.CA) {
+			return nil, fmt.Errorf("no valid certificate found in the CA bundle")
+		}
+		config.RootCAs = pool
+	}
+	if len(o.Cert) > 0 {
+		cert, err := tls.X509KeyPair(o.Cert, o.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// ClientPool keeps an HTTP client per Kopilot, so that connections to log
+// sources are reused between runs. A client is replaced when its TLS options
+// change. The zero value is ready to use.
+type ClientPool struct {
+	mu      sync.Mutex
+	clients map[string]pooledClient
+}
+
+type pooledClient struct {
+	fingerprint string
+	client      *http.Client
+}
+
+// Get returns the client of key for the TLS options.
+func (p *ClientPool) Get(key string, opts TLSOptions) (*http.Client, error) {
+	fingerprint := opts.fingerprint()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pooled, ok := p.clients[key]; ok && pooled.fingerprint == fingerprint {
+		return pooled.client, nil
+	}
+
+	client, err := newHTTPClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	if pooled, ok := p.clients[key]; ok {
+		pooled.client.CloseIdleConnections()
+	}
+	if p.clients == nil {
+		p.clients = map[string]pooledClient{}
+	}
+	p.clients[key] = pooledClient{fingerprint: fingerprint, client: client}
+	return client, nil
+}
+
+// Forget closes and drops the client of key.
+func (p *ClientPool) Forget(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pooled, ok := p.clients[key]; ok {
+		pooled.client.CloseIdleConnections()
+		delete(p.clients, key)
+	}
+}
+
+func newHTTPClient(opts TLSOptions) (*http.Client, error) {
+	tlsConfig, err := opts.config()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.MaxIdleConnsPerHost = 10
+	return &http.Client{Transport: transport, Timeout: 30 * time.Second}, nil
+}
+
+// httpClient returns the pooled client of the config, or a new client if the
+// config has no pool.
+func (c Config) httpClient(opts TLSOptions) (*http.Client, error) {
+	if c.Clients == nil {
+		return newHTTPClient(opts)
+	}
+	return c.Clients.Get(c.Key, opts)
+}
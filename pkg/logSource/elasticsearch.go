@@ -0,0 +1,327 @@
+package logsource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+)
+
+func init() {
+	Register("Elasticsearch", func(ctx context.Context, config Config) (LogSource, error) {
+		if config.Spec.Elasticsearch == nil {
+			return nil, fmt.Errorf("elasticsearch must be set when the log source type is Elasticsearch")
+		}
+		return NewElasticsearchSource(ctx, config, *config.Spec.Elasticsearch)
+	})
+}
+
+type ElasticsearchClient struct {
+	address      string
+	indexPattern string
+	username     string
+	password     string
+	apiKey       string
+	fields       ElasticsearchFields
+	httpClient   *http.Client
+}
+
+// ElasticsearchFields are the fields of a log document. Nested fields are
+// separated by dots. Pod, Namespace and Container must be keyword fields; a
+// "keyword" multi-field is read from the text field it belongs to.
+type ElasticsearchFields struct {
+	Pod       string
+	Namespace string
+	Container string
+	Message   string
+	Timestamp string
+}
+
+// ElasticsearchAuth holds the credentials of an Elasticsearch cluster. Either
+// Username and Password or APIKey is set.
+type ElasticsearchAuth struct {
+	Username string
+	Password string
+	APIKey   string
+}
+
+type ElasticsearchQueryOptions struct {
+	PodName   string
+	Namespace string
+	// Container restricts the logs to a container of the pod if set.
+	Container string
+	Limit     int
+	// Start and End bound the queried time range. End defaults to now and
+	// Start to End minus TimeRange.
+	Start     time.Time
+	End       time.Time
+	TimeRange time.Duration
+}
+
+type elasticsearchResponse struct {
+	Hits struct {
+		Hits []struct {
+			Source map[string]any `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// DefaultElasticsearchFields match the documents written by the Fluent Bit
+// kubernetes filter into indices with the default dynamic mapping.
+var DefaultElasticsearchFields = ElasticsearchFields{
+	Pod:       "kubernetes.pod_name.keyword",
+	Namespace: "kubernetes.namespace_name.keyword",
+	Container: "kubernetes.container_name.keyword",
+	Message:   "log",
+	Timestamp: "@timestamp",
+}
+
+// NewElasticsearchClient creates an Elasticsearch client. The fields that are
+// not set default to DefaultElasticsearchFields.
+func NewElasticsearchClient(address, indexPattern string, httpClient *http.Client, auth ElasticsearchAuth, fields ElasticsearchFields) *ElasticsearchClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if indexPattern == "" {
+		indexPattern = "logstash-*"
+	}
+	for _, f := range []struct {
+		field *string
+		def   string
+	}{
+		{&fields.Pod, DefaultElasticsearchFields.Pod},
+		{&fields.Namespace, DefaultElasticsearchFields.Namespace},
+		{&fields.Container, DefaultElasticsearchFields.Container},
+		{&fields.Message, DefaultElasticsearchFields.Message},
+		{&fields.Timestamp, DefaultElasticsearchFields.Timestamp},
+	} {
+		if *f.field == "" {
+			*f.field = f.def
+		}
+	}
+	return &ElasticsearchClient{
+		address:      address,
+		indexPattern: indexPattern,
+		username:     auth.Username,
+		password:     auth.Password,
+		apiKey:       auth.APIKey,
+		fields:       fields,
+		httpClient:   httpClient,
+	}
+}
+
+// FetchLogs returns the latest log lines of a pod in the time range, oldest first.
+func (c *ElasticsearchClient) FetchLogs(ctx context.Context, opts ElasticsearchQueryOptions) ([]LogLine, error) {
+	if opts.PodName == "" || opts.Namespace == "" {
+		return nil, fmt.Errorf("PodName and Namespace are required in options")
+	}
+
+	if opts.Limit <= 0 {
+		opts.Limit = 20
+	}
+	if opts.TimeRange <= 0 {
+		opts.TimeRange = 24 * time.Hour
+	}
+	endTime := opts.End
+	if endTime.IsZero() {
+		endTime = time.Now()
+	}
+	startTime := opts.Start
+	if startTime.IsZero() {
+		startTime = endTime.Add(-opts.TimeRange)
+	}
+
+	filter := []any{
+		map[string]any{"term": map[string]any{c.fields.Pod: opts.PodName}},
+		map[string]any{"term": map[string]any{c.fields.Namespace: opts.Namespace}},
+		map[string]any{"range": map[string]any{c.fields.Timestamp: map[string]any{
+			"gte":    startTime.UTC().Format(time.RFC3339Nano),
+			"lte":    endTime.UTC().Format(time.RFC3339Nano),
+			"format": "strict_date_optional_time",
+		}}},
+	}
+	if opts.Container != "" {
+		filter = append(filter, map[string]any{"term": map[string]any{c.fields.Container: opts.Container}})
+	}
+	query := map[string]any{
+		"size": opts.Limit,
+		"sort": []any{
+			map[string]any{c.fields.Timestamp: map[string]any{"order": "desc"}},
+		},
+		"query": map[string]any{
+			"bool": map[string]any{"filter": filter},
+		},
+	}
+	body, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	searchURL, err := url.Parse(c.address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid elasticsearch address: %w", err)
+	}
+	searchURL = searchURL.JoinPath(c.indexPattern, "_search")
+	searchURL.RawQuery = url.Values{"ignore_unavailable": {"true"}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, searchURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	switch {
+	case c.apiKey != "":
+		req.Header.Set("Authorization", "ApiKey "+c.apiKey)
+	case c.username != "":
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request to Elasticsearch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("elasticsearch returned non-200 status code: %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var esResp elasticsearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&esResp); err != nil {
+		return nil, fmt.Errorf("failed to decode Elasticsearch response: %w", err)
+	}
+
+	// Hits are sorted newest first.
+	lines := make([]LogLine, 0, len(esResp.Hits.Hits))
+	for i := len(esResp.Hits.Hits) - 1; i >= 0; i-- {
+		hit := esResp.Hits.Hits[i]
+		line := LogLine{
+			Container: opts.Container,
+			Stream:    fieldString(hit.Source, "stream"),
+			Text:      strings.TrimRight(fieldString(hit.Source, c.fields.Message), "\n"),
+		}
+		if line.Container == "" {
+			line.Container = fieldString(hit.Source, sourceField(c.fields.Container))
+		}
+		if ts, err := time.Parse(time.RFC3339Nano, fieldString(hit.Source, c.fields.Timestamp)); err == nil {
+			line.Timestamp = ts
+		}
+		lines = append(lines, line)
+	}
+
+	sortLines(lines)
+	return lines, nil
+}
+
+// ElasticsearchSource fetches logs from Elasticsearch or OpenSearch.
+type ElasticsearchSource struct {
+	client *ElasticsearchClient
+}
+
+// NewElasticsearchSource resolves the credentials and TLS options of spec and
+// creates the source.
+func NewElasticsearchSource(ctx context.Context, config Config, spec kopilotv1.ElasticsearchSource) (*ElasticsearchSource, error) {
+	secrets := config.Secrets
+	var auth ElasticsearchAuth
+	var err error
+	if spec.UsernameSecretRef != nil && spec.PasswordSecretRef != nil {
+		if auth.Username, err = secrets.GetSecret(ctx, *spec.UsernameSecretRef); err != nil {
+			return nil, err
+		}
+		if auth.Password, err = secrets.GetSecret(ctx, *spec.PasswordSecretRef); err != nil {
+			return nil, err
+		}
+	}
+	if spec.APIKeySecretRef != nil {
+		if auth.APIKey, err = secrets.GetSecret(ctx, *spec.APIKeySecretRef); err != nil {
+			return nil, err
+		}
+	}
+
+	tlsOptions, err := ResolveTLSOptions(ctx, secrets, spec.TLS)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := config.httpClient(tlsOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	client := NewElasticsearchClient(spec.Address, spec.IndexPattern, httpClient, auth, ElasticsearchFields{
+		Pod:       spec.Fields.Pod,
+		Namespace: spec.Fields.Namespace,
+		Container: spec.Fields.Container,
+		Message:   spec.Fields.Message,
+		Timestamp: spec.Fields.Timestamp,
+	})
+	return &ElasticsearchSource{client: client}, nil
+}
+
+// Fetch runs a query per container of the pod, so that TailLines bounds the
+// lines of each container, or a single query if the containers are unknown.
+func (s *ElasticsearchSource) Fetch(ctx context.Context, ref PodRef, opts Options) ([]LogLine, error) {
+	containers := ref.Containers
+	if len(containers) == 0 {
+		containers = []string{""}
+	}
+
+	var lines []LogLine
+	for _, container := range containers {
+		containerLines, err := s.client.FetchLogs(ctx, ElasticsearchQueryOptions{
+			PodName:   ref.Name,
+			Namespace: ref.Namespace,
+			Container: container,
+			Limit:     int(opts.TailLines),
+			Start:     opts.Since,
+			End:       opts.until(container),
+		})
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, containerLines...)
+	}
+	sortLines(lines)
+	return limitLines(lines, opts), nil
+}
+
+// sourceField returns the field of the document source holding a field,
+// which is the text field of a "keyword" multi-field.
+func sourceField(field string) string {
+	return strings.TrimSuffix(field, ".keyword")
+}
+
+// fieldString returns the value of a dotted field of a document, which may be
+// stored either flat ("kubernetes.pod_name") or nested.
+func fieldString(source map[string]any, field string) string {
+	if v, ok := source[field]; ok {
+		return valueString(v)
+	}
+	head, rest, found := strings.Cut(field, ".")
+	if !found {
+		return ""
+	}
+	nested, ok := source[head].(map[string]any)
+	if !ok {
+		return ""
+	}
+	return fieldString(nested, rest)
+}
+
+func valueString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,160 @@
+package logsource
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+)
+
+func TestElasticsearchSourceFetch(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	docs := map[string][]map[string]any{
+		"app": {
+			// Nested fields, as written by the Fluent Bit kubernetes filter.
+			{"@timestamp": now.Add(-time.Second).Format(time.RFC3339Nano), "log": "app 2\n", "stream": "stderr",
+				"kubernetes": map[string]any{"container_name": "app"}},
+			{"@timestamp": now.Add(-2 * time.Second).Format(time.RFC3339Nano), "log": "app 1\n", "stream": "stdout",
+				"kubernetes": map[string]any{"container_name": "app"}},
+		},
+		"sidecar": {
+			// Flat fields.
+			{"@timestamp": now.Add(-3 * time.Second).Format(time.RFC3339Nano), "log": "sidecar 1", "kubernetes.container_name": "sidecar"},
+		},
+	}
+
+	var queries []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/logs-*/_search" || r.URL.Query().Get("ignore_unavailable") != "true" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		if got := r.Header.Get("Authorization"); got != "ApiKey key" {
+			t.Errorf("Authorization = %q, want %q", got, "ApiKey key")
+		}
+		var query map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+			t.Fatal(err)
+		}
+		queries = append(queries, query)
+
+		filter := query["query"].(map[string]any)["bool"].(map[string]any)["filter"].([]any)
+		container := filter[len(filter)-1].(map[string]any)["term"].(map[string]any)["kubernetes.container_name.keyword"].(string)
+		var resp struct {
+			Hits struct {
+				Hits []map[string]any `json:"hits"`
+			} `json:"hits"`
+		}
+		for _, doc := range docs[container] {
+			resp.Hits.Hits = append(resp.Hits.Hits, map[string]any{"_source": doc})
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer server.Close()
+
+	source := &ElasticsearchSource{client: NewElasticsearchClient(server.URL, "logs-*", nil, ElasticsearchAuth{APIKey: "key"}, ElasticsearchFields{})}
+	lines, err := source.Fetch(context.Background(),
+		PodRef{Namespace: "default", Name: "web-1", Containers: []string{"app", "sidecar"}},
+		Options{TailLines: 1, Until: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(queries) != 2 {
+		t.Fatalf("got %d queries, want one per container", len(queries))
+	}
+	wantFilter := []any{
+		map[string]any{"term": map[string]any{"kubernetes.pod_name.keyword": "web-1"}},
+		map[string]any{"term": map[string]any{"kubernetes.namespace_name.keyword": "default"}},
+	}
+	for _, query := range queries {
+		if query["size"] != float64(1) {
+			t.Errorf("size = %v, want 1", query["size"])
+		}
+		filter := query["query"].(map[string]any)["bool"].(map[string]any)["filter"].([]any)
+		if !reflect.DeepEqual(filter[:2], wantFilter) {
+			t.Errorf("filter = %v, want term queries on %v", filter, wantFilter)
+		}
+	}
+
+	want := []LogLine{
+		{Timestamp: now.Add(-3 * time.Second), Container: "sidecar", Text: "sidecar 1"},
+		{Timestamp: now.Add(-time.Second), Container: "app", Stream: "stderr", Text: "app 2"},
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("Fetch() = %+v, want %+v", lines, want)
+	}
+}
+
+func TestElasticsearchClientFetchLogsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"index_not_found"}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewElasticsearchClient(server.URL, "", nil, ElasticsearchAuth{Username: "user", Password: "pass"}, ElasticsearchFields{})
+	_, err := client.FetchLogs(context.Background(), ElasticsearchQueryOptions{PodName: "web-1", Namespace: "default"})
+	if err == nil || err.Error() != `elasticsearch returned non-200 status code: 404: {"error":"index_not_found"}` {
+		t.Errorf("FetchLogs() error = %v", err)
+	}
+}
+
+type secretMap map[string]string
+
+func (s secretMap) GetSecret(_ context.Context, ref kopilotv1.SecretKeyRef) (string, error) {
+	value, ok := s[ref.Name+"/"+ref.Key]
+	if !ok {
+		return "", fmt.Errorf("secret %s/%s not found", ref.Name, ref.Key)
+	}
+	return value, nil
+}
+
+func TestElasticsearchSourceTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"hits":{"hits":[{"_source":{"@timestamp":"2025-01-01T12:00:00Z","log":"started"}}]}}`)
+	}))
+	// The handshake without the CA fails on purpose.
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	defer server.Close()
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	var pool ClientPool
+	config := Config{Key: "default/kopilot", Secrets: secretMap{"es-tls/ca.crt": string(ca)}, Clients: &pool}
+	spec := kopilotv1.ElasticsearchSource{
+		Address: server.URL,
+		TLS:     &kopilotv1.TLSConfig{CASecretRef: &kopilotv1.SecretKeyRef{Name: "es-tls", Key: "ca.crt"}},
+	}
+	source, err := NewElasticsearchSource(context.Background(), config, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines, err := source.Fetch(context.Background(), PodRef{Namespace: "default", Name: "web-1"}, Options{TailLines: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || lines[0].Text != "started" {
+		t.Errorf("Fetch() = %+v, want the line of the TLS server", lines)
+	}
+	if client, _ := pool.Get(config.Key, TLSOptions{CA: ca}); client != source.client.httpClient {
+		t.Error("source does not use the pooled client")
+	}
+
+	// Without the CA the server certificate is not trusted.
+	source, err = NewElasticsearchSource(context.Background(), Config{}, kopilotv1.ElasticsearchSource{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Fetch(context.Background(), PodRef{Namespace: "default", Name: "web-1"}, Options{TailLines: 10}); err == nil {
+		t.Error("Fetch() succeeded without the CA")
+	}
+}
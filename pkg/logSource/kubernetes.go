@@ -0,0 +1,114 @@
+package logsource
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func init() {
+	Register("Kubernetes", func(_ context.Context, config Config) (LogSource, error) {
+		if config.Clientset == nil {
+			return nil, fmt.Errorf("a kubernetes clientset is required")
+		}
+		return NewKubernetesSource(config.Clientset), nil
+	})
+}
+
+// KubernetesSource fetches logs from the Kubernetes API. It fetches the logs
+// of all started containers, including init and sidecar containers, and of the
+// previous instance of restarted containers.
+type KubernetesSource struct {
+	clientset kubernetes.Interface
+}
+
+func NewKubernetesSource(clientset kubernetes.Interface) *KubernetesSource {
+	return &KubernetesSource{clientset: clientset}
+}
+
+func (k *KubernetesSource) Fetch(ctx context.Context, ref PodRef, opts Options) ([]LogLine, error) {
+	pod, err := k.clientset.CoreV1().Pods(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pod: %w", err)
+	}
+
+	var lines []LogLine
+	var firstErr error
+	requests := 0
+	fetch := func(container string, previous bool) {
+		requests++
+		containerLines, err := k.fetchContainerLogs(ctx, pod, container, previous, opts)
+		if err != nil {
+			zap.L().Error("Failed to fetch container logs", zap.String("pod", pod.Name), zap.String("container", container), zap.Bool("previous", previous), zap.Error(err))
+			if firstErr == nil {
+				firstErr = err
+			}
+			return
+		}
+		lines = append(lines, containerLines...)
+	}
+
+	for _, containerStatus := range slices.Concat(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses) {
+		if containerStatus.LastTerminationState.Terminated != nil {
+			fetch(containerStatus.Name, true)
+		}
+		if containerStatus.State.Running != nil || containerStatus.State.Terminated != nil {
+			fetch(containerStatus.Name, false)
+		}
+	}
+
+	if requests > 0 && len(lines) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return limitLines(lines, opts), nil
+}
+
+func (k *KubernetesSource) fetchContainerLogs(ctx context.Context, pod *corev1.Pod, container string, previous bool, opts Options) ([]LogLine, error) {
+	podLogOptions := &corev1.PodLogOptions{
+		Container:  container,
+		Previous:   previous,
+		Timestamps: true,
+	}
+	if opts.TailLines > 0 {
+		podLogOptions.TailLines = &opts.TailLines
+	}
+	if !opts.Since.IsZero() {
+		since := metav1.NewTime(opts.Since)
+		podLogOptions.SinceTime = &since
+	}
+
+	podLogs, err := k.clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, podLogOptions).Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := podLogs.Close(); err != nil {
+			zap.L().Error("Error closing podLogs", zap.Error(err))
+		}
+	}()
+
+	var lines []LogLine
+	scanner := bufio.NewScanner(podLogs)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := LogLine{Container: container, Previous: previous, Text: scanner.Text()}
+		if ts, text, found := strings.Cut(line.Text, " "); found {
+			if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+				line.Timestamp, line.Text = t, text
+			}
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
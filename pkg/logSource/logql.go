@@ -0,0 +1,207 @@
+package logsource
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// LogQLVars are the values of the variables of a LogQL query template.
+type LogQLVars struct {
+	Pod       string
+	Namespace string
+	Container string
+}
+
+var logQLVariable = regexp.MustCompile(`\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+
+// UsesContainerVariable reports whether the query template has to be rendered per container.
+func UsesContainerVariable(template string) bool {
+	return strings.Contains(template, "{container}")
+}
+
+// RenderLogQLQuery replaces the {pod}, {namespace} and {container} variables of
+// a LogQL query template. Values are escaped for the string literal they appear
+// in, and additionally regex-escaped when used with the =~ and !~ matchers.
+func RenderLogQLQuery(template string, vars LogQLVars) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range logQLVariable.FindAllStringSubmatchIndex(template, -1) {
+		var value string
+		switch template[m[2]:m[3]] {
+		case "pod":
+			value = vars.Pod
+		case "namespace":
+			value = vars.Namespace
+		case "container":
+			value = vars.Container
+		default:
+			continue
+		}
+		b.WriteString(template[last:m[0]])
+		b.WriteString(escapeLogQLValue(template[:m[0]], value))
+		last = m[1]
+	}
+	b.WriteString(template[last:])
+	return b.String()
+}
+
+// escapeLogQLValue escapes value for the position right after prefix.
+func escapeLogQLValue(prefix, value string) string {
+	quote, opening, ok := openStringLiteral(prefix)
+	if !ok {
+		// Not inside a string literal, e.g. a misplaced variable. Validation
+		// rejects such templates, so only keep the value from breaking the query.
+		return strings.NewReplacer(`"`, "", "`", "", "{", "", "}", "").Replace(value)
+	}
+
+	operator := strings.TrimRight(prefix[:opening], " \t")
+	if strings.HasSuffix(operator, "=~") || strings.HasSuffix(operator, "!~") || strings.HasSuffix(operator, "|~") {
+		value = regexp.QuoteMeta(value)
+	}
+	if quote == '`' {
+		// Raw strings cannot escape a backtick.
+		return strings.ReplaceAll(value, "`", "")
+	}
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+}
+
+// openStringLiteral reports whether s ends inside a string literal, and returns
+// its quote character and the position of the opening quote.
+func openStringLiteral(s string) (byte, int, bool) {
+	var quote byte
+	opening := -1
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == 0 && (c == '"' || c == '`'):
+			quote, opening = c, i
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote, opening = 0, -1
+		}
+	}
+	return quote, opening, quote != 0
+}
+
+// ValidateLogQLQuery validates a LogQL query template. It returns warnings for
+// templates that are valid but likely not intended.
+func ValidateLogQLQuery(template string) ([]string, error) {
+	for _, m := range logQLVariable.FindAllStringSubmatchIndex(template, -1) {
+		name := template[m[2]:m[3]]
+		if name != "pod" && name != "namespace" && name != "container" {
+			return nil, fmt.Errorf("unknown variable {%s}, supported variables are {pod}, {namespace} and {container}", name)
+		}
+		if _, _, ok := openStringLiteral(template[:m[0]]); !ok {
+			return nil, fmt.Errorf("variable {%s} must be used inside a string literal, e.g. pod=\"{%s}\"", name, name)
+		}
+	}
+
+	query := RenderLogQLQuery(template, LogQLVars{Pod: "pod", Namespace: "namespace", Container: "container"})
+	if err := validateLogQL(query); err != nil {
+		return nil, err
+	}
+
+	var warnings []string
+	if !strings.Contains(template, "{pod}") {
+		warnings = append(warnings, "logqlQuery does not use the {pod} variable, the logs of other pods may be analyzed")
+	}
+	return warnings, nil
+}
+
+var logQLMatcher = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(=~|!~|!=|=)\s*`)
+
+// validateLogQL checks the stream selector of a LogQL query and that the
+// string literals, braces and parentheses of the pipeline are balanced.
+func validateLogQL(query string) error {
+	rest := strings.TrimSpace(query)
+	if !strings.HasPrefix(rest, "{") {
+		return fmt.Errorf("logqlQuery must start with a stream selector, e.g. {namespace=\"{namespace}\",pod=\"{pod}\"}")
+	}
+	rest = rest[1:]
+
+	for {
+		m := logQLMatcher.FindStringSubmatch(rest)
+		if m == nil {
+			return fmt.Errorf("invalid label matcher in stream selector near %q", truncateQuery(rest))
+		}
+		rest = rest[len(m[0]):]
+
+		value, n, err := readStringLiteral(rest)
+		if err != nil {
+			return err
+		}
+		rest = strings.TrimLeft(rest[n:], " \t")
+		if m[2] == "=~" || m[2] == "!~" {
+			if _, err := regexp.Compile(value); err != nil {
+				return fmt.Errorf("invalid regular expression for label %s: %w", m[1], err)
+			}
+		}
+
+		if strings.HasPrefix(rest, ",") {
+			rest = rest[1:]
+			continue
+		}
+		if strings.HasPrefix(rest, "}") {
+			rest = rest[1:]
+			break
+		}
+		return fmt.Errorf("expected ',' or '}' in stream selector near %q", truncateQuery(rest))
+	}
+
+	depth := 0
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '"', '`':
+			_, n, err := readStringLiteral(rest)
+			if err != nil {
+				return err
+			}
+			rest = rest[n:]
+			continue
+		case '(', '{':
+			depth++
+		case ')', '}':
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("unbalanced %q in logqlQuery", rest[0])
+			}
+		}
+		rest = rest[1:]
+	}
+	if depth != 0 {
+		return fmt.Errorf("unbalanced parentheses or braces in logqlQuery")
+	}
+	return nil
+}
+
+// readStringLiteral reads the string literal at the start of s and returns its
+// value and length.
+func readStringLiteral(s string) (string, int, error) {
+	if s == "" || (s[0] != '"' && s[0] != '`') {
+		return "", 0, fmt.Errorf("expected a string literal near %q", truncateQuery(s))
+	}
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if quote == '"' && c == '\\' && i+1 < len(s) {
+			i++
+			b.WriteByte(s[i])
+			continue
+		}
+		if c == quote {
+			return b.String(), i + 1, nil
+		}
+		b.WriteByte(c)
+	}
+	return "", 0, fmt.Errorf("unterminated string literal in logqlQuery")
+}
+
+func truncateQuery(s string) string {
+	if len(s) > 32 {
+		return s[:32] + "..."
+	}
+	return s
+}
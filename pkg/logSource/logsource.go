@@ -0,0 +1,164 @@
+package logsource
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// LogSource fetches the logs of a pod.
+type LogSource interface {
+	// Fetch returns the log lines of the pod, oldest first per container.
+	Fetch(ctx context.Context, pod PodRef, opts Options) ([]LogLine, error)
+}
+
+// PodRef identifies a pod.
+type PodRef struct {
+	Namespace string
+	Name      string
+	// Containers are the names of the init and regular containers of the pod.
+	Containers []string
+}
+
+// Options bound the logs fetched for a pod.
+type Options struct {
+	// TailLines is the maximum number of lines per container.
+	TailLines int64
+	// MaxBytes caps the size of the lines per container, keeping the most recent lines.
+	MaxBytes int64
+	// Since and Until bound the time range of the logs. A zero Since fetches
+	// the latest TailLines lines, a zero Until ends the range now. Until only
+	// ends the logs of the previous instance of Container, the failing
+	// container; sources that do not tell the instances apart end all logs of
+	// the container. The other containers keep their logs until now.
+	Since     time.Time
+	Until     time.Time
+	Container string
+}
+
+// until returns the end of the time range of a container, or of the whole pod
+// if container is empty.
+func (opts Options) until(container string) time.Time {
+	if container == "" || opts.Container == "" || container == opts.Container {
+		return opts.Until
+	}
+	return time.Time{}
+}
+
+// LogLine is a structured log line.
+type LogLine struct {
+	Timestamp time.Time
+	Container string
+	// Stream is "stdout" or "stderr" if known.
+	Stream string
+	Text   string
+	// Previous is set for lines of the previous instance of a restarted container.
+	Previous bool
+	// Count is the number of lines the line stands for after preprocessing,
+	// e.g. collapsed duplicates. 0 and 1 both stand for a single line.
+	Count int
+	// Template is set if Text is the template of a cluster of similar lines.
+	Template bool
+}
+
+// SecretGetter resolves secret references.
+type SecretGetter interface {
+	GetSecret(ctx context.Context, ref kopilotv1.SecretKeyRef) (string, error)
+}
+
+// Config is passed to the factories of log sources.
+type Config struct {
+	Spec      kopilotv1.LogSourceSpec
+	Clientset kubernetes.Interface
+	Secrets   SecretGetter
+	// Key identifies the owner of the log source in Clients.
+	Key string
+	// Clients pools the HTTP clients of log sources. If nil, a new client is
+	// created for every log source.
+	Clients *ClientPool
+}
+
+// Factory creates a log source.
+type Factory func(ctx context.Context, config Config) (LogSource, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register makes a log source available for the LogSourceSpec type name.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("log source %q is already registered", name))
+	}
+	registry[name] = factory
+}
+
+// New creates the log source of config.Spec.
+func New(ctx context.Context, config Config) (LogSource, error) {
+	registryMu.RLock()
+	factory, ok := registry[config.Spec.Type]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown log source type: %s", config.Spec.Type)
+	}
+	return factory(ctx, config)
+}
+
+// limitLines applies the per container limits of opts to lines sorted oldest
+// first per container.
+func limitLines(lines []LogLine, opts Options) []LogLine {
+	type group struct {
+		container string
+		previous  bool
+	}
+	count := map[group]int64{}
+	size := map[group]int64{}
+	full := map[group]bool{}
+	keep := make([]bool, len(lines))
+	for i := len(lines) - 1; i >= 0; i-- {
+		g := group{lines[i].Container, lines[i].Previous}
+		if full[g] {
+			continue
+		}
+		// The current instance of a restarted container started after Until.
+		if until := opts.until(lines[i].Container); lines[i].Previous && !until.IsZero() &&
+			!lines[i].Timestamp.IsZero() && lines[i].Timestamp.After(until) {
+			continue
+		}
+		lineSize := int64(len(lines[i].Text)) + 1
+		if opts.MaxBytes > 0 && size[g]+lineSize > opts.MaxBytes {
+			full[g] = true
+			continue
+		}
+		count[g]++
+		size[g] += lineSize
+		keep[i] = true
+		if opts.TailLines > 0 && count[g] >= opts.TailLines {
+			full[g] = true
+		}
+	}
+
+	result := make([]LogLine, 0, len(lines))
+	for i, line := range lines {
+		if keep[i] {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// sortLines sorts lines oldest first, keeping the order of lines with the same
+// timestamp.
+func sortLines(lines []LogLine) {
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Timestamp.Before(lines[j].Timestamp)
+	})
+}
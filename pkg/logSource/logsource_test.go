@@ -0,0 +1,76 @@
+package logsource
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLimitLines(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	line := func(container string, offset time.Duration, text string) LogLine {
+		return LogLine{Container: container, Timestamp: now.Add(offset), Text: text}
+	}
+	previous := func(line LogLine) LogLine {
+		line.Previous = true
+		return line
+	}
+
+	tests := []struct {
+		name  string
+		lines []LogLine
+		opts  Options
+		want  []LogLine
+	}{
+		{
+			name:  "tail lines per container",
+			lines: []LogLine{line("app", -3, "a1"), line("app", -2, "a2"), line("sidecar", -2, "s1"), line("app", -1, "a3")},
+			opts:  Options{TailLines: 2},
+			want:  []LogLine{line("app", -2, "a2"), line("sidecar", -2, "s1"), line("app", -1, "a3")},
+		},
+		{
+			name: "until ends the previous instance of the failing container",
+			lines: []LogLine{
+				previous(line("app", -time.Minute, "before crash")),
+				previous(line("app", 0, "crash")),
+				previous(line("app", time.Minute, "shipped after the window")),
+				previous(line("sidecar", time.Minute, "previous sidecar")),
+				line("app", 2*time.Minute, "restarted"),
+				line("sidecar", 2*time.Minute, "sidecar"),
+			},
+			opts: Options{Until: now, Container: "app"},
+			want: []LogLine{
+				previous(line("app", -time.Minute, "before crash")),
+				previous(line("app", 0, "crash")),
+				previous(line("sidecar", time.Minute, "previous sidecar")),
+				line("app", 2*time.Minute, "restarted"),
+				line("sidecar", 2*time.Minute, "sidecar"),
+			},
+		},
+		{
+			name:  "lines without a timestamp are kept",
+			lines: []LogLine{previous(LogLine{Container: "app", Text: "no timestamp"})},
+			opts:  Options{Until: now, Container: "app"},
+			want:  []LogLine{previous(LogLine{Container: "app", Text: "no timestamp"})},
+		},
+		{
+			name:  "most recent whole lines within max bytes",
+			lines: []LogLine{line("app", -3, "aaaa"), line("app", -2, "bbbb"), line("app", -1, "cccc")},
+			opts:  Options{MaxBytes: 10},
+			want:  []LogLine{line("app", -2, "bbbb"), line("app", -1, "cccc")},
+		},
+		{
+			name:  "no older lines after a line exceeding max bytes",
+			lines: []LogLine{line("app", -3, "a"), line("app", -2, "too long for the limit"), line("app", -1, "b")},
+			opts:  Options{MaxBytes: 10},
+			want:  []LogLine{line("app", -1, "b")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := limitLines(tt.lines, tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("limitLines() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
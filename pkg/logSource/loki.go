@@ -1,16 +1,32 @@
 package logsource
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
-	"strings"
+	"slices"
+	"strconv"
 	"time"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
 )
 
+func init() {
+	Register("Loki", func(ctx context.Context, config Config) (LogSource, error) {
+		if config.Spec.Loki == nil {
+			return nil, fmt.Errorf("loki must be set when the log source type is Loki")
+		}
+		return NewLokiSource(ctx, config, *config.Spec.Loki)
+	})
+}
+
 type LokiClient struct {
-	address string
+	address    string
+	httpClient *http.Client
+	headers    http.Header
 }
 
 type LokiResponse struct {
@@ -29,20 +45,33 @@ type LokiResult struct {
 }
 
 type LokiQueryOptions struct {
+	// Query is a rendered LogQL query. If empty, the logs of the pod are
+	// selected by the namespace and pod labels.
+	Query     string
 	PodName   string
 	Namespace string
 	Filter    string
 	Limit     int
+	// Start and End bound the queried time range. End defaults to now and
+	// Start to End minus TimeRange.
+	Start     time.Time
+	End       time.Time
 	TimeRange time.Duration
 }
 
-func NewLokiClient(address string) *LokiClient {
-	return &LokiClient{address: address}
+// NewLokiClient creates a Loki client. The headers, e.g. for authentication,
+// are sent with every request.
+func NewLokiClient(address string, httpClient *http.Client, headers http.Header) *LokiClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &LokiClient{address: address, httpClient: httpClient, headers: headers}
 }
 
-func (l *LokiClient) FetchLogs(opts LokiQueryOptions) (string, error) {
-	if opts.PodName == "" || opts.Namespace == "" {
-		return "", fmt.Errorf("PodName and Namespace are required in options")
+// FetchLogs returns the latest log lines matching the query, oldest first.
+func (l *LokiClient) FetchLogs(ctx context.Context, opts LokiQueryOptions) ([]LogLine, error) {
+	if opts.Query == "" && (opts.PodName == "" || opts.Namespace == "") {
+		return nil, fmt.Errorf("PodName and Namespace are required in options")
 	}
 
 	if opts.Limit <= 0 {
@@ -52,18 +81,26 @@ func (l *LokiClient) FetchLogs(opts LokiQueryOptions) (string, error) {
 		opts.TimeRange = 700 * time.Hour
 	}
 
-	streamSelector := fmt.Sprintf(`{namespace="%s",pod="%s"}`, opts.Namespace, opts.PodName)
-	finalQuery := streamSelector
+	finalQuery := opts.Query
+	if finalQuery == "" {
+		finalQuery = RenderLogQLQuery(`{namespace="{namespace}",pod="{pod}"}`, LogQLVars{Pod: opts.PodName, Namespace: opts.Namespace})
+	}
 	if opts.Filter != "" {
-		finalQuery = fmt.Sprintf("%s %s", streamSelector, opts.Filter)
+		finalQuery = fmt.Sprintf("%s %s", finalQuery, opts.Filter)
 	}
 	lokiURL, err := url.Parse(l.address)
 	if err != nil {
-		return "", fmt.Errorf("invalid loki address: %w", err)
+		return nil, fmt.Errorf("invalid loki address: %w", err)
 	}
 	lokiURL.Path = "/loki/api/v1/query_range"
-	endTime := time.Now()
-	startTime := endTime.Add(-opts.TimeRange)
+	endTime := opts.End
+	if endTime.IsZero() {
+		endTime = time.Now()
+	}
+	startTime := opts.Start
+	if startTime.IsZero() {
+		startTime = endTime.Add(-opts.TimeRange)
+	}
 
 	params := url.Values{}
 	params.Add("query", finalQuery)
@@ -73,40 +110,162 @@ func (l *LokiClient) FetchLogs(opts LokiQueryOptions) (string, error) {
 	params.Add("end", endTime.Format(time.RFC3339Nano))
 
 	lokiURL.RawQuery = params.Encode()
-	client := &http.Client{Timeout: 30 * time.Second}
-	req, err := http.NewRequest("GET", lokiURL.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, lokiURL.String(), nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range l.headers {
+		req.Header[name] = values
 	}
 
-	resp, err := client.Do(req)
+	resp, err := l.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to execute request to Loki: %w", err)
+		return nil, fmt.Errorf("failed to execute request to Loki: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("loki returned non-200 status code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("loki returned non-200 status code: %d", resp.StatusCode)
 	}
 
 	var lokiResp LokiResponse
 	if err := json.NewDecoder(resp.Body).Decode(&lokiResp); err != nil {
-		return "", fmt.Errorf("failed to decode Loki response: %w", err)
+		return nil, fmt.Errorf("failed to decode Loki response: %w", err)
 	}
 
 	if lokiResp.Status != "success" {
-		return "", fmt.Errorf("loki query failed with status: %s", lokiResp.Status)
+		return nil, fmt.Errorf("loki query failed with status: %s", lokiResp.Status)
 	}
 
-	var collectedLogs []string
+	var lines []LogLine
 	for _, result := range lokiResp.Data.Result {
+		container := result.Stream["container"]
+		stream := result.Stream["stream"]
 		for _, valuePair := range result.Values {
-			collectedLogs = append(collectedLogs, valuePair[1])
+			if len(valuePair) < 2 {
+				continue
+			}
+			line := LogLine{Container: container, Stream: stream, Text: valuePair[1]}
+			if ns, err := strconv.ParseInt(valuePair[0], 10, 64); err == nil {
+				line.Timestamp = time.Unix(0, ns)
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	// Streams are returned newest first.
+	slices.Reverse(lines)
+	sortLines(lines)
+	return lines, nil
+}
+
+// LokiSource fetches logs from Loki with a LogQL query template.
+type LokiSource struct {
+	client   *LokiClient
+	template string
+}
+
+// NewLokiSource resolves the credentials, headers and TLS options of spec and
+// creates the source with the pooled HTTP client of config.
+func NewLokiSource(ctx context.Context, config Config, spec kopilotv1.LokiSource) (*LokiSource, error) {
+	headers := http.Header{}
+	for _, header := range spec.Headers {
+		value := header.Value
+		if header.ValueSecretRef != nil {
+			var err error
+			if value, err = config.Secrets.GetSecret(ctx, *header.ValueSecretRef); err != nil {
+				return nil, err
+			}
 		}
+		headers.Set(header.Name, value)
+	}
+	if spec.TenantID != "" {
+		headers.Set("X-Scope-OrgID", spec.TenantID)
+	}
+	if spec.Auth != nil {
+		authorization, err := lokiAuthorization(ctx, config.Secrets, *spec.Auth)
+		if err != nil {
+			return nil, err
+		}
+		headers.Set("Authorization", authorization)
 	}
 
-	for i, j := 0, len(collectedLogs)-1; i < j; i, j = i+1, j-1 {
-		collectedLogs[i], collectedLogs[j] = collectedLogs[j], collectedLogs[i]
+	tlsOptions, err := ResolveTLSOptions(ctx, config.Secrets, spec.TLS)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := config.httpClient(tlsOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LokiSource{client: NewLokiClient(spec.Address, httpClient, headers), template: spec.LogQLQuery}, nil
+}
+
+func lokiAuthorization(ctx context.Context, secrets SecretGetter, auth kopilotv1.LokiAuth) (string, error) {
+	switch auth.Type {
+	case "basic":
+		if auth.UsernameSecretRef == nil || auth.PasswordSecretRef == nil {
+			return "", fmt.Errorf("usernameSecretRef and passwordSecretRef are required for basic auth")
+		}
+		username, err := secrets.GetSecret(ctx, *auth.UsernameSecretRef)
+		if err != nil {
+			return "", err
+		}
+		password, err := secrets.GetSecret(ctx, *auth.PasswordSecretRef)
+		if err != nil {
+			return "", err
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password)), nil
+	case "bearer":
+		if auth.TokenSecretRef == nil {
+			return "", fmt.Errorf("tokenSecretRef is required for bearer auth")
+		}
+		token, err := secrets.GetSecret(ctx, *auth.TokenSecretRef)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	default:
+		return "", fmt.Errorf("unknown loki auth type: %s", auth.Type)
+	}
+}
+
+// Fetch runs the query once per container if the template uses the
+// {container} variable, and once for the pod otherwise.
+func (s *LokiSource) Fetch(ctx context.Context, ref PodRef, opts Options) ([]LogLine, error) {
+	if s.template == "" || !UsesContainerVariable(s.template) {
+		lines, err := s.fetch(ctx, LogQLVars{Pod: ref.Name, Namespace: ref.Namespace}, opts)
+		if err != nil {
+			return nil, err
+		}
+		return limitLines(lines, opts), nil
+	}
+
+	var lines []LogLine
+	for _, container := range ref.Containers {
+		containerLines, err := s.fetch(ctx, LogQLVars{Pod: ref.Name, Namespace: ref.Namespace, Container: container}, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range containerLines {
+			containerLines[i].Container = container
+		}
+		lines = append(lines, containerLines...)
+	}
+	return limitLines(lines, opts), nil
+}
+
+func (s *LokiSource) fetch(ctx context.Context, vars LogQLVars, opts Options) ([]LogLine, error) {
+	queryOpts := LokiQueryOptions{
+		PodName:   vars.Pod,
+		Namespace: vars.Namespace,
+		Limit:     int(opts.TailLines),
+		Start:     opts.Since,
+		End:       opts.until(vars.Container),
+	}
+	if s.template != "" {
+		queryOpts.Query = RenderLogQLQuery(s.template, vars)
 	}
-	return strings.Join(collectedLogs, "\n"), nil
+	return s.client.FetchLogs(ctx, queryOpts)
 }
@@ -0,0 +1,107 @@
+package preprocess
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const wildcard = "<*>"
+
+// logCluster is a cluster of similar lines with a common template.
+type logCluster struct {
+	template []string
+	// last is the position of the last line of the cluster.
+	last    int
+	count   int
+	members int
+}
+
+// cluster groups similar lines in the style of Drain: lines are split into
+// tokens, tokens with digits are treated as parameters, and a line joins the
+// most similar cluster with the same number of tokens and the same first token.
+// Clusters of at least minSize lines are replaced by their template at the
+// position of their last line, so that the lines between the members keep
+// their order and the template stands for the latest occurrence.
+func cluster(group []entry, similarity float64, minSize int) []entry {
+	var clusters []*logCluster
+	buckets := map[string][]*logCluster{}
+	assigned := make([]*logCluster, len(group))
+
+	for i, e := range group {
+		if e.pinned {
+			continue
+		}
+		tokens := tokenize(e.Text)
+		if len(tokens) == 0 {
+			continue
+		}
+		key := bucketKey(tokens)
+
+		var best *logCluster
+		bestSim := -1.0
+		for _, c := range buckets[key] {
+			if sim := tokenSimilarity(c.template, tokens); sim >= similarity && sim > bestSim {
+				best, bestSim = c, sim
+			}
+		}
+		if best == nil {
+			best = &logCluster{template: tokens}
+			buckets[key] = append(buckets[key], best)
+			clusters = append(clusters, best)
+		} else {
+			for j, token := range tokens {
+				if best.template[j] != token {
+					best.template[j] = wildcard
+				}
+			}
+		}
+		best.last = i
+		best.count += e.Count
+		best.members++
+		assigned[i] = best
+	}
+
+	result := make([]entry, 0, len(group))
+	for i, e := range group {
+		c := assigned[i]
+		if c == nil || c.count < minSize || c.members < 2 {
+			result = append(result, e)
+			continue
+		}
+		if c.last != i {
+			continue
+		}
+		e.Text = strings.Join(c.template, " ")
+		e.Count = c.count
+		e.Template = true
+		result = append(result, e)
+	}
+	return result
+}
+
+func tokenize(text string) []string {
+	tokens := strings.Fields(text)
+	for i, token := range tokens {
+		if strings.IndexFunc(token, unicode.IsDigit) >= 0 {
+			tokens[i] = wildcard
+		}
+	}
+	return tokens
+}
+
+func bucketKey(tokens []string) string {
+	return strconv.Itoa(len(tokens)) + " " + tokens[0]
+}
+
+// tokenSimilarity is the fraction of tokens equal to the non-wildcard tokens
+// of the template.
+func tokenSimilarity(template, tokens []string) float64 {
+	equal := 0
+	for i, token := range template {
+		if token != wildcard && token == tokens[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(tokens))
+}
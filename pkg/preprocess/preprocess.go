@@ -0,0 +1,349 @@
+package preprocess
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	logsource "github.com/Fl0rencess720/Kopilot/pkg/logSource"
+)
+
+// DefaultErrorPattern matches error lines if no pattern is configured.
+const DefaultErrorPattern = `(?i)\b(error|exception|fatal|panic|fail(ed|ure)?|traceback)\b`
+
+const (
+	defaultMaxTokens      = 4000
+	defaultSimilarity     = 0.5
+	defaultMinClusterSize = 3
+	// minLineTokens is the least a single line may be truncated to.
+	minLineTokens = 50
+	// minPinnedTokens is the least a pinned line may be truncated to before
+	// it is unpinned to fit the budget.
+	minPinnedTokens = 10
+
+	truncatedSuffix = " …[truncated]"
+)
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+type options struct {
+	stripANSI      bool
+	keepErrors     bool
+	errorPattern   *regexp.Regexp
+	deduplicate    bool
+	clustering     bool
+	similarity     float64
+	minClusterSize int
+	maxTokens      int
+}
+
+func newOptions(spec *kopilotv1.PreprocessingSpec) (options, error) {
+	if spec == nil {
+		spec = &kopilotv1.PreprocessingSpec{StripANSI: true, KeepErrors: true, Deduplicate: true, MaxTokens: defaultMaxTokens}
+	}
+	opts := options{
+		stripANSI:   spec.StripANSI,
+		keepErrors:  spec.KeepErrors,
+		deduplicate: spec.Deduplicate,
+		maxTokens:   int(spec.MaxTokens),
+	}
+
+	pattern := spec.ErrorPattern
+	if pattern == "" {
+		pattern = DefaultErrorPattern
+	}
+	var err error
+	if opts.errorPattern, err = regexp.Compile(pattern); err != nil {
+		return options{}, fmt.Errorf("invalid error pattern: %w", err)
+	}
+
+	if c := spec.Clustering; c != nil {
+		opts.clustering = true
+		opts.similarity = defaultSimilarity
+		if c.SimilarityThreshold != "" {
+			if opts.similarity, err = strconv.ParseFloat(c.SimilarityThreshold, 64); err != nil {
+				return options{}, fmt.Errorf("invalid similarity threshold: %w", err)
+			}
+		}
+		opts.minClusterSize = defaultMinClusterSize
+		if c.MinClusterSize > 0 {
+			opts.minClusterSize = int(c.MinClusterSize)
+		}
+	}
+	return opts, nil
+}
+
+// entry is a line being processed. Pinned lines are kept, unless they do not
+// fit the token budget.
+type entry struct {
+	logsource.LogLine
+	pinned bool
+}
+
+// Process applies the preprocessing steps of spec to lines. The lines of each
+// container instance are processed separately, and the token budget is shared
+// between them. A nil spec applies the defaults.
+func Process(lines []logsource.LogLine, spec *kopilotv1.PreprocessingSpec) ([]logsource.LogLine, error) {
+	opts, err := newOptions(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := splitGroups(lines)
+	for i, group := range groups {
+		groups[i] = processGroup(group, opts)
+	}
+	if opts.maxTokens > 0 {
+		capTokens(groups, opts.maxTokens)
+	}
+
+	result := make([]logsource.LogLine, 0, len(lines))
+	for _, group := range groups {
+		for _, e := range group {
+			result = append(result, e.LogLine)
+		}
+	}
+	return result, nil
+}
+
+// splitGroups splits lines by container instance, in order of appearance.
+func splitGroups(lines []logsource.LogLine) [][]entry {
+	type key struct {
+		container string
+		previous  bool
+	}
+	var groups [][]entry
+	index := map[key]int{}
+	for _, line := range lines {
+		k := key{line.Container, line.Previous}
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, nil)
+		}
+		if line.Count < 1 {
+			line.Count = 1
+		}
+		groups[i] = append(groups[i], entry{LogLine: line})
+	}
+	return groups
+}
+
+func processGroup(group []entry, opts options) []entry {
+	if opts.stripANSI {
+		for i := range group {
+			group[i].Text = strings.TrimRight(ansiEscape.ReplaceAllString(group[i].Text, ""), "\r")
+		}
+	}
+
+	if opts.keepErrors {
+		first, last := -1, -1
+		for i := range group {
+			if opts.errorPattern.MatchString(group[i].Text) {
+				if first < 0 {
+					first = i
+				}
+				last = i
+			}
+		}
+		if first >= 0 {
+			group[first].pinned = true
+			group[last].pinned = true
+		}
+	}
+
+	if opts.deduplicate {
+		group = deduplicate(group)
+	}
+	if opts.clustering {
+		group = cluster(group, opts.similarity, opts.minClusterSize)
+	}
+	return group
+}
+
+// deduplicate collapses identical lines into their first occurrence.
+func deduplicate(group []entry) []entry {
+	result := make([]entry, 0, len(group))
+	seen := map[string]int{}
+	for _, e := range group {
+		if e.pinned {
+			result = append(result, e)
+			continue
+		}
+		if i, ok := seen[e.Text]; ok {
+			result[i].Count += e.Count
+			continue
+		}
+		seen[e.Text] = len(result)
+		result = append(result, e)
+	}
+	return result
+}
+
+// capTokens drops the oldest lines of each group that exceed its share of the
+// token budget. Pinned lines are kept, and each gap is replaced by a marker.
+// Pinned lines that exceed the share together are truncated or unpinned.
+func capTokens(groups [][]entry, maxTokens int) {
+	costs := make([]int, len(groups))
+	order := make([]int, len(groups))
+	for i, group := range groups {
+		for _, e := range group {
+			costs[i] += lineTokens(e)
+		}
+		order[i] = i
+	}
+
+	// Groups below their share leave the rest of it to the larger groups.
+	sort.Slice(order, func(a, b int) bool { return costs[order[a]] < costs[order[b]] })
+	remaining := maxTokens
+	for n, i := range order {
+		budget := remaining / (len(order) - n)
+		if costs[i] > budget {
+			groups[i] = capGroup(groups[i], budget)
+			costs[i] = budget
+		}
+		remaining -= costs[i]
+	}
+}
+
+func capGroup(group []entry, budget int) []entry {
+	// Each pinned line and the kept tail are at most surrounded by a marker.
+	pinned := 0
+	for _, e := range group {
+		if e.pinned {
+			pinned++
+		}
+	}
+	budget = max(budget-(pinned+2)*markerTokens, 0)
+
+	maxLine := max(budget/4, minLineTokens)
+	for i := range group {
+		group[i].Text = truncateTokens(group[i].Text, maxLine)
+	}
+	fitPinned(group, budget)
+
+	keep := make([]bool, len(group))
+	used := 0
+	for i, e := range group {
+		if e.pinned {
+			keep[i] = true
+			used += lineTokens(e)
+		}
+	}
+	for i := len(group) - 1; i >= 0; i-- {
+		if keep[i] {
+			continue
+		}
+		cost := lineTokens(group[i])
+		if used+cost > budget {
+			break
+		}
+		keep[i] = true
+		used += cost
+	}
+
+	result := make([]entry, 0, len(group))
+	omitted := 0
+	for i, e := range group {
+		if !keep[i] {
+			omitted += e.Count
+			continue
+		}
+		if omitted > 0 {
+			result = append(result, omittedMarker(e.LogLine, omitted))
+			omitted = 0
+		}
+		result = append(result, e)
+	}
+	if omitted > 0 && len(group) > 0 {
+		result = append(result, omittedMarker(group[len(group)-1].LogLine, omitted))
+	}
+	return result
+}
+
+// fitPinned truncates the pinned lines to an equal share of the budget if
+// they exceed it together. If the share is too small, the oldest pinned lines
+// are unpinned until the others fit.
+func fitPinned(group []entry, budget int) {
+	var pinned []int
+	cost := 0
+	for i, e := range group {
+		if e.pinned {
+			pinned = append(pinned, i)
+			cost += lineTokens(e)
+		}
+	}
+	for len(pinned) > 0 && cost > budget {
+		// A line costs one token more than its text for the line break.
+		if share := budget/len(pinned) - 1; share >= minPinnedTokens {
+			for _, i := range pinned {
+				group[i].Text = truncateTokens(group[i].Text, share)
+			}
+			return
+		}
+		group[pinned[0]].pinned = false
+		cost -= lineTokens(group[pinned[0]])
+		pinned = pinned[1:]
+	}
+}
+
+// markerTokens bounds the tokens of an omitted marker.
+var markerTokens = lineTokens(omittedMarker(logsource.LogLine{}, 1_000_000_000))
+
+func omittedMarker(line logsource.LogLine, omitted int) entry {
+	return entry{LogLine: logsource.LogLine{
+		Container: line.Container,
+		Previous:  line.Previous,
+		Text:      fmt.Sprintf("... %d lines omitted ...", omitted),
+		Count:     1,
+	}}
+}
+
+// lineTokens estimates the tokens of a rendered line.
+func lineTokens(e entry) int {
+	return EstimateTokens(e.Text) + 1
+}
+
+// EstimateTokens estimates the number of tokens of s: about four bytes per
+// token for ASCII text and one token per rune otherwise.
+func EstimateTokens(s string) int {
+	ascii, other := 0, 0
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// truncateTokens truncates s to maxTokens, including the truncation suffix.
+func truncateTokens(s string, maxTokens int) string {
+	if EstimateTokens(s) <= maxTokens {
+		return s
+	}
+	maxTokens = max(maxTokens-EstimateTokens(truncatedSuffix), 0)
+	tokens, end := 0, 0
+	ascii := 0
+	for i, r := range s {
+		if r < utf8.RuneSelf {
+			ascii++
+			if ascii%4 == 1 {
+				tokens++
+			}
+		} else {
+			tokens++
+		}
+		if tokens > maxTokens {
+			break
+		}
+		end = i + utf8.RuneLen(r)
+	}
+	return s[:end] + truncatedSuffix
+}
@@ -0,0 +1,147 @@
+package preprocess
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	logsource "github.com/Fl0rencess720/Kopilot/pkg/logSource"
+)
+
+func lines(texts ...string) []logsource.LogLine {
+	result := make([]logsource.LogLine, len(texts))
+	for i, text := range texts {
+		result[i] = logsource.LogLine{Container: "app", Text: text}
+	}
+	return result
+}
+
+func render(lines []logsource.LogLine) []string {
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		result[i] = line.Text
+		if line.Count > 1 {
+			result[i] += fmt.Sprintf(" x%d", line.Count)
+		}
+	}
+	return result
+}
+
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  *kopilotv1.PreprocessingSpec
+		lines []logsource.LogLine
+		want  []string
+	}{
+		{
+			name:  "strip ANSI",
+			spec:  &kopilotv1.PreprocessingSpec{StripANSI: true},
+			lines: lines("\x1b[31mred\x1b[0m\r", "\x1b]0;title\x07plain"),
+			want:  []string{"red", "plain"},
+		},
+		{
+			name:  "deduplicate",
+			spec:  &kopilotv1.PreprocessingSpec{Deduplicate: true},
+			lines: lines("a", "b", "a", "a"),
+			want:  []string{"a x3", "b"},
+		},
+		{
+			name:  "keep duplicate error lines",
+			spec:  &kopilotv1.PreprocessingSpec{KeepErrors: true, Deduplicate: true},
+			lines: lines("error: x", "a", "a", "error: x"),
+			want:  []string{"error: x", "a x2", "error: x"},
+		},
+		{
+			name: "cluster at the last member",
+			spec: &kopilotv1.PreprocessingSpec{Clustering: &kopilotv1.ClusteringSpec{MinClusterSize: 3}},
+			lines: lines(
+				"request 1 took 10ms",
+				"starting worker",
+				"request 2 took 12ms",
+				"listening on :8080",
+				"request 3 took 9ms",
+				"shutting down",
+			),
+			want: []string{"starting worker", "listening on :8080", "request <*> took <*> x3", "shutting down"},
+		},
+		{
+			name: "small clusters are kept",
+			spec: &kopilotv1.PreprocessingSpec{Clustering: &kopilotv1.ClusteringSpec{MinClusterSize: 3}},
+			lines: lines(
+				"request 1 took 10ms",
+				"request 2 took 12ms",
+			),
+			want: []string{"request 1 took 10ms", "request 2 took 12ms"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Process(tt.lines, tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(render(got), "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Process() = %q, want %q", render(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessCap(t *testing.T) {
+	long := func(prefix string) string {
+		return prefix + strings.Repeat(" detail", 400)
+	}
+	tests := []struct {
+		name      string
+		maxTokens int32
+		lines     []logsource.LogLine
+		// kept are lines that must be kept, in order.
+		kept []string
+	}{
+		{
+			name:      "keep the first and last error and the tail",
+			maxTokens: 120,
+			lines: lines(append(append([]string{"error: first"}, strings.Split(strings.Repeat("noise\n", 200), "\n")...),
+				"error: last", "tail")...),
+			kept: []string{"error: first", "error: last", "tail"},
+		},
+		{
+			name:      "truncate long pinned lines",
+			maxTokens: 120,
+			lines:     lines(long("error: first"), "noise", long("error: last")),
+			kept:      []string{"error: first", "error: last"},
+		},
+		{
+			name:      "unpin the first error if the budget is too small",
+			maxTokens: 50,
+			lines:     lines(long("error: first"), "noise", long("error: last")),
+			kept:      []string{"error: last"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Process(tt.lines, &kopilotv1.PreprocessingSpec{KeepErrors: true, MaxTokens: tt.maxTokens})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokens := 0
+			for _, line := range got {
+				tokens += lineTokens(entry{LogLine: line})
+			}
+			if tokens > int(tt.maxTokens) {
+				t.Errorf("Process() = %d tokens, want at most %d", tokens, tt.maxTokens)
+			}
+			next := 0
+			for _, line := range got {
+				if next < len(tt.kept) && strings.HasPrefix(line.Text, tt.kept[next]) {
+					next++
+				}
+			}
+			if next < len(tt.kept) {
+				t.Errorf("Process() = %q, want %q kept in order", render(got), tt.kept)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package redact
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Rule redacts the matches of a regular expression. If the expression has a
+// capturing group, only the first group is redacted.
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+	// valid filters out false positives of built-in detectors.
+	valid func(string) bool
+}
+
+// Detectors are the built-in rules by name, applied in the order of DetectorNames.
+var Detectors = map[string][]Rule{
+	"jwt": {{
+		Name:    "JWT",
+		Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+	}},
+	"bearerToken": {{
+		Name:    "TOKEN",
+		Pattern: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]{8,}=*)`),
+	}},
+	"awsKey": {{
+		Name:    "AWS_KEY",
+		Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	}, {
+		Name:    "AWS_KEY",
+		Pattern: regexp.MustCompile(`(?i)aws_secret_access_key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`),
+	}},
+	"email": {{
+		Name:    "EMAIL",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	}},
+	"ipv4": {{
+		Name:    "IP",
+		Pattern: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`),
+		valid:   func(s string) bool { return net.ParseIP(s) != nil },
+	}},
+	"ipv6": {{
+		Name:    "IP",
+		Pattern: regexp.MustCompile(`(?i)(?:^|[^0-9a-z:])([0-9a-f]{0,4}(?::[0-9a-f]{0,4}){2,7})`),
+		valid: func(s string) bool {
+			return strings.ContainsAny(s, "0123456789") && net.ParseIP(s) != nil
+		},
+	}},
+	"creditCard": {{
+		Name:    "CARD",
+		Pattern: regexp.MustCompile(`\b[3-6](?:[ -]?\d){12,18}\b`),
+		valid:   luhn,
+	}},
+}
+
+// DetectorNames are the names of the built-in detectors.
+var DetectorNames = []string{"jwt", "bearerToken", "awsKey", "email", "ipv4", "ipv6", "creditCard"}
+
+var placeholderPattern = regexp.MustCompile(`\[REDACTED_[A-Z0-9_]+_\d+\]`)
+
+// Redactor replaces sensitive values with placeholders and can restore them.
+// A value is always replaced by the same placeholder.
+type Redactor struct {
+	rules        []Rule
+	placeholders map[string]string
+	originals    map[string]string
+	counts       map[string]int
+}
+
+// New creates a redactor with the named built-in detectors, or all of them if
+// none is named, and the custom rules.
+func New(detectors []string, rules []Rule) (*Redactor, error) {
+	if len(detectors) == 0 {
+		detectors = DetectorNames
+	}
+	r := &Redactor{
+		placeholders: map[string]string{},
+		originals:    map[string]string{},
+		counts:       map[string]int{},
+	}
+	for _, name := range DetectorNames {
+		if slices.Contains(detectors, name) {
+			r.rules = append(r.rules, Detectors[name]...)
+		}
+	}
+	for _, name := range detectors {
+		if _, ok := Detectors[name]; !ok {
+			return nil, fmt.Errorf("unknown detector: %s", name)
+		}
+	}
+	r.rules = append(r.rules, rules...)
+	return r, nil
+}
+
+var ruleNameReplacer = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// NewRule compiles a custom rule. The name is upper-cased for placeholders.
+func NewRule(name, pattern string) (Rule, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid pattern of rule %s: %w", name, err)
+	}
+	return Rule{Name: ruleNameReplacer.ReplaceAllString(strings.ToUpper(name), "_"), Pattern: re}, nil
+}
+
+// Redact replaces the sensitive values in s with placeholders.
+func (r *Redactor) Redact(s string) string {
+	for _, rule := range r.rules {
+		s = r.apply(rule, s)
+	}
+	return s
+}
+
+func (r *Redactor) apply(rule Rule, s string) string {
+	matches := rule.Pattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+	existing := placeholderPattern.FindAllStringIndex(s, -1)
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		if start == end || overlaps(existing, start, end) {
+			continue
+		}
+		value := s[start:end]
+		if rule.valid != nil && !rule.valid(value) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(r.placeholder(rule.Name, value))
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func (r *Redactor) placeholder(kind, value string) string {
+	if p, ok := r.placeholders[value]; ok {
+		return p
+	}
+	r.counts[kind]++
+	p := fmt.Sprintf("[REDACTED_%s_%d]", kind, r.counts[kind])
+	r.placeholders[value] = p
+	r.originals[p] = value
+	return p
+}
+
+// Restore replaces the placeholders in s with the original values.
+func (r *Redactor) Restore(s string) string {
+	if len(r.originals) == 0 {
+		return s
+	}
+	return placeholderPattern.ReplaceAllStringFunc(s, func(p string) string {
+		if original, ok := r.originals[p]; ok {
+			return original
+		}
+		return p
+	})
+}
+
+// RedactPod returns a copy of the pod with the sensitive values of its spec,
+// metadata and status messages replaced. If maskEnv is set, all literal env
+// var values are replaced as well. The termination messages of containers
+// may be the tail of their logs.
+func (r *Redactor) RedactPod(pod corev1.Pod, maskEnv bool) corev1.Pod {
+	redacted := *pod.DeepCopy()
+	r.redactMetadata(&redacted.ObjectMeta)
+
+	redactContainer := func(c *corev1.Container) {
+		for i := range c.Command {
+			c.Command[i] = r.Redact(c.Command[i])
+		}
+		for i := range c.Args {
+			c.Args[i] = r.Redact(c.Args[i])
+		}
+		for i := range c.Env {
+			if c.Env[i].Value == "" {
+				continue
+			}
+			if maskEnv {
+				c.Env[i].Value = r.placeholder("ENV", c.Env[i].Value)
+			} else {
+				c.Env[i].Value = r.Redact(c.Env[i].Value)
+			}
+		}
+	}
+	for i := range redacted.Spec.InitContainers {
+		redactContainer(&redacted.Spec.InitContainers[i])
+	}
+	for i := range redacted.Spec.Containers {
+		redactContainer(&redacted.Spec.Containers[i])
+	}
+	for i := range redacted.Spec.EphemeralContainers {
+		redactContainer((*corev1.Container)(&redacted.Spec.EphemeralContainers[i].EphemeralContainerCommon))
+	}
+	r.redactPodStatus(&redacted.Status)
+	return redacted
+}
+
+func (r *Redactor) redactPodStatus(status *corev1.PodStatus) {
+	status.Message = r.Redact(status.Message)
+	for i := range status.Conditions {
+		status.Conditions[i].Message = r.Redact(status.Conditions[i].Message)
+	}
+	redactState := func(state *corev1.ContainerState) {
+		if state.Waiting != nil {
+			state.Waiting.Message = r.Redact(state.Waiting.Message)
+		}
+		if state.Terminated != nil {
+			state.Terminated.Message = r.Redact(state.Terminated.Message)
+		}
+	}
+	for _, statuses := range [][]corev1.ContainerStatus{status.InitContainerStatuses, status.ContainerStatuses, status.EphemeralContainerStatuses} {
+		for i := range statuses {
+			redactState(&statuses[i].State)
+			redactState(&statuses[i].LastTerminationState)
+		}
+	}
+}
+
+// RedactPodTemplate returns a copy of the pod template with the same values
+// replaced as by RedactPod.
+func (r *Redactor) RedactPodTemplate(template corev1.PodTemplateSpec, maskEnv bool) corev1.PodTemplateSpec {
+	pod := r.RedactPod(corev1.Pod{ObjectMeta: template.ObjectMeta, Spec: template.Spec}, maskEnv)
+	return corev1.PodTemplateSpec{ObjectMeta: pod.ObjectMeta, Spec: pod.Spec}
+}
+
+// RedactNode returns a copy of the node with the sensitive values of its
+// metadata, addresses and condition messages replaced.
+func (r *Redactor) RedactNode(node corev1.Node) corev1.Node {
+	redacted := *node.DeepCopy()
+	r.redactMetadata(&redacted.ObjectMeta)
+	for i := range redacted.Status.Addresses {
+		redacted.Status.Addresses[i].Address = r.Redact(redacted.Status.Addresses[i].Address)
+	}
+	for i := range redacted.Status.Conditions {
+		redacted.Status.Conditions[i].Message = r.Redact(redacted.Status.Conditions[i].Message)
+	}
+	return redacted
+}
+
+func (r *Redactor) redactMetadata(meta *metav1.ObjectMeta) {
+	meta.ManagedFields = nil
+	// The last applied configuration repeats the spec, including env vars.
+	delete(meta.Annotations, corev1.LastAppliedConfigAnnotation)
+
+	keys := make([]string, 0, len(meta.Annotations))
+	for key := range meta.Annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		meta.Annotations[key] = r.Redact(meta.Annotations[key])
+	}
+}
+
+func overlaps(spans [][]int, start, end int) bool {
+	for _, span := range spans {
+		if start < span[1] && span[0] < end {
+			return true
+		}
+	}
+	return false
+}
+
+// luhn reports whether the digits of s pass the Luhn checksum.
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && n <= 19 && sum%10 == 0
+}
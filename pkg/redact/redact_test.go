@@ -0,0 +1,164 @@
+package redact
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "email and ip",
+			in:   "login failed for alice@example.com from 10.0.0.12",
+			want: "login failed for [REDACTED_EMAIL_1] from [REDACTED_IP_1]",
+		},
+		{
+			name: "bearer token keeps the scheme",
+			in:   "Authorization: Bearer abcdefgh12345678",
+			want: "Authorization: Bearer [REDACTED_TOKEN_1]",
+		},
+		{
+			name: "invalid ip and card numbers are kept",
+			in:   "version 1.2.3.400, order 4111111111111112",
+			want: "version 1.2.3.400, order 4111111111111112",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := New(nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := r.Redact(tt.in)
+			if got != tt.want {
+				t.Errorf("Redact() = %q, want %q", got, tt.want)
+			}
+			if restored := r.Restore(got); restored != tt.in {
+				t.Errorf("Restore() = %q, want %q", restored, tt.in)
+			}
+		})
+	}
+}
+
+func TestRedactPodStatus(t *testing.T) {
+	const secret = "alice@example.com"
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web"},
+		Status: corev1.PodStatus{
+			Message:    "evicted, owner " + secret,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Message: "not ready for " + secret}},
+			InitContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "init",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: "failed for " + secret}},
+			}},
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "app",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff", Message: "back-off " + secret}},
+				// The log tail with terminationMessagePolicy FallbackToLogsOnError.
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: "panic: user " + secret}},
+			}},
+			EphemeralContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "debug",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: "exit " + secret}},
+			}},
+		},
+	}
+	r, err := New([]string{"email"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	redacted := r.RedactPod(pod, false)
+
+	status := redacted.Status
+	messages := map[string]string{
+		"status":               status.Message,
+		"condition":            status.Conditions[0].Message,
+		"init terminated":      status.InitContainerStatuses[0].State.Terminated.Message,
+		"waiting":              status.ContainerStatuses[0].State.Waiting.Message,
+		"last terminated":      status.ContainerStatuses[0].LastTerminationState.Terminated.Message,
+		"ephemeral terminated": status.EphemeralContainerStatuses[0].State.Terminated.Message,
+	}
+	want := map[string]string{
+		"status":               "evicted, owner [REDACTED_EMAIL_1]",
+		"condition":            "not ready for [REDACTED_EMAIL_1]",
+		"init terminated":      "failed for [REDACTED_EMAIL_1]",
+		"waiting":              "back-off [REDACTED_EMAIL_1]",
+		"last terminated":      "panic: user [REDACTED_EMAIL_1]",
+		"ephemeral terminated": "exit [REDACTED_EMAIL_1]",
+	}
+	for field, got := range messages {
+		if got != want[field] {
+			t.Errorf("%s message = %q, want %q", field, got, want[field])
+		}
+	}
+	if pod.Status.ContainerStatuses[0].LastTerminationState.Terminated.Message != "panic: user "+secret {
+		t.Errorf("RedactPod() modified the original pod")
+	}
+}
+
+func TestRedactPlaceholders(t *testing.T) {
+	rule, err := NewRule("customer-id", `C-\d{6}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := New(nil, []Rule{rule})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := "login failed for alice@example.com, customer C-123456\n" +
+		"retry for alice@example.com, bob@example.com"
+	got := r.Redact(in)
+	// A value is always replaced by the same placeholder.
+	want := "login failed for [REDACTED_EMAIL_1], customer [REDACTED_CUSTOMER_ID_1]\n" +
+		"retry for [REDACTED_EMAIL_1], [REDACTED_EMAIL_2]"
+	if got != want {
+		t.Errorf("Redact() = %q, want %q", got, want)
+	}
+	// Placeholders that were not issued by the redactor are kept.
+	if restored := r.Restore("[REDACTED_EMAIL_1] 的密码错误, [REDACTED_IP_7]"); restored != "alice@example.com 的密码错误, [REDACTED_IP_7]" {
+		t.Errorf("Restore() = %q", restored)
+	}
+}
+
+func TestRedactPodEnv(t *testing.T) {
+	pod := corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+		Name: "app",
+		Env: []corev1.EnvVar{
+			{Name: "DB_PASSWORD", Value: "s3cr3t"},
+			{Name: "ADMIN", Value: "alice@example.com"},
+		},
+	}}}}
+	tests := []struct {
+		name    string
+		maskEnv bool
+		want    []string
+	}{
+		{name: "detected values", want: []string{"s3cr3t", "[REDACTED_EMAIL_1]"}},
+		{name: "masked env", maskEnv: true, want: []string{"[REDACTED_ENV_1]", "[REDACTED_ENV_2]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := New(nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			env := r.RedactPod(pod, tt.maskEnv).Spec.Containers[0].Env
+			for i, want := range tt.want {
+				if env[i].Value != want {
+					t.Errorf("%s = %q, want %q", env[i].Name, env[i].Value, want)
+				}
+			}
+			if pod.Spec.Containers[0].Env[0].Value != "s3cr3t" {
+				t.Error("RedactPod() modified the original pod")
+			}
+		})
+	}
+}
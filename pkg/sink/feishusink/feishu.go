@@ -2,6 +2,7 @@ package feishusink
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
@@ -10,19 +11,15 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
 	"github.com/Fl0rencess720/Kopilot/internal/controller/utils"
+	"github.com/Fl0rencess720/Kopilot/pkg/sink"
 	"go.uber.org/zap"
-	"k8s.io/client-go/kubernetes"
 )
 
-type LLMContent struct {
-	Reason   string `json:"reason"`
-	Solution string `json:"solution"`
-}
-
 type BotMessage struct {
 	MsgType   string      `json:"msg_type"`
 	Content   interface{} `json:"content"`
@@ -57,12 +54,12 @@ type FeishuSink struct {
 	secret     string
 }
 
-func NewFeishuSink(clientset kubernetes.Interface, feishuSink kopilotv1.FeishuSink) (*FeishuSink, error) {
-	webhookURL, err := utils.GetSecret(clientset, feishuSink.WebhookSecretRef.Key, feishuSink.WebhookSecretRef.Namespace, feishuSink.WebhookSecretRef.Name)
+func NewFeishuSink(ctx context.Context, secrets utils.SecretGetter, feishuSink kopilotv1.FeishuSink) (*FeishuSink, error) {
+	webhookURL, err := secrets.GetSecret(ctx, feishuSink.WebhookSecretRef)
 	if err != nil {
 		return nil, err
 	}
-	secret, err := utils.GetSecret(clientset, feishuSink.SignatureSecretRef.Key, feishuSink.SignatureSecretRef.Namespace, feishuSink.WebhookSecretRef.Name)
+	secret, err := secrets.GetSecret(ctx, feishuSink.SignatureSecretRef)
 	if err != nil {
 		return nil, err
 	}
@@ -72,7 +69,17 @@ func NewFeishuSink(clientset kubernetes.Interface, feishuSink kopilotv1.FeishuSi
 	}, nil
 }
 
-func (s *FeishuSink) SendBotMessage(namespace, podName, content string) error {
+var _ sink.Sink = &FeishuSink{}
+
+func (s *FeishuSink) Send(ctx context.Context, msg sink.Message) error {
+	return s.send(ctx, genPostContent(msg))
+}
+
+func (s *FeishuSink) SendResolved(ctx context.Context, msg sink.ResolvedMessage) error {
+	return s.send(ctx, genResolvedPostContent(msg))
+}
+
+func (s *FeishuSink) send(ctx context.Context, content PostContent) error {
 	timestamp := time.Now().Unix()
 
 	signature, err := genSign(s.secret, timestamp)
@@ -85,7 +92,7 @@ func (s *FeishuSink) SendBotMessage(namespace, podName, content string) error {
 		Timestamp: strconv.FormatInt(timestamp, 10),
 		Sign:      signature,
 		MsgType:   "post",
-		Content:   genPostContent(namespace, podName, content),
+		Content:   content,
 	}
 
 	jsonData, err := json.Marshal(message)
@@ -94,7 +101,7 @@ func (s *FeishuSink) SendBotMessage(namespace, podName, content string) error {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", s.webhookURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", s.webhookURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		zap.L().Error("http.NewRequest failed", zap.Error(err))
 		return err
@@ -153,35 +160,94 @@ func genSign(secret string, timestamp int64) (string, error) {
 	return signature, nil
 }
 
-func unmarshalLLMContent(content string) (LLMContent, error) {
-	var llmContent LLMContent
-	if err := json.Unmarshal([]byte(content), &llmContent); err != nil {
-		zap.L().Error("unmarshal LLM content failed", zap.Error(err))
-		return LLMContent{}, err
+func genPostContent(msg sink.Message) PostContent {
+	elements := []Elements{
+		{
+			Tag:  "text",
+			Text: resourceText(msg.Namespace, msg.PodName, msg.NodeName),
+		},
 	}
-	return llmContent, nil
-}
-
-func genPostContent(namespace, podName, content string) PostContent {
-	llmContent, err := unmarshalLLMContent(content)
-	if err != nil {
-		zap.L().Error("unmarshal LLM content failed", zap.Error(err))
-		return PostContent{}
+	if len(msg.AffectedPods) > 1 {
+		elements = append(elements, Elements{
+			Tag:  "text",
+			Text: fmt.Sprintf("affected pods (%d): %s\n", len(msg.AffectedPods), strings.Join(msg.AffectedPods, ", ")),
+		})
+	}
+	if classification := msg.Classification(); classification != "" {
+		elements = append(elements, Elements{
+			Tag:  "text",
+			Text: fmt.Sprintf("classification: %s\n", classification),
+		})
+	}
+	if msg.Reason != "" || msg.Solution != "" {
+		elements = append(elements, Elements{
+			Tag:  "text",
+			Text: fmt.Sprintf("reason: %s\nsolution: %s\n", msg.Reason, msg.Solution),
+		})
+	}
+	if rollout := msg.Rollout(); rollout != "" {
+		elements = append(elements, Elements{
+			Tag:  "text",
+			Text: fmt.Sprintf("rollout: %s\n", rollout),
+		})
+	}
+	if msg.AutoFixResult != "" {
+		elements = append(elements, Elements{
+			Tag:  "text",
+			Text: fmt.Sprintf("autofix: %s\n", msg.AutoFixResult),
+		})
+	}
+	if msg.SearchResult != "" {
+		elements = append(elements, Elements{
+			Tag:  "text",
+			Text: fmt.Sprintf("search: %s\n", msg.SearchResult),
+		})
+	}
+	if msg.HumanHelpResult != "" {
+		elements = append(elements, Elements{
+			Tag:  "text",
+			Text: fmt.Sprintf("document: %s\n", msg.HumanHelpResult),
+		})
 	}
 
 	postContent := PostContent{}
 	postContent.Post.ZhCn.Title = "Kopilot Bot Alert"
+	postContent.Post.ZhCn.Content = [][]Elements{elements}
+	return postContent
+}
+
+func genResolvedPostContent(msg sink.ResolvedMessage) PostContent {
+	postContent := PostContent{}
+	postContent.Post.ZhCn.Title = "Kopilot Bot Resolved"
 	postContent.Post.ZhCn.Content = [][]Elements{
 		{
 			{
 				Tag:  "text",
-				Text: fmt.Sprintf("namespace: %s\npod: %s\n", namespace, podName),
+				Text: resourceText(msg.Namespace, msg.PodName, msg.NodeName),
 			},
 			{
 				Tag:  "text",
-				Text: fmt.Sprintf("reason: %s\nsolution: %s\n", llmContent.Reason, llmContent.Solution),
+				Text: fmt.Sprintf("reason: %s\nresolved after: %s\n", msg.Reason, msg.Duration.Round(time.Second)),
 			},
 		},
 	}
+	if len(msg.AffectedPods) > 1 {
+		postContent.Post.ZhCn.Content[0] = append(postContent.Post.ZhCn.Content[0], Elements{
+			Tag:  "text",
+			Text: fmt.Sprintf("affected pods (%d): %s\n", len(msg.AffectedPods), strings.Join(msg.AffectedPods, ", ")),
+		})
+	}
 	return postContent
 }
+
+// resourceText describes the pod of a notification, or the node of a node
+// incident. Rollout incidents may have no pod.
+func resourceText(namespace, podName, nodeName string) string {
+	switch {
+	case nodeName != "":
+		return fmt.Sprintf("node: %s\n", nodeName)
+	case podName == "":
+		return fmt.Sprintf("namespace: %s\n", namespace)
+	}
+	return fmt.Sprintf("namespace: %s\npod: %s\n", namespace, podName)
+}
@@ -0,0 +1,105 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Message is the notification of an analyzed incident.
+type Message struct {
+	Namespace string
+	PodName   string
+	PodUID    string
+	// NodeName is set instead of the pod for node incidents.
+	NodeName string
+
+	// OwnerKind and OwnerName identify the workload that owns the pod.
+	OwnerKind string
+	OwnerName string
+	// AffectedPods are all unhealthy pods of the workload, the analyzed pod included.
+	AffectedPods []string
+
+	// Fingerprint identifies the incident.
+	Fingerprint string
+	// Container, FailureReason, Severity and FailureMessage classify the failure.
+	Container      string
+	FailureReason  string
+	Severity       string
+	FailureMessage string
+	// LogsExcerpt is an excerpt of the logs that were analyzed.
+	LogsExcerpt string
+
+	// Reason and Solution are the result of the single working mode.
+	Reason   string
+	Solution string
+	// NewRevisionAtFault and RollbackRecommended are the verdict on a stalled
+	// rollout of the owner, if the incident is one.
+	NewRevisionAtFault  *bool
+	RollbackRecommended *bool
+
+	// AutoFixResult, SearchResult and HumanHelpResult are the result of the multi working mode.
+	AutoFixResult   string
+	SearchResult    string
+	HumanHelpResult string
+}
+
+// Classification describes the failure classification for humans, e.g.
+// "OOMKilled (critical) in container app: ...".
+func (m Message) Classification() string {
+	if m.FailureReason == "" {
+		return ""
+	}
+	text := m.FailureReason
+	if m.Severity != "" {
+		text += fmt.Sprintf(" (%s)", m.Severity)
+	}
+	if m.Container != "" {
+		text += " in container " + m.Container
+	}
+	if m.FailureMessage != "" {
+		text += ": " + m.FailureMessage
+	}
+	return text
+}
+
+// Rollout describes the verdict on a stalled rollout for humans, e.g.
+// "Deployment web: new revision at fault: yes, rollback recommended: yes".
+func (m Message) Rollout() string {
+	if m.NewRevisionAtFault == nil && m.RollbackRecommended == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s %s: new revision at fault: %s, rollback recommended: %s",
+		m.OwnerKind, m.OwnerName, yesNo(m.NewRevisionAtFault), yesNo(m.RollbackRecommended))
+}
+
+func yesNo(b *bool) string {
+	switch {
+	case b == nil:
+		return "unknown"
+	case *b:
+		return "yes"
+	}
+	return "no"
+}
+
+// ResolvedMessage is the notification of a resolved incident.
+type ResolvedMessage struct {
+	Namespace string
+	// PodName is the analyzed pod of the incident.
+	PodName string
+	// AffectedPods are all pods of the incident, the analyzed pod included.
+	AffectedPods []string
+	NodeName     string
+	OwnerKind    string
+	OwnerName    string
+	Fingerprint  string
+	Reason       string
+	Duration     time.Duration
+}
+
+// Sink is a notification channel.
+type Sink interface {
+	Send(ctx context.Context, msg Message) error
+	SendResolved(ctx context.Context, msg ResolvedMessage) error
+}
@@ -0,0 +1,307 @@
+package slacksink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/Fl0rencess720/Kopilot/internal/controller/utils"
+	"github.com/Fl0rencess720/Kopilot/pkg/sink"
+	"go.uber.org/zap"
+)
+
+const (
+	postMessageURL = "https://slack.com/api/chat.postMessage"
+
+	// maxTextLength is the maximum length of the text of a section block.
+	maxTextLength = 3000
+	// maxFieldLength is the maximum length of a field of a section block.
+	maxFieldLength = 2000
+)
+
+// mrkdwnEscaper escapes the characters that Slack interprets as control
+// characters in mrkdwn text.
+var mrkdwnEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+type Message struct {
+	Channel string  `json:"channel,omitempty"`
+	Text    string  `json:"text"`
+	Blocks  []Block `json:"blocks"`
+}
+
+type Block struct {
+	Type   string  `json:"type"`
+	Text   *Text   `json:"text,omitempty"`
+	Fields []*Text `json:"fields,omitempty"`
+}
+
+type Text struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type Response struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+type SlackSink struct {
+	webhookURL string
+	botToken   string
+	channel    string
+}
+
+var _ sink.Sink = &SlackSink{}
+
+func NewSlackSink(ctx context.Context, secrets utils.SecretGetter, slackSink kopilotv1.SlackSink) (*SlackSink, error) {
+	s := &SlackSink{channel: slackSink.Channel}
+	switch {
+	case slackSink.WebhookSecretRef != nil:
+		webhookURL, err := secrets.GetSecret(ctx, *slackSink.WebhookSecretRef)
+		if err != nil {
+			return nil, err
+		}
+		s.webhookURL = webhookURL
+	case slackSink.BotTokenSecretRef != nil:
+		botToken, err := secrets.GetSecret(ctx, *slackSink.BotTokenSecretRef)
+		if err != nil {
+			return nil, err
+		}
+		s.botToken = botToken
+	default:
+		return nil, fmt.Errorf("either webhookSecretRef or botTokenSecretRef must be set")
+	}
+	return s, nil
+}
+
+func (s *SlackSink) Send(ctx context.Context, msg sink.Message) error {
+	return s.post(ctx, genAlertMessage(msg))
+}
+
+func (s *SlackSink) SendResolved(ctx context.Context, msg sink.ResolvedMessage) error {
+	return s.post(ctx, genResolvedMessage(msg))
+}
+
+func (s *SlackSink) post(ctx context.Context, message Message) error {
+	url := s.webhookURL
+	if s.botToken != "" {
+		url = postMessageURL
+		message.Channel = s.channel
+	}
+
+	jsonData, err := json.Marshal(message)
+	if err != nil {
+		zap.L().Error("json marshal failed", zap.Error(err))
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		zap.L().Error("http.NewRequest failed", zap.Error(err))
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if s.botToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.botToken)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		zap.L().Error("http.Do failed", zap.Error(err))
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			zap.L().Error("close response body failed", zap.Error(err))
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		zap.L().Error("response body read failed", zap.Error(err))
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("slack api call failed, status code: %d, body: %s", resp.StatusCode, string(body))
+		zap.L().Error(err.Error())
+		return err
+	}
+
+	// Incoming webhooks answer with a plain "ok", the Web API with a JSON document.
+	if s.botToken == "" {
+		return nil
+	}
+
+	var response Response
+	if err := json.Unmarshal(body, &response); err != nil {
+		zap.L().Error("json unmarshal failed", zap.Error(err))
+		return err
+	}
+
+	if !response.OK {
+		err := fmt.Errorf("slack api call failed, err: %s", response.Error)
+		zap.L().Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func genAlertMessage(msg sink.Message) Message {
+	blocks := []Block{
+		header(":rotating_light: Kopilot Bot Alert"),
+		{
+			Type:   "section",
+			Fields: resourceFields(msg.Namespace, msg.PodName, msg.NodeName),
+		},
+	}
+	blocks = appendAffectedPods(blocks, msg.AffectedPods)
+	blocks = appendSection(blocks, "Classification", msg.Classification())
+	blocks = appendSection(blocks, "Reason", msg.Reason)
+	blocks = appendSection(blocks, "Solution", msg.Solution)
+	blocks = appendSection(blocks, "Rollout", msg.Rollout())
+	blocks = appendSection(blocks, "AutoFix", msg.AutoFixResult)
+	blocks = appendSection(blocks, "Search", msg.SearchResult)
+	blocks = appendSection(blocks, "Document", msg.HumanHelpResult)
+
+	return Message{
+		Text:   "Kopilot Bot Alert: " + resourceName(msg.Namespace, msg.PodName, msg.NodeName),
+		Blocks: blocks,
+	}
+}
+
+func genResolvedMessage(msg sink.ResolvedMessage) Message {
+	blocks := []Block{
+		header(":white_check_mark: Kopilot Bot Resolved"),
+		{
+			Type: "section",
+			Fields: append(resourceFields(msg.Namespace, msg.PodName, msg.NodeName),
+				field("Owner", ownerName(msg.OwnerKind, msg.OwnerName)),
+				field("Reason", msg.Reason),
+				field("Resolved after", msg.Duration.Round(time.Second).String()),
+			),
+		},
+	}
+	blocks = appendAffectedPods(blocks, msg.AffectedPods)
+
+	return Message{
+		Text:   "Kopilot Bot Resolved: " + resourceName(msg.Namespace, msg.PodName, msg.NodeName),
+		Blocks: blocks,
+	}
+}
+
+// resourceFields describe the pod of a notification, or the node of a node
+// incident. Rollout incidents may have no pod.
+func resourceFields(namespace, podName, nodeName string) []*Text {
+	if nodeName != "" {
+		return []*Text{field("Node", nodeName)}
+	}
+	fields := []*Text{field("Namespace", namespace)}
+	if podName != "" {
+		fields = append(fields, field("Pod", podName))
+	}
+	return fields
+}
+
+// field returns a field of a section block with the escaped value, truncated
+// to the field limit.
+func field(title, value string) *Text {
+	return markdown(fmt.Sprintf("*%s:*\n%s", title, escape(value, maxFieldLength-len([]rune(title))-4)))
+}
+
+// escape escapes value for mrkdwn text. A value whose escaped form exceeds
+// limit characters is truncated before it is escaped, so that no entity is cut.
+func escape(value string, limit int) string {
+	escaped := mrkdwnEscaper.Replace(value)
+	if utf8.RuneCountInString(escaped) <= limit {
+		return escaped
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range value {
+		item := mrkdwnEscaper.Replace(string(r))
+		if n+utf8.RuneCountInString(item) > limit-1 {
+			break
+		}
+		b.WriteString(item)
+		n += utf8.RuneCountInString(item)
+	}
+	return b.String() + "…"
+}
+
+func ownerName(kind, name string) string {
+	if kind == "" {
+		return name
+	}
+	return kind + "/" + name
+}
+
+// appendAffectedPods appends the affected pods of an incident with more than
+// one pod. The list ends with "… and N more" if it exceeds the text limit.
+func appendAffectedPods(blocks []Block, pods []string) []Block {
+	if len(pods) <= 1 {
+		return blocks
+	}
+	title := fmt.Sprintf("Affected pods (%d)", len(pods))
+	return appendEscapedSection(blocks, title, podList(pods, maxTextLength-len(title)-4))
+}
+
+// podList joins the escaped pod names, up to limit characters.
+func podList(pods []string, limit int) string {
+	var text string
+	for i, pod := range pods {
+		item := mrkdwnEscaper.Replace(pod)
+		if i > 0 {
+			item = ", " + item
+		}
+		var rest string
+		if remaining := len(pods) - i - 1; remaining > 0 {
+			rest = fmt.Sprintf(" … and %d more", remaining)
+		}
+		if len([]rune(text+item+rest)) > limit {
+			return text + fmt.Sprintf(" … and %d more", len(pods)-i)
+		}
+		text += item
+	}
+	return text
+}
+
+func resourceName(namespace, podName, nodeName string) string {
+	if nodeName != "" {
+		return "node " + nodeName
+	}
+	return namespace + "/" + podName
+}
+
+// appendSection appends a section with the escaped content, truncated to the
+// text limit.
+func appendSection(blocks []Block, title, content string) []Block {
+	if content == "" {
+		return blocks
+	}
+	return appendEscapedSection(blocks, title, escape(content, maxTextLength-len([]rune(title))-4))
+}
+
+// appendEscapedSection appends a section with content that is already escaped
+// and within the text limit.
+func appendEscapedSection(blocks []Block, title, content string) []Block {
+	text := fmt.Sprintf("*%s:*\n%s", title, content)
+	return append(blocks, Block{Type: "divider"}, Block{Type: "section", Text: markdown(text)})
+}
+
+func header(text string) Block {
+	return Block{Type: "header", Text: &Text{Type: "plain_text", Text: text}}
+}
+
+func markdown(text string) *Text {
+	return &Text{Type: "mrkdwn", Text: text}
+}
@@ -0,0 +1,296 @@
+package webhooksink
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	kopilotv1 "github.com/Fl0rencess720/Kopilot/api/v1"
+	"github.com/Fl0rencess720/Kopilot/internal/controller/utils"
+	"github.com/Fl0rencess720/Kopilot/pkg/sink"
+	"go.uber.org/zap"
+)
+
+const (
+	// PayloadVersion is the version of the JSON payload schema.
+	// It is bumped on incompatible changes only: v2 made pod optional, it is
+	// not set for node incidents and incidents analyzed without a pod, and
+	// added node.
+	PayloadVersion = "kopilot.fl0rencess720/v2"
+
+	EventIncident = "incident"
+	EventResolved = "resolved"
+
+	TimestampHeader = "X-Kopilot-Timestamp"
+	SignatureHeader = "X-Kopilot-Signature"
+
+	defaultTimeout      = 10 * time.Second
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = time.Second
+)
+
+// Payload is the JSON document POSTed to the webhook.
+type Payload struct {
+	Version     string    `json:"version"`
+	Event       string    `json:"event"`
+	Timestamp   time.Time `json:"timestamp"`
+	Fingerprint string    `json:"fingerprint,omitempty"`
+
+	Pod            *Pod            `json:"pod,omitempty"`
+	Node           *Node           `json:"node,omitempty"`
+	Owner          *Owner          `json:"owner,omitempty"`
+	AffectedPods   []string        `json:"affectedPods,omitempty"`
+	Classification *Classification `json:"classification,omitempty"`
+	LogsExcerpt    string          `json:"logsExcerpt,omitempty"`
+	Analysis       *Analysis       `json:"analysis,omitempty"`
+	Remediation    *Remediation    `json:"remediation,omitempty"`
+	Resolution     *Resolution     `json:"resolution,omitempty"`
+}
+
+type Pod struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	UID       string `json:"uid,omitempty"`
+}
+
+type Node struct {
+	Name string `json:"name"`
+}
+
+// Owner is the workload of the incident. Namespace is not set for nodes.
+type Owner struct {
+	Namespace string `json:"namespace,omitempty"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+}
+
+type Classification struct {
+	Container string `json:"container,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	Severity  string `json:"severity,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+type Analysis struct {
+	Reason              string `json:"reason,omitempty"`
+	Solution            string `json:"solution,omitempty"`
+	NewRevisionAtFault  *bool  `json:"newRevisionAtFault,omitempty"`
+	RollbackRecommended *bool  `json:"rollbackRecommended,omitempty"`
+}
+
+type Remediation struct {
+	AutoFix   string `json:"autoFix,omitempty"`
+	Search    string `json:"search,omitempty"`
+	HumanHelp string `json:"humanHelp,omitempty"`
+}
+
+type Resolution struct {
+	DurationSeconds int64 `json:"durationSeconds"`
+}
+
+type WebhookSink struct {
+	url          string
+	signingKey   string
+	headers      map[string]string
+	client       *http.Client
+	maxRetries   int
+	retryBackoff time.Duration
+}
+
+var _ sink.Sink = &WebhookSink{}
+
+func NewWebhookSink(ctx context.Context, secrets utils.SecretGetter, webhookSink kopilotv1.WebhookSink) (*WebhookSink, error) {
+	url, err := secrets.GetSecret(ctx, webhookSink.URLSecretRef)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &WebhookSink{
+		url:          url,
+		headers:      map[string]string{},
+		client:       &http.Client{Timeout: defaultTimeout},
+		maxRetries:   defaultMaxRetries,
+		retryBackoff: defaultRetryBackoff,
+	}
+
+	if ref := webhookSink.SigningSecretRef; ref != nil {
+		s.signingKey, err = secrets.GetSecret(ctx, *ref)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, header := range webhookSink.Headers {
+		value, err := secrets.GetSecret(ctx, header.ValueSecretRef)
+		if err != nil {
+			return nil, err
+		}
+		s.headers[header.Name] = value
+	}
+	if webhookSink.Timeout != nil {
+		s.client.Timeout = webhookSink.Timeout.Duration
+	}
+	if webhookSink.MaxRetries != nil {
+		s.maxRetries = int(*webhookSink.MaxRetries)
+	}
+	if webhookSink.RetryBackoff != nil {
+		s.retryBackoff = webhookSink.RetryBackoff.Duration
+	}
+	return s, nil
+}
+
+func (s *WebhookSink) Send(ctx context.Context, msg sink.Message) error {
+	return s.post(ctx, genIncidentPayload(msg, time.Now()))
+}
+
+func (s *WebhookSink) SendResolved(ctx context.Context, msg sink.ResolvedMessage) error {
+	return s.post(ctx, genResolvedPayload(msg, time.Now()))
+}
+
+// post sends the payload, retrying with exponential backoff on network errors,
+// 429 and 5xx responses.
+func (s *WebhookSink) post(ctx context.Context, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		zap.L().Error("json marshal failed", zap.Error(err))
+		return err
+	}
+
+	backoff := s.retryBackoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := s.do(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= s.maxRetries {
+			zap.L().Error("webhook call failed", zap.Int("attempts", attempt+1), zap.Error(err))
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (s *WebhookSink) do(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range s.headers {
+		req.Header.Set(name, value)
+	}
+	if s.signingKey != "" {
+		timestamp := time.Now().Unix()
+		req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(SignatureHeader, "sha256="+genSign(s.signingKey, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			zap.L().Error("close response body failed", zap.Error(err))
+		}
+	}()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("webhook call failed, status code: %d, body: %s", resp.StatusCode, string(respBody))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// genSign signs "<timestamp>.<body>" with HMAC-SHA256, so that receivers can
+// verify the payload and reject replayed requests.
+func genSign(key string, timestamp int64, body []byte) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	h.Write([]byte("."))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func genIncidentPayload(msg sink.Message, now time.Time) Payload {
+	payload := Payload{
+		Version:      PayloadVersion,
+		Event:        EventIncident,
+		Timestamp:    now.UTC(),
+		Fingerprint:  msg.Fingerprint,
+		LogsExcerpt:  msg.LogsExcerpt,
+		AffectedPods: msg.AffectedPods,
+	}
+	switch {
+	case msg.NodeName != "":
+		payload.Node = &Node{Name: msg.NodeName}
+	case msg.PodName != "":
+		payload.Pod = &Pod{Namespace: msg.Namespace, Name: msg.PodName, UID: msg.PodUID}
+	}
+	if msg.OwnerKind != "" {
+		payload.Owner = &Owner{Namespace: msg.Namespace, Kind: msg.OwnerKind, Name: msg.OwnerName}
+	}
+	if msg.Container != "" || msg.FailureReason != "" {
+		payload.Classification = &Classification{
+			Container: msg.Container,
+			Reason:    msg.FailureReason,
+			Severity:  msg.Severity,
+			Message:   msg.FailureMessage,
+		}
+	}
+	if msg.Reason != "" || msg.Solution != "" {
+		payload.Analysis = &Analysis{
+			Reason:              msg.Reason,
+			Solution:            msg.Solution,
+			NewRevisionAtFault:  msg.NewRevisionAtFault,
+			RollbackRecommended: msg.RollbackRecommended,
+		}
+	}
+	if msg.AutoFixResult != "" || msg.SearchResult != "" || msg.HumanHelpResult != "" {
+		payload.Remediation = &Remediation{
+			AutoFix:   msg.AutoFixResult,
+			Search:    msg.SearchResult,
+			HumanHelp: msg.HumanHelpResult,
+		}
+	}
+	return payload
+}
+
+func genResolvedPayload(msg sink.ResolvedMessage, now time.Time) Payload {
+	payload := Payload{
+		Version:      PayloadVersion,
+		Event:        EventResolved,
+		Timestamp:    now.UTC(),
+		Fingerprint:  msg.Fingerprint,
+		AffectedPods: msg.AffectedPods,
+		Resolution:   &Resolution{DurationSeconds: int64(msg.Duration.Seconds())},
+	}
+	switch {
+	case msg.NodeName != "":
+		payload.Node = &Node{Name: msg.NodeName}
+	case msg.PodName != "":
+		payload.Pod = &Pod{Namespace: msg.Namespace, Name: msg.PodName}
+	}
+	if msg.OwnerKind != "" {
+		payload.Owner = &Owner{Namespace: msg.Namespace, Kind: msg.OwnerKind, Name: msg.OwnerName}
+	}
+	if msg.Reason != "" {
+		payload.Classification = &Classification{Reason: msg.Reason}
+	}
+	return payload
+}
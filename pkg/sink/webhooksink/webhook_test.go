@@ -0,0 +1,176 @@
+package webhooksink
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Fl0rencess720/Kopilot/pkg/sink"
+)
+
+func newTestSink(url string) *WebhookSink {
+	return &WebhookSink{
+		url:          url,
+		headers:      map[string]string{},
+		client:       &http.Client{Timeout: time.Second},
+		maxRetries:   2,
+		retryBackoff: time.Millisecond,
+	}
+}
+
+func TestWebhookSinkSignature(t *testing.T) {
+	var received bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type = %q", got)
+		}
+		if got := r.Header.Get("X-Team"); got != "web" {
+			t.Errorf("X-Team = %q, want the configured header", got)
+		}
+		timestamp := r.Header.Get(TimestampHeader)
+		if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+			t.Errorf("%s = %q, want unix seconds", TimestampHeader, timestamp)
+		}
+		// Receivers verify "sha256=" + hex(HMAC-SHA256(key, "<timestamp>.<body>")).
+		mac := hmac.New(sha256.New, []byte("s3cr3t"))
+		mac.Write([]byte(timestamp + "." + string(body)))
+		want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if got := r.Header.Get(SignatureHeader); !hmac.Equal([]byte(got), []byte(want)) {
+			t.Errorf("%s = %q, want %q", SignatureHeader, got, want)
+		}
+	}))
+	defer server.Close()
+
+	s := newTestSink(server.URL)
+	s.signingKey = "s3cr3t"
+	s.headers["X-Team"] = "web"
+	if err := s.Send(context.Background(), sink.Message{Namespace: "default", PodName: "web-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if !received {
+		t.Error("webhook not called")
+	}
+}
+
+func TestWebhookSinkRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		wantErr  bool
+		// attempts is the number of requests, maxRetries is 2.
+		attempts int
+	}{
+		{name: "success", statuses: []int{200}, attempts: 1},
+		{name: "5xx is retried", statuses: []int{500, 204}, attempts: 2},
+		{name: "429 is retried", statuses: []int{429, 429, 200}, attempts: 3},
+		{name: "4xx is not retried", statuses: []int{400}, wantErr: true, attempts: 1},
+		{name: "retries are bounded", statuses: []int{503, 503, 503, 200}, wantErr: true, attempts: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statuses[attempts])
+				attempts++
+			}))
+			defer server.Close()
+
+			err := newTestSink(server.URL).Send(context.Background(), sink.Message{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Send() error = %v, want error %v", err, tt.wantErr)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("got %d attempts, want %d", attempts, tt.attempts)
+			}
+		})
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestWebhookSinkRetriesNetworkErrors(t *testing.T) {
+	attempts := 0
+	s := newTestSink("http://webhook.invalid")
+	s.client.Transport = roundTripFunc(func(*http.Request) (*http.Response, error) {
+		attempts++
+		return nil, errors.New("connection refused")
+	})
+
+	if err := s.Send(context.Background(), sink.Message{}); err == nil {
+		t.Error("Send() succeeded, want an error")
+	}
+	if attempts != 3 {
+		t.Errorf("got %d attempts, want 3", attempts)
+	}
+}
+
+func TestPayloads(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	rollback := true
+	tests := []struct {
+		name    string
+		payload Payload
+		want    string
+	}{
+		{
+			name: "pod incident",
+			payload: genIncidentPayload(sink.Message{
+				Namespace: "default", PodName: "web-1", PodUID: "uid-1",
+				OwnerKind: "Deployment", OwnerName: "web", AffectedPods: []string{"web-1", "web-2"},
+				Fingerprint: "0123456789abcdef", Container: "app", FailureReason: "CrashLoopBackOff",
+				Severity: "critical", FailureMessage: "back-off", LogsExcerpt: "panic",
+				Reason: "bad config", Solution: "fix the config",
+			}, now),
+			want: `{"version":"kopilot.fl0rencess720/v2","event":"incident","timestamp":"2025-01-01T12:00:00Z","fingerprint":"0123456789abcdef",` +
+				`"pod":{"namespace":"default","name":"web-1","uid":"uid-1"},"owner":{"namespace":"default","kind":"Deployment","name":"web"},` +
+				`"affectedPods":["web-1","web-2"],"classification":{"container":"app","reason":"CrashLoopBackOff","severity":"critical","message":"back-off"},` +
+				`"logsExcerpt":"panic","analysis":{"reason":"bad config","solution":"fix the config"}}`,
+		},
+		{
+			name: "rollout incident without a pod",
+			payload: genIncidentPayload(sink.Message{
+				Namespace: "default", OwnerKind: "Deployment", OwnerName: "web",
+				FailureReason: "ProgressDeadlineExceeded", HumanHelpResult: "roll back", RollbackRecommended: &rollback,
+			}, now),
+			want: `{"version":"kopilot.fl0rencess720/v2","event":"incident","timestamp":"2025-01-01T12:00:00Z",` +
+				`"owner":{"namespace":"default","kind":"Deployment","name":"web"},"classification":{"reason":"ProgressDeadlineExceeded"},` +
+				`"remediation":{"humanHelp":"roll back"}}`,
+		},
+		{
+			name: "resolved node incident",
+			payload: genResolvedPayload(sink.ResolvedMessage{
+				NodeName: "node-a", OwnerKind: "Node", OwnerName: "node-a", Reason: "NodeNotReady", Duration: 90 * time.Second,
+			}, now),
+			want: `{"version":"kopilot.fl0rencess720/v2","event":"resolved","timestamp":"2025-01-01T12:00:00Z",` +
+				`"node":{"name":"node-a"},"owner":{"kind":"Node","name":"node-a"},"classification":{"reason":"NodeNotReady"},` +
+				`"resolution":{"durationSeconds":90}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("payload = %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}